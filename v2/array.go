@@ -1,5 +1,7 @@
 package jd
 
+import "context"
+
 // jsonArray is a polymorphic type representing a concrete JSON array. It
 // dispatches to list, set or multiset semantics.
 type jsonArray []JsonNode
@@ -27,6 +29,7 @@ func (a jsonArray) raw() interface{} {
 }
 
 func (a1 jsonArray) Equals(n JsonNode, opts ...Option) bool {
+	opts = InferKeys(a1, n, opts...)
 	o := refine(newOptions(opts), nil)
 	return a1.equals(n, o)
 }
@@ -43,13 +46,16 @@ func (a jsonArray) hashCode(opts *options) [8]byte {
 }
 
 func (a jsonArray) Diff(n JsonNode, opts ...Option) Diff {
+	opts = InferKeys(a, n, opts...)
 	// We need to refine to extract global options (SET, MULTISET, etc.) for dispatch,
 	// but we want to preserve PathOptions. So we do a selective refine.
-	o := a.refineForArrayDispatch(newOptions(opts))
+	op := newOptions(opts)
+	o := a.refineForArrayDispatch(op)
 	n1 := dispatch(a, o)
 	n2 := dispatch(n, o)
 	strategy := getPatchStrategy(o)
-	return n1.diff(n2, make(Path, 0), o, strategy)
+	d := n1.diff(n2, make(Path, 0), o, strategy)
+	return withMoves(a, d, op)
 }
 
 // refineForArrayDispatch extracts global options for dispatch while preserving PathOptions
@@ -59,16 +65,19 @@ func (a jsonArray) refineForArrayDispatch(opts *options) *options {
 	for _, opt := range opts.retain {
 		switch o := opt.(type) {
 		// Global options - extract to apply for dispatch to work
-		case mergeOption, setOption, multisetOption, colorOption, precisionOption, setKeysOption:
+		case mergeOption, setOption, multisetOption, colorOption, precisionOption, relativeOption, ulpOption, setKeysOption, similarityOption, coerceOption,
+			ignoreCaseOption, trimSpaceOption, collapseSpaceOption, nfcOption, nfkcOption, patternOption, embeddedOption:
 			apply = append(apply, o)
 			retain = append(retain, o)
+		case budgetOption:
+			retain = append(retain, o)
 		case pathOption:
 			// Special case: PathOption with empty path should have its options applied for dispatch
 			if len(o.At) == 0 {
 				// Extract dispatch-relevant options from the PathOption
 				for _, thenOpt := range o.Then {
 					switch thenOpt.(type) {
-					case setOption, multisetOption:
+					case setOption, multisetOption, setKeysOption:
 						apply = append(apply, thenOpt)
 					}
 				}
@@ -82,6 +91,7 @@ func (a jsonArray) refineForArrayDispatch(opts *options) *options {
 		apply:     apply,
 		retain:    retain,
 		diffingOn: opts.diffingOn,
+		budget:    opts.budget,
 	}
 }
 
@@ -101,6 +111,14 @@ func (a jsonArray) Patch(d Diff) (JsonNode, error) {
 	return patchAll(a, d)
 }
 
+func (a jsonArray) DiffContext(ctx context.Context, n JsonNode, opts ...Option) (Diff, error) {
+	return diffContext(ctx, a, n, opts)
+}
+
+func (a jsonArray) PatchContext(ctx context.Context, d Diff) (JsonNode, error) {
+	return patchContext(ctx, a, d)
+}
+
 func (a jsonArray) patch(pathBehind, pathAhead Path, before, oldValues, newValues, after []JsonNode, strategy patchStrategy) (JsonNode, error) {
 	_, metadata, _ := pathAhead.next()
 	o := refine(&options{retain: metadata}, nil)
@@ -0,0 +1,162 @@
+package jd
+
+import (
+	"encoding/json"
+	"sort"
+)
+
+type autoKeysOption struct{}
+
+// AUTO_KEYS compares arrays of objects as sets keyed by an identity
+// field inferred from both sides of the Diff or comparison. See
+// InferKeys.
+var AUTO_KEYS = autoKeysOption{}
+
+func (o autoKeysOption) isOption() {}
+func (o autoKeysOption) MarshalJSON() ([]byte, error) {
+	return json.Marshal("AUTO_KEYS")
+}
+
+// preferredKeys are tried in order before any other field.
+var preferredKeys = []string{"id", "name", "key", "uid"}
+
+// InferKeys replaces AUTO_KEYS in opts with a PathOption setting the
+// SetKeys of each array of objects in a and b which has an identity
+// field. Arrays at the same path, ignoring indices, share one identity.
+// It is the first of "id", "name", "key" and "uid", or else of the other
+// fields in sorted order, which every element has as a string, number
+// or boolean unique within its array. Arrays with fewer than two
+// elements on both sides are left alone.
+//
+// Diff and Equals call InferKeys themselves. Rendering the Diff with the returned
+// options records the chosen keys in the options header so the same
+// Diff can be produced later without AUTO_KEYS.
+func InferKeys(a, b JsonNode, opts ...Option) []Option {
+	i := -1
+	for j, o := range opts {
+		if _, ok := o.(autoKeysOption); ok {
+			i = j
+			break
+		}
+	}
+	if i < 0 {
+		return opts
+	}
+	arrays := &keyedArrays{index: map[string]int{}}
+	arrays.collect(a, Path{})
+	arrays.collect(b, Path{})
+	inferred := []Option{}
+	for _, at := range arrays.paths {
+		if key, ok := arrays.identity(at); ok {
+			inferred = append(inferred, PathOption(at, SetKeys(key)))
+		}
+	}
+	out := append([]Option{}, opts[:i]...)
+	out = append(out, inferred...)
+	for _, o := range opts[i+1:] {
+		if _, ok := o.(autoKeysOption); !ok {
+			out = append(out, o)
+		}
+	}
+	return out
+}
+
+// keyedArrays are the arrays found at each path with indices replaced
+// by PathAllValues, in the order the paths were first found.
+type keyedArrays struct {
+	paths  []Path
+	index  map[string]int
+	arrays [][][]JsonNode
+}
+
+func (k *keyedArrays) collect(n JsonNode, p Path) {
+	switch n := n.(type) {
+	case jsonObject:
+		keys := make([]string, 0, len(n))
+		for key := range n {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			k.collect(n[key], append(p.clone(), PathKey(key)))
+		}
+	case jsonArray:
+		k.add(p, n)
+	case jsonList:
+		k.add(p, n)
+	case jsonSet:
+		k.add(p, n)
+	case jsonMultiset:
+		k.add(p, n)
+	}
+}
+
+func (k *keyedArrays) add(p Path, elements []JsonNode) {
+	key := p.JsonNode().Json()
+	i, ok := k.index[key]
+	if !ok {
+		i = len(k.paths)
+		k.index[key] = i
+		k.paths = append(k.paths, p)
+		k.arrays = append(k.arrays, nil)
+	}
+	k.arrays[i] = append(k.arrays[i], elements)
+	for _, e := range elements {
+		k.collect(e, append(p.clone(), PathAllValues{}))
+	}
+}
+
+// identity returns the identity field of the arrays at p.
+func (k *keyedArrays) identity(p Path) (string, bool) {
+	arrays := k.arrays[k.index[p.JsonNode().Json()]]
+	longest := 0
+	fields := map[string]bool{}
+	for _, a := range arrays {
+		longest = max(longest, len(a))
+		for _, e := range a {
+			o, ok := e.(jsonObject)
+			if !ok {
+				return "", false
+			}
+			for field := range o {
+				fields[field] = true
+			}
+		}
+	}
+	if longest < 2 {
+		return "", false
+	}
+	others := []string{}
+	for field := range fields {
+		others = append(others, field)
+	}
+	sort.Strings(others)
+	for _, field := range append(preferredKeys, others...) {
+		if fields[field] && identifies(arrays, field) {
+			return field, true
+		}
+	}
+	return "", false
+}
+
+// identifies reports whether every element of every array has a
+// unique scalar value for field.
+func identifies(arrays [][]JsonNode, field string) bool {
+	for _, a := range arrays {
+		seen := map[[8]byte]bool{}
+		for _, e := range a {
+			v := e.(jsonObject)[field]
+			switch v.(type) {
+			case jsonString, jsonNumber, jsonDecimal, jsonBool:
+			default:
+				return false
+			}
+			h := v.hashCode(newOptions(nil))
+			if seen[h] {
+				return false
+			}
+			seen[h] = true
+		}
+	}
+	return true
+}
@@ -0,0 +1,196 @@
+package jd
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestInferKeys(t *testing.T) {
+	cases := []struct {
+		name    string
+		a       string
+		b       string
+		options []Option
+		want    string
+	}{{
+		name:    "no auto keys",
+		a:       `[{"id":1},{"id":2}]`,
+		b:       `[{"id":2},{"id":1}]`,
+		options: []Option{SET},
+		want:    `["SET"]`,
+	}, {
+		name:    "root array",
+		a:       `[{"id":1,"v":1},{"id":2,"v":2}]`,
+		b:       `[{"id":2,"v":3},{"id":1,"v":1}]`,
+		options: []Option{AUTO_KEYS},
+		want:    `[{"@":[],"^":[{"keys":["id"]}]}]`,
+	}, {
+		name:    "preferred keys in order",
+		a:       `{"a":[{"uid":1,"key":"x","name":"n1"},{"uid":2,"key":"y","name":"n2"}]}`,
+		b:       `{}`,
+		options: []Option{AUTO_KEYS},
+		want:    `[{"@":["a"],"^":[{"keys":["name"]}]}]`,
+	}, {
+		name:    "preferred key must be unique",
+		a:       `{"a":[{"id":1,"name":"x"},{"id":1,"name":"y"}]}`,
+		b:       `{"a":[{"id":2,"name":"x"}]}`,
+		options: []Option{AUTO_KEYS},
+		want:    `[{"@":["a"],"^":[{"keys":["name"]}]}]`,
+	}, {
+		name:    "any unique field",
+		a:       `{"a":[{"port":80,"proto":"tcp"},{"port":443,"proto":"tcp"}]}`,
+		b:       `{"a":[{"port":443,"proto":"tcp"},{"port":8080,"proto":"udp"}]}`,
+		options: []Option{AUTO_KEYS},
+		want:    `[{"@":["a"],"^":[{"keys":["port"]}]}]`,
+	}, {
+		name:    "field missing from an element",
+		a:       `{"a":[{"id":1},{"x":2}]}`,
+		b:       `{"a":[{"id":1},{"id":2}]}`,
+		options: []Option{AUTO_KEYS},
+		want:    `[]`,
+	}, {
+		name:    "values must be scalars",
+		a:       `{"a":[{"id":[1]},{"id":null},{"id":{}}]}`,
+		b:       `{}`,
+		options: []Option{AUTO_KEYS},
+		want:    `[]`,
+	}, {
+		name:    "arrays of other values",
+		a:       `{"a":[{"id":1},2],"b":[1,2]}`,
+		b:       `{}`,
+		options: []Option{AUTO_KEYS},
+		want:    `[]`,
+	}, {
+		name:    "single elements",
+		a:       `{"a":[{"id":1}]}`,
+		b:       `{"a":[{"id":2}]}`,
+		options: []Option{AUTO_KEYS},
+		want:    `[]`,
+	}, {
+		name: "nested arrays share a path",
+		a: `{"items":[` +
+			`{"id":"web","ports":[{"name":"http","port":80},{"name":"https","port":443}]},` +
+			`{"id":"db","ports":[{"name":"pg","port":5432}]}]}`,
+		b: `{"items":[` +
+			`{"id":"db","ports":[{"name":"pg","port":5433}]},` +
+			`{"id":"web","ports":[{"name":"https","port":443},{"name":"http","port":80}]}]}`,
+		options: []Option{SET, AUTO_KEYS, DIFF_ON},
+		want: `["SET",` +
+			`{"@":["items"],"^":[{"keys":["id"]}]},` +
+			`{"@":["items",null,"ports"],"^":[{"keys":["name"]}]},` +
+			`"DIFF_ON"]`,
+	}}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			a, err := ReadJsonString(c.a)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := ReadJsonString(c.b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := json.Marshal(InferKeys(a, b, c.options...))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != c.want {
+				t.Errorf("got %v. want %v", string(got), c.want)
+			}
+		})
+	}
+}
+
+func TestAutoKeysDiff(t *testing.T) {
+	a, err := ReadJsonString(`{"items":[{"id":"a","ports":[{"name":"http","port":80},{"name":"https","port":443}]},{"id":"b","v":1}]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ReadJsonString(`{"items":[{"id":"b","v":2},{"id":"a","ports":[{"name":"https","port":8443},{"name":"http","port":80}]}]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := a.Diff(b, AUTO_KEYS)
+	want := s(
+		`^ {"@":["items"],"^":[{"keys":["id"]}]}`,
+		`^ {"@":["items",null,"ports"],"^":[{"keys":["name"]}]}`,
+		`@ ["items",{"id":"b"},"v"]`,
+		`- 1`,
+		`+ 2`,
+		`@ ["items",{"id":"a"},"ports",{"name":"https"},"port"]`,
+		`- 443`,
+		`+ 8443`,
+	)
+	options := InferKeys(a, b, AUTO_KEYS)
+	if got := d.Render(options...); got != want {
+		t.Errorf("got diff:\n%v\nwant:\n%v", got, want)
+	}
+	// The header reproduces the Diff without AUTO_KEYS.
+	read, err := ReadDiffString(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := a.Diff(b, read[0].Options...).Render(); got != d.Render() {
+		t.Errorf("got diff:\n%v\nwant:\n%v", got, d.Render())
+	}
+	patched, err := a.Patch(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rest := patched.Diff(b, options...); len(rest) != 0 {
+		t.Errorf("got diff after patching:\n%v", rest.Render())
+	}
+}
+
+func TestAutoKeysEquals(t *testing.T) {
+	cases := []struct {
+		a    string
+		b    string
+		want bool
+	}{
+		{`[{"id":1},{"id":2}]`, `[{"id":2},{"id":1}]`, true},
+		{`{"a":[{"id":1,"v":1},{"id":2}]}`, `{"a":[{"id":2},{"id":1,"v":1}]}`, true},
+		{`[{"id":1},{"id":2}]`, `[{"id":2},{"id":3}]`, false},
+		{`[1,2]`, `[2,1]`, false},
+	}
+	for _, c := range cases {
+		a, b := mustParse(t, c.a), mustParse(t, c.b)
+		if got := a.Equals(b, AUTO_KEYS); got != c.want {
+			t.Errorf("%v equals %v: got %v. want %v", c.a, c.b, got, c.want)
+		}
+		if got := len(a.Diff(b, AUTO_KEYS)) == 0; got != c.want {
+			t.Errorf("%v diff %v is empty: got %v. want %v", c.a, c.b, got, c.want)
+		}
+	}
+	// Dispatched arrays agree too.
+	b := mustParse(t, `[{"id":2},{"id":1}]`)
+	for _, n := range []JsonNode{
+		jsonList{jsonObject{"id": jsonNumber(1)}, jsonObject{"id": jsonNumber(2)}},
+		jsonSet{jsonObject{"id": jsonNumber(1)}, jsonObject{"id": jsonNumber(2)}},
+		jsonMultiset{jsonObject{"id": jsonNumber(1)}, jsonObject{"id": jsonNumber(2)}},
+	} {
+		if equal, empty := n.Equals(b, AUTO_KEYS), len(n.Diff(b, AUTO_KEYS)) == 0; equal != empty {
+			t.Errorf("%T: Equals got %v but the diff is empty: %v", n, equal, empty)
+		}
+	}
+}
+
+func TestInferKeysDispatchedArrays(t *testing.T) {
+	elements := []JsonNode{
+		jsonObject{"id": jsonNumber(1)},
+		jsonObject{"id": jsonNumber(2)},
+	}
+	for _, n := range []JsonNode{
+		jsonList(elements),
+		jsonSet(elements),
+		jsonMultiset(elements),
+	} {
+		got, err := json.Marshal(InferKeys(n, voidNode{}, AUTO_KEYS))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := `[{"@":[],"^":[{"keys":["id"]}]}]`; string(got) != want {
+			t.Errorf("got %v inferring keys of %T. want %v", string(got), n, want)
+		}
+	}
+}
@@ -1,5 +1,7 @@
 package jd
 
+import "context"
+
 type jsonBool bool
 
 var _ JsonNode = jsonBool(true)
@@ -16,12 +18,13 @@ func (b jsonBool) raw() interface{} {
 	return bool(b)
 }
 
-func (b1 jsonBool) Equals(n JsonNode, _ ...Option) bool {
-	return b1.equals(n, nil)
+func (b1 jsonBool) Equals(n JsonNode, opts ...Option) bool {
+	o := refine(&options{retain: opts}, nil)
+	return b1.equals(n, o)
 }
 
-func (b1 jsonBool) equals(n JsonNode, _ *options) bool {
-	b2, ok := n.(jsonBool)
+func (b1 jsonBool) equals(n JsonNode, o *options) bool {
+	b2, ok := coerced(n, o).(jsonBool)
 	if !ok {
 		return false
 	}
@@ -58,6 +61,14 @@ func (b jsonBool) Patch(d Diff) (JsonNode, error) {
 	return patchAll(b, d)
 }
 
+func (b jsonBool) DiffContext(ctx context.Context, n JsonNode, opts ...Option) (Diff, error) {
+	return diffContext(ctx, b, n, opts)
+}
+
+func (b jsonBool) PatchContext(ctx context.Context, d Diff) (JsonNode, error) {
+	return patchContext(ctx, b, d)
+}
+
 func (b jsonBool) patch(
 	pathBehind, pathAhead Path,
 	before, oldValues, newValues, after []JsonNode,
@@ -0,0 +1,49 @@
+package jd
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+type coerceOption struct{}
+
+// COERCE compares a string holding a JSON number, boolean or null as the
+// typed value, so "1" equals 1 and 1.0, "true" equals true and "null"
+// equals null. Other strings are compared as strings.
+var COERCE = coerceOption{}
+
+func (o coerceOption) isOption() {}
+func (o coerceOption) MarshalJSON() ([]byte, error) {
+	return json.Marshal("COERCE")
+}
+
+// coerced returns the typed value held by the string n when COERCE
+// applies and n itself otherwise.
+func coerced(n JsonNode, o *options) JsonNode {
+	s, ok := n.(jsonString)
+	if !ok || o == nil {
+		return n
+	}
+	if _, ok := getOption[coerceOption](o); !ok {
+		return n
+	}
+	switch s {
+	case "true":
+		return jsonBool(true)
+	case "false":
+		return jsonBool(false)
+	case "null":
+		return jsonNull{}
+	}
+	if s == "" || strings.TrimSpace(string(s)) != string(s) || !json.Valid([]byte(s)) {
+		return n
+	}
+	if c := s[0]; c != '-' && (c < '0' || c > '9') {
+		return n
+	}
+	number, err := newNumber(string(s))
+	if err != nil { //jd:nocover — s is a valid JSON number
+		return n
+	}
+	return number
+}
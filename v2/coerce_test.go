@@ -0,0 +1,148 @@
+package jd
+
+import (
+	"testing"
+)
+
+func TestCoerceDiff(t *testing.T) {
+	cases := []struct {
+		name    string
+		options []Option
+		a       string
+		b       string
+		want    []string
+	}{{
+		name:    "typed values",
+		options: []Option{COERCE},
+		a:       `{"a":"1","b":"true","c":"null","d":"1.50","e":"false","f":"x"}`,
+		b:       `{"a":1,"b":true,"c":null,"d":1.5,"e":false,"f":"y"}`,
+		want: ss(
+			`@ ["f"]`,
+			`- "x"`,
+			`+ "y"`,
+		),
+	}, {
+		name:    "typed values on the left",
+		options: []Option{COERCE},
+		a:       `[1,true,null,12345678901234567890]`,
+		b:       `["1","true","null","12345678901234567890"]`,
+		want:    ss(),
+	}, {
+		name:    "without coerce",
+		options: []Option{},
+		a:       `{"a":"1"}`,
+		b:       `{"a":1}`,
+		want: ss(
+			`@ ["a"]`,
+			`- "1"`,
+			`+ 1`,
+		),
+	}, {
+		name:    "strings which are not scalars",
+		options: []Option{COERCE},
+		a:       `[" 1","+1",".5","True","","1x","-","[1]"]`,
+		b:       `[1,1,0.5,true,null,1,0,[1]]`,
+		want: ss(
+			`@ [0]`,
+			`[`,
+			`- " 1"`,
+			`- "+1"`,
+			`- ".5"`,
+			`- "True"`,
+			`- ""`,
+			`- "1x"`,
+			`- "-"`,
+			`- "[1]"`,
+			`+ 1`,
+			`+ 1`,
+			`+ 0.5`,
+			`+ true`,
+			`+ null`,
+			`+ 1`,
+			`+ 0`,
+			`+ [1]`,
+			`]`,
+		),
+	}, {
+		name:    "different values",
+		options: []Option{COERCE},
+		a:       `{"a":"1","b":"true"}`,
+		b:       `{"a":2,"b":false}`,
+		want: ss(
+			`@ ["a"]`,
+			`- "1"`,
+			`+ 2`,
+			`@ ["b"]`,
+			`- "true"`,
+			`+ false`,
+		),
+	}, {
+		name:    "path option",
+		options: []Option{PathOption(Path{PathKey("a")}, COERCE)},
+		a:       `{"a":"1","b":"1"}`,
+		b:       `{"a":1,"b":1}`,
+		want: ss(
+			`@ ["b"]`,
+			`- "1"`,
+			`+ 1`,
+		),
+	}, {
+		name:    "set",
+		options: []Option{SET, COERCE},
+		a:       `{"a":[{"id":"1"},"2","false"]}`,
+		b:       `{"a":[false,2,{"id":1}]}`,
+		want:    ss(),
+	}, {
+		name:    "multiset",
+		options: []Option{MULTISET, COERCE},
+		a:       `["1","1",null]`,
+		b:       `["null",1,1]`,
+		want:    ss(),
+	}, {
+		name:    "multiset of equal strings and numbers",
+		options: []Option{MULTISET, COERCE},
+		a:       `["1",1,5]`,
+		b:       `[5]`,
+		want: ss(
+			`@ [[]]`,
+			`- "1"`,
+			`- 1`,
+		),
+	}, {
+		name:    "set keys",
+		options: []Option{SetKeys("id"), COERCE},
+		a:       `[{"id":"1","v":"2"},{"id":"2","v":1}]`,
+		b:       `[{"id":2,"v":"1"},{"id":1,"v":3}]`,
+		want: ss(
+			`@ [{"id":"1"},"v"]`,
+			`- "2"`,
+			`+ 3`,
+		),
+	}}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ctx := newTestContext(t).withOptions(c.options...)
+			checkDiff(ctx, c.a, c.b, c.want...)
+			checkRoundTrip(t, c.a, c.b, c.options)
+		})
+	}
+}
+
+func TestCoerceEquals(t *testing.T) {
+	for _, c := range []struct {
+		a, b JsonNode
+	}{
+		{jsonBool(true), jsonString("true")},
+		{jsonNull{}, jsonString("null")},
+		{jsonNumber(10), jsonString("1e1")},
+		{jsonDecimal("0.10000000000000000001"), jsonString("0.10000000000000000001")},
+		{jsonString("-0"), jsonNumber(0)},
+	} {
+		if !c.a.Equals(c.b, COERCE) {
+			t.Errorf("%v does not equal %v with COERCE", c.a.Json(), c.b.Json())
+		}
+		if c.a.Equals(c.b) {
+			t.Errorf("%v equals %v without COERCE", c.a.Json(), c.b.Json())
+		}
+	}
+}
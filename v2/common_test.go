@@ -99,6 +99,26 @@ func checkDiff(ctx *testContext, a, b string, diffLines ...string) {
 	}
 }
 
+// checkRoundTrip checks that the diff of a and b, written and read
+// back, patches a to a value equal to b.
+func checkRoundTrip(t *testing.T, a, b string, opts []Option) {
+	t.Helper()
+	nodeA, _ := ReadJsonString(a)
+	nodeB, _ := ReadJsonString(b)
+	d, err := ReadDiffString(nodeA.Diff(nodeB, opts...).Render())
+	if err != nil {
+		t.Fatal(err)
+	}
+	nodeA, _ = ReadJsonString(a)
+	got, err := nodeA.Patch(d)
+	if err != nil {
+		t.Fatalf("patching %v: %v", a, err)
+	}
+	if !got.Equals(nodeB, opts...) {
+		t.Errorf("patching %v gave %v. Want %v", a, got.Json(), b)
+	}
+}
+
 func checkPatch(ctx *testContext, a, e string, diffLines ...string) {
 	diffString := ""
 	for _, dl := range diffLines {
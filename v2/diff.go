@@ -1,5 +1,7 @@
 package jd
 
+import "encoding/json"
+
 // DiffElement (hunk) is a way in which two JsonNodes differ at a given
 // Path. OldValues can be removed and NewValues can be added. The exact
 // Path and how to interpret the intervening structure is determined by a
@@ -45,6 +47,15 @@ type DiffElement struct {
 	// new and old values of a diff element. They are only used
 	// for diffs in a list element.
 	After []JsonNode
+
+	// From is the Path of a value which is moved to Path. A hunk
+	// with From has no Before, Remove, Add or After. Moves are only
+	// produced with the DETECT_MOVES option.
+	From Path
+
+	// Copy leaves the value at From in place, copying it to Path
+	// instead of moving it.
+	Copy bool
 }
 
 // Diff describes how two JsonNodes differ from each other. A Diff is
@@ -57,7 +68,52 @@ type Diff []DiffElement
 
 // JSON Patch (RFC 6902)
 type patchElement struct {
-	Op    string      `json:"op"`   // "add", "test" or "remove"
+	Op    string      `json:"op"`   // "add", "test", "remove", "replace", "move" or "copy"
+	From  string      `json:"from"` // JSON Pointer (RFC 6901) for "move" and "copy"
 	Path  string      `json:"path"` // JSON Pointer (RFC 6901)
 	Value interface{} `json:"value"`
 }
+
+// MarshalJSON writes "from" only for "move" and "copy", which have no
+// "value". A "remove" without a value to check has no "value" either.
+func (e patchElement) MarshalJSON() ([]byte, error) {
+	switch {
+	case e.Op == "remove" && e.Value == nil:
+		return json.Marshal(struct {
+			Op   string `json:"op"`
+			Path string `json:"path"`
+		}{e.Op, e.Path})
+	case e.Op == "move" || e.Op == "copy":
+		return json.Marshal(struct {
+			Op   string `json:"op"`
+			From string `json:"from"`
+			Path string `json:"path"`
+		}{e.Op, e.From, e.Path})
+	default:
+		return json.Marshal(struct {
+			Op    string      `json:"op"`
+			Path  string      `json:"path"`
+			Value interface{} `json:"value"`
+		}{e.Op, e.Path, e.Value})
+	}
+}
+
+// jsonHunk is the JSON encoding of a DiffElement written by
+// RenderJsonHunks and read by ReadJsonHunksString. Void values have no
+// JSON encoding so they are left out of the value lists and flagged
+// instead: void context marks the start or end of a list and a void
+// Add deletes the value at Path under merge semantics.
+type jsonHunk struct {
+	Metadata    Metadata          `json:"Metadata"`
+	Options     []json.RawMessage `json:"Options,omitempty"`
+	Path        json.RawMessage   `json:"Path"`
+	Before      []json.RawMessage `json:"Before"`
+	Remove      []json.RawMessage `json:"Remove"`
+	Add         []json.RawMessage `json:"Add"`
+	After       []json.RawMessage `json:"After"`
+	From        json.RawMessage   `json:"From,omitempty"`
+	Copy        bool              `json:"Copy,omitempty"`
+	StartOfList bool              `json:"StartOfList,omitempty"`
+	EndOfList   bool              `json:"EndOfList,omitempty"`
+	Delete      bool              `json:"Delete,omitempty"`
+}
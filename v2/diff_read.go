@@ -2,8 +2,12 @@ package jd
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"reflect"
+	"sort"
 	"strings"
 )
 
@@ -32,6 +36,7 @@ func readDiff(s string) (Diff, error) {
 		REMOVE = iota
 		ADD    = iota
 		AFTER  = iota
+		FROM   = iota
 	)
 	var de DiffElement
 	var state = INIT
@@ -56,7 +61,7 @@ func readDiff(s string) (Diff, error) {
 		case META:
 			allow("^", "@")
 		case AT:
-			allow("[", " ", "-", "+")
+			allow("[", " ", "-", "+", "<", "=")
 		case BEFORE:
 			allow(" ", "-", "+")
 		case REMOVE:
@@ -65,24 +70,27 @@ func readDiff(s string) (Diff, error) {
 			allow("+", " ", "]", "^", "@")
 		case AFTER:
 			allow(" ", "]", "^", "@")
+		case FROM:
+			allow("^", "@")
 		}
 		if transitionErr != nil {
-			return errorAt(i, transitionErr)
+			return errorAt(i, 1, transitionErr)
 		}
 		// Process line.
 		switch header {
 		case "^":
-			if state == ADD || state == REMOVE {
+			if state == ADD || state == REMOVE || state == FROM {
 				// Save the previous diff element.
 				err := checkDiffElement(de)
 				if err != nil {
-					return errorAt(i, err)
+					return errorAt(i, 1, err)
 				}
 				diff = append(diff, de)
+				de.Metadata.Unchecked, de.Metadata.Upsert = false, false
 			}
 			n, err := ReadJsonString(dl[1:])
 			if err != nil {
-				return errorfAt(i, "Invalid Metadata. %v", err.Error())
+				return errorfAt(i, valueCol(dl, err), "Invalid Metadata. %v", err.Error())
 			}
 			// Try to parse as an Option first
 			opt, err := NewOption(n.raw())
@@ -93,11 +101,25 @@ func readDiff(s string) (Diff, error) {
 					// Neither option nor legacy metadata worked, skip this line
 					continue
 				}
-				de.Metadata = de.Metadata.merge(m) //jd:nocover — NewOption handles all readMetadata inputs
+				switch {
+				case m.Unchecked:
+					// Only the next hunk is unchecked.
+					de.Metadata.Unchecked = true
+					de.Metadata.Upsert = m.Upsert
+				case !m.Merge:
+					// {"Merge":false} ends merge semantics inherited from previous hunks.
+					de.Metadata.Merge = false
+					de.Options = withoutOption[mergeOption](de.Options)
+				}
+				de.Metadata = de.Metadata.merge(m)
 			} else {
 				// Successfully parsed as option, check for duplicates before storing
 				isDuplicate := false
 				for _, existingOpt := range de.Options {
+					if _, ok := opt.(pathOption); ok && !reflect.DeepEqual(existingOpt, opt) {
+						// PathOptions for different paths accumulate.
+						continue
+					}
 					if fmt.Sprintf("%T", existingOpt) == fmt.Sprintf("%T", opt) {
 						// Same type of option already exists, skip to avoid duplication
 						if _, isMerge := opt.(mergeOption); isMerge {
@@ -118,37 +140,40 @@ func readDiff(s string) (Diff, error) {
 			}
 			state = META
 		case "@":
-			if state == ADD || state == REMOVE || state == AFTER {
+			if state == ADD || state == REMOVE || state == AFTER || state == FROM {
 				// Save the previous diff element.
 				err := checkDiffElement(de)
 				if err != nil {
-					return errorAt(i, err)
+					return errorAt(i, 1, err)
 				}
 				diff = append(diff, de)
+				de.Metadata.Unchecked, de.Metadata.Upsert = false, false
 			}
 			p, err := ReadJsonString(dl[1:])
 			if err != nil {
-				return errorfAt(i, "Invalid path. %v", err.Error())
+				return errorfAt(i, valueCol(dl, err), "Invalid path. %v", err.Error())
 			}
 			path, err := NewPath(p)
 			if err != nil {
-				return errorAt(i, err)
+				return errorAt(i, 2, err)
 			}
 			de.Path = path
 			de.Before = []JsonNode{}
 			de.Remove = []JsonNode{}
 			de.Add = []JsonNode{}
 			de.After = []JsonNode{}
+			de.From = nil
+			de.Copy = false
 			state = AT
 		case "[":
 			if state != AT { //jd:nocover — only AT allows "["
-				return errorfAt(i, "Invalid context. [ must appear immediately after @")
+				return errorfAt(i, 1, "Invalid context. [ must appear immediately after @")
 			}
 			de.Before = append(de.Before, voidNode{})
 			state = BEFORE
 		case "]":
 			if state != REMOVE && state != ADD && state != AFTER { //jd:nocover — only those 3 allow "]"
-				return errorfAt(i, "Invalid context. ] must appear at the end of the context")
+				return errorfAt(i, 1, "Invalid context. ] must appear at the end of the context")
 			}
 			de.After = append(de.After, voidNode{})
 			state = AFTER
@@ -158,53 +183,68 @@ func readDiff(s string) (Diff, error) {
 				// Accumulate before context
 				b, err := ReadJsonString(dl[1:])
 				if err != nil {
-					return errorfAt(i, "Invalid context. %v", err.Error())
+					return errorfAt(i, valueCol(dl, err), "Invalid context. %v", err.Error())
 				}
 				de.Before = append(de.Before, b)
 				state = BEFORE
 			case state == ADD || state == REMOVE || state == AFTER:
 				a, err := ReadJsonString(dl[1:])
 				if err != nil {
-					return errorfAt(i, "Invalid context. %v", err.Error())
+					return errorfAt(i, valueCol(dl, err), "Invalid context. %v", err.Error())
 				}
 				de.After = append(de.After, a)
 				// Accumulate after context
 				state = AFTER
 			default: //jd:nocover — all states allowing " " are handled above
-				return errorfAt(i, "Invalid context. Must preceed or follow + or -")
+				return errorfAt(i, 1, "Invalid context. Must preceed or follow + or -")
 			}
 		case "-":
 			v, err := ReadJsonString(dl[1:])
 			if err != nil {
-				return errorfAt(i, "Invalid value. %v", err.Error())
+				return errorfAt(i, valueCol(dl, err), "Invalid value. %v", err.Error())
 			}
 			de.Remove = append(de.Remove, v)
 			state = REMOVE
 		case "+":
 			v, err := ReadJsonString(dl[1:])
 			if err != nil {
-				return errorfAt(i, "Invalid value. %v", err.Error())
+				return errorfAt(i, valueCol(dl, err), "Invalid value. %v", err.Error())
 			}
 			de.Add = append(de.Add, v)
 			state = ADD
+		case "<", "=":
+			p, err := ReadJsonString(dl[1:])
+			if err != nil {
+				return errorfAt(i, valueCol(dl, err), "Invalid path. %v", err.Error())
+			}
+			from, err := NewPath(p)
+			if err != nil {
+				return errorAt(i, 2, err)
+			}
+			if len(from) == 0 {
+				return errorfAt(i, 2, "Invalid path. Expecting a path to move or copy from.")
+			}
+			de.From = from
+			de.Copy = header == "="
+			state = FROM
 		default: //jd:nocover — all allowed headers have explicit cases
-			errorfAt(i, "Unexpected %v.", dl[0])
+			errorfAt(i, 1, "Unexpected %v.", dl[0])
 		}
 	}
 	if state == META {
 		// ^ is not a valid terminal state.
-		return errorfAt(len(diffLines), "Unexpected end of diff. Expecting ^ or @.")
+		return errorfAt(len(diffLines), 1, "Unexpected end of diff. Expecting ^ or @.")
 	}
 	if state == AT {
 		// @ is not a valid terminal state.
-		return errorfAt(len(diffLines), "Unexpected end of diff. Expecting - or +.")
+		return errorfAt(len(diffLines), 1, "Unexpected end of diff. Expecting -, +, < or =.")
 	}
 	if state != INIT {
 		// Save the last diff element.
 		// Empty string diff is valid so state could be INIT
 		err := checkDiffElement(de)
 		if err != nil {
-			return errorAt(len(diffLines), err)
+			return errorAt(len(diffLines), 1, err)
 		}
 		diff = append(diff, de)
 	}
@@ -212,6 +252,9 @@ func readDiff(s string) (Diff, error) {
 }
 
 func checkDiffElement(de DiffElement) error {
+	if de.Path.hasWildcard() || de.From.hasWildcard() {
+		return fmt.Errorf("wildcards only select values in PathOptions")
+	}
 	if len(de.Add) > 1 || len(de.Remove) > 1 {
 		// Must be an array-based type
 		if len(de.Path) == 0 {
@@ -227,14 +270,29 @@ func checkDiffElement(de DiffElement) error {
 	return nil
 }
 
-func errorAt(lineZeroIndex int, err error) (Diff, error) {
-	return errorfAt(lineZeroIndex, "%v", err.Error())
+func errorAt(lineZeroIndex, col int, err error) (Diff, error) {
+	return errorfAt(lineZeroIndex, col, "%v", err.Error())
+}
+
+func errorfAt(lineZeroIndex, col int, err string, i ...interface{}) (Diff, error) {
+	return nil, &ParseError{
+		Line:    lineZeroIndex + 1,
+		Col:     col,
+		Message: fmt.Sprintf(err, i...),
+	}
 }
 
-func errorfAt(lineZeroIndex int, err string, i ...interface{}) (Diff, error) {
-	line := lineZeroIndex + 1
-	e := fmt.Sprintf(err, i...)
-	return nil, fmt.Errorf("invalid diff at line %v. %v", line, e)
+// valueCol is the column of err in diff line dl, which was returned
+// reading the JSON after the line's one character header.
+func valueCol(dl string, err error) int {
+	var syntaxErr *json.SyntaxError
+	switch {
+	case errors.As(err, &syntaxErr):
+		return 1 + int(syntaxErr.Offset)
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return len(dl) + 1
+	}
+	return 2
 }
 
 // ReadPatchFile reads a JSON Patch (RFC 6902) from a file. It is subject
@@ -247,10 +305,10 @@ func ReadPatchFile(filename string) (Diff, error) {
 	return ReadPatchString(string(bytes))
 }
 
-// ReadPatchString reads a JSON Patch (RFC 6902) from a
-// string. ReadPatchString supports a subset of the specification and
-// requires a sequence of "test", "remove", "add" operations which mimics
-// the strict patching strategy of a native jd patch.
+// ReadPatchString reads a JSON Patch (RFC 6902) from a string. A
+// sequence of "test", "remove", "add" operations, as written by
+// RenderPatch, mimics the strict patching strategy of a native jd
+// patch and becomes a strict hunk.
 //
 // For example:
 //
@@ -259,9 +317,23 @@ func ReadPatchFile(filename string) (Diff, error) {
 //	  {"op":"remove","path":"/foo","value":"bar"},
 //	  {"op":"add","path":"/foo","value":"baz"}
 //	]
+//
+// The other operations are read as follows:
+//
+//   - A "test" followed by a "replace" of the same path becomes a strict
+//     hunk which removes the tested value.
+//   - A "test" on its own becomes a strict hunk which replaces the value
+//     with itself.
+//   - A "replace" or "remove" without a "test" has no value to check so
+//     it becomes a hunk which removes whatever value is found when it
+//     is applied by Patch. Patch fails when there is none.
+//   - An "add" of an object member without a "test" replaces the member
+//     when it is found and adds it otherwise. An "add" at a list index
+//     inserts the value.
+//   - A "move" or "copy" becomes a hunk with From set.
 func ReadPatchString(s string) (Diff, error) {
 	var patch []patchElement
-	err := json.Unmarshal([]byte(s), &patch)
+	err := unmarshalJson([]byte(s), &patch)
 	if err != nil {
 		return nil, err
 	}
@@ -283,10 +355,14 @@ func ReadPatchString(s string) (Diff, error) {
 			diff = append(diff, e)
 		} else {
 			i := len(diff) - 1
-			if diff[i].Path.JsonNode().Equals(e.Path.JsonNode()) {
+			if coalesce(diff[i], e) {
 				diff[i].Remove = append(diff[i].Remove, e.Remove...)
-				// Must be done in reverse order
-				diff[i].Add = append(e.Add, diff[i].Add...)
+				if isAppend(e.Path) {
+					diff[i].Add = append(diff[i].Add, e.Add...)
+				} else {
+					// Each add inserts before the last.
+					diff[i].Add = append(e.Add, diff[i].Add...)
+				}
 			} else {
 				diff = append(diff, e)
 			}
@@ -294,6 +370,31 @@ func ReadPatchString(s string) (Diff, error) {
 	}
 }
 
+// isAppend reports whether p ends with the "-" index of a JSON Pointer,
+// which appends to a list.
+func isAppend(p Path) bool {
+	return len(p) > 0 && p[len(p)-1] == PathIndex(-1)
+}
+
+// coalesce reports whether hunk e read from a JSON Patch can be folded
+// into the previous hunk. Removals and additions at the same list
+// index are folded in order, removals first.
+func coalesce(prev, e DiffElement) bool {
+	switch {
+	case prev.Metadata.Merge || e.Metadata.Merge || prev.Metadata.Unchecked:
+		return false
+	case e.Metadata.Unchecked:
+		// A removal followed by an add of the member is a replacement.
+		return e.Metadata.Upsert && len(prev.Remove) > 0 && len(prev.Add) == 0 &&
+			prev.Path.JsonNode().Equals(e.Path.JsonNode())
+	case prev.From != nil || e.From != nil:
+		return false
+	case len(prev.Add) > 0 && len(e.Remove) > 0:
+		return false
+	}
+	return prev.Path.JsonNode().Equals(e.Path.JsonNode())
+}
+
 // setPatchDiffElementContext detects before and/or after context and
 // sets it on the diff element. It returns what remains of the
 // patch. We expect exactly zero or one test before and zero or one
@@ -305,12 +406,16 @@ func setPatchDiffElementContext(patch []patchElement, d *DiffElement) ([]patchEl
 	if len(patch) == 0 {
 		return nil, fmt.Errorf("unexpected end of JSON Patch")
 	}
-	if len(patch) == 1 || patch[0].Op != "test" {
+	if patch[0].Op != "test" {
 		// No before or after context.
 		d.Before = []JsonNode{voidNode{}}
 		d.After = []JsonNode{voidNode{}}
 		return patch, nil
 	}
+	if len(patch) == 1 {
+		// A test on its own.
+		return patch, nil
+	}
 	// To tell if this has context and a change or just lines of
 	// change, we need to compare the indices.
 	path, err := readPointer(patch[0].Path)
@@ -342,13 +447,17 @@ func setPatchDiffElementContext(patch []patchElement, d *DiffElement) ([]patchEl
 	switch {
 	case firstIndex == secondIndex && (patch[1].Op == "replace" || patch[1].Op == "remove"):
 		// No before or after context.
-		d.Before = []JsonNode{voidNode{}}
-		d.After = []JsonNode{voidNode{}}
+		if firstIndex == 0 {
+			d.Before = []JsonNode{voidNode{}}
+			d.After = []JsonNode{voidNode{}}
+		}
 		return patch, nil
 	case firstIndex == secondIndex && (patch[1].Op == "add"):
 		// After context with add, which inserts before,
 		// moving the rest of the array forward.
-		d.Before = []JsonNode{voidNode{}}
+		if firstIndex == 0 {
+			d.Before = []JsonNode{voidNode{}}
+		}
 		after, err := NewJsonNode(patch[0].Value)
 		if err != nil { //jd:nocover — Value from json.Unmarshal is always valid
 			return nil, err
@@ -383,7 +492,7 @@ func setPatchDiffElementContext(patch []patchElement, d *DiffElement) ([]patchEl
 		return nil, fmt.Errorf("expected path for array. got %q", patch[2].Path)
 	}
 	switch {
-	case (patch[2].Op == "test" || patch[2].Op == "add") && thirdIndex <= secondIndex:
+	case (patch[2].Op == "test" || patch[2].Op == "add") && firstIndex == thirdIndex-1 && thirdIndex <= secondIndex:
 		// Before and after context.
 		before, err := NewJsonNode(patch[0].Value)
 		if err != nil { //jd:nocover — Value from json.Unmarshal is always valid
@@ -405,7 +514,7 @@ func setPatchDiffElementContext(patch []patchElement, d *DiffElement) ([]patchEl
 		}
 		d.After = []JsonNode{after}
 		return patch[1:], nil
-	case patch[1].Op == "test" && (patch[2].Op == "replace" || patch[2].Op == "remove") && firstIndex < secondIndex:
+	case patch[1].Op == "test" && (patch[2].Op == "replace" || patch[2].Op == "remove") && firstIndex == secondIndex-1:
 		// Before context with replace / remove.
 		before, err := NewJsonNode(patch[0].Value)
 		if err != nil { //jd:nocover — Value from json.Unmarshal is always valid
@@ -452,34 +561,83 @@ func readPatchDiffElement(patch []patchElement) (DiffElement, []patchElement, er
 			return d, nil, err
 		}
 		d.Remove = []JsonNode{testValue}
-		// Validate test and remove are paired because jd remove is strict.
-		if len(patch) == 1 || patch[1].Op != "remove" {
-			return d, nil, fmt.Errorf("JSON Patch test op must be followed by a remove op")
+		if len(patch) > 1 && patch[1].Path == p.Path {
+			switch patch[1].Op {
+			case "remove":
+				// The value of a remove op is optional.
+				if patch[1].Value != nil {
+					removeValue, err := NewJsonNode(patch[1].Value)
+					if err != nil { //jd:nocover — Value from json.Unmarshal is always valid
+						return d, nil, err
+					}
+					if !testValue.Equals(removeValue) {
+						return d, nil, fmt.Errorf("JSON Patch remove op must have the same value as test op")
+					}
+				}
+				return d, patch[2:], nil
+			case "replace":
+				replaceValue, err := NewJsonNode(patch[1].Value)
+				if err != nil { //jd:nocover — Value from json.Unmarshal is always valid
+					return d, nil, err
+				}
+				d.Add = []JsonNode{replaceValue}
+				return d, patch[2:], nil
+			}
 		}
-		if patch[1].Path != p.Path {
-			return d, nil, fmt.Errorf("JSON Patch remove op must have the same path as test op")
+		// A test on its own replaces the value with itself.
+		d.Add = []JsonNode{testValue}
+		return d, patch[1:], nil
+	case "add":
+		d.Path, err = readPointer(p.Path)
+		if err != nil {
+			return d, nil, err
 		}
-		removeValue, err := NewJsonNode(patch[1].Value)
+		addValue, err := NewJsonNode(p.Value)
 		if err != nil { //jd:nocover — Value from json.Unmarshal is always valid
 			return d, nil, err
 		}
-		if !testValue.Equals(removeValue) {
-			return d, nil, fmt.Errorf("JSON Patch remove op must have the same value as test op")
+		d.Add = []JsonNode{addValue}
+		// An object member is replaced when it is found, and so is
+		// the whole document at the root.
+		replace := len(d.Path) == 0
+		if !replace {
+			_, replace = d.Path[len(d.Path)-1].(PathKey)
 		}
-		return d, patch[2:], nil
-	case "add":
+		d.Metadata.Unchecked = replace
+		d.Metadata.Upsert = replace
+		return d, patch[1:], nil
+	case "replace", "remove":
 		d.Path, err = readPointer(p.Path)
 		if err != nil {
 			return d, nil, err
 		}
-		addValue, err := NewJsonNode(p.Value)
+		d.Metadata.Unchecked = true
+		if p.Op == "remove" {
+			d.Add = []JsonNode{voidNode{}}
+			return d, patch[1:], nil
+		}
+		replaceValue, err := NewJsonNode(p.Value)
 		if err != nil { //jd:nocover — Value from json.Unmarshal is always valid
 			return d, nil, err
 		}
-		d.Add = []JsonNode{addValue}
+		d.Add = []JsonNode{replaceValue}
+		return d, patch[1:], nil
+	case "move", "copy":
+		d.Path, err = readPointer(p.Path)
+		if err != nil {
+			return d, nil, err
+		}
+		d.From, err = readPointer(p.From)
+		if err != nil {
+			return d, nil, err
+		}
+		d.Copy = p.Op == "copy"
+		if !d.Copy && hasPathPrefix(d.Path, d.From) {
+			return d, nil, fmt.Errorf("JSON Patch move op cannot move %q into itself", p.From)
+		}
 		return d, patch[1:], nil
 	default:
-		return d, nil, fmt.Errorf("invalid JSON Patch: must be test/remove or add ops")
+		return d, nil, fmt.Errorf("invalid JSON Patch op: %q", p.Op)
 	}
 }
 
@@ -512,8 +670,14 @@ func ReadMergeString(s string) (Diff, error) {
 func readMergeInto(d Diff, p Path, n JsonNode) Diff {
 	switch n := n.(type) {
 	case jsonObject:
-		for k, v := range n {
-			d = readMergeInto(d, append(p.clone(), PathKey(k)), v)
+		// Keys in order, so the hunks read are the same every time.
+		keys := make([]string, 0, len(n))
+		for k := range n {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			d = readMergeInto(d, append(p.clone(), PathKey(k)), n[k])
 		}
 		if len(n) == 0 {
 			return append(d, DiffElement{
@@ -540,3 +704,107 @@ func readMergeInto(d Diff, p Path, n JsonNode) Diff {
 	}
 	return d
 }
+
+// ReadJsonHunksFile reads a Diff from a file written by
+// RenderJsonHunks.
+func ReadJsonHunksFile(filename string) (Diff, error) {
+	bytes, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return ReadJsonHunksString(string(bytes))
+}
+
+// ReadJsonHunksString reads a Diff from a string written by
+// RenderJsonHunks. Unlike the native jd format, Metadata is not
+// inherited by following hunks.
+func ReadJsonHunksString(s string) (Diff, error) {
+	var hunks []jsonHunk
+	dec := json.NewDecoder(strings.NewReader(s))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&hunks); err != nil {
+		return nil, err
+	}
+	d := Diff{}
+	for i, h := range hunks {
+		de, err := readJsonHunk(h)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hunk %v. %v", i, err)
+		}
+		d = append(d, de)
+	}
+	return d, nil
+}
+
+func readJsonHunk(h jsonHunk) (DiffElement, error) {
+	de := DiffElement{
+		Metadata: h.Metadata,
+		Copy:     h.Copy,
+	}
+	if h.Path == nil {
+		return de, fmt.Errorf("missing Path")
+	}
+	var err error
+	if de.Path, err = readJsonPath(h.Path); err != nil {
+		return de, err
+	}
+	for _, raw := range h.Options {
+		var a any
+		if err := json.Unmarshal(raw, &a); err != nil { //jd:nocover — raw was decoded from valid JSON
+			return de, err
+		}
+		o, err := NewOption(a)
+		if err != nil {
+			return de, err
+		}
+		de.Options = append(de.Options, o)
+	}
+	de.Before = readJsonValues(h.Before)
+	de.Remove = readJsonValues(h.Remove)
+	de.Add = readJsonValues(h.Add)
+	de.After = readJsonValues(h.After)
+	if h.StartOfList {
+		de.Before = append([]JsonNode{voidNode{}}, de.Before...)
+	}
+	if h.EndOfList {
+		de.After = append(de.After, voidNode{})
+	}
+	if h.Delete {
+		if len(de.Add) > 0 {
+			return de, fmt.Errorf("Delete with values to Add")
+		}
+		de.Add = []JsonNode{voidNode{}}
+	}
+	if h.From != nil {
+		if de.From, err = readJsonPath(h.From); err != nil {
+			return de, err
+		}
+		if len(de.Before)+len(de.Remove)+len(de.Add)+len(de.After) > 0 {
+			return de, fmt.Errorf("From with values")
+		}
+	} else if de.Copy {
+		return de, fmt.Errorf("Copy without From")
+	}
+	return de, checkDiffElement(de)
+}
+
+func readJsonPath(raw json.RawMessage) (Path, error) {
+	n, err := ReadJsonString(string(raw))
+	if err != nil { //jd:nocover — raw was decoded from valid JSON
+		return nil, err
+	}
+	return NewPath(n)
+}
+
+// readJsonValues reads values which were decoded from valid JSON.
+func readJsonValues(raws []json.RawMessage) []JsonNode {
+	var nodes []JsonNode
+	for _, raw := range raws {
+		n, err := ReadJsonString(string(raw))
+		if err != nil { //jd:nocover — raw was decoded from valid JSON
+			panic(err)
+		}
+		nodes = append(nodes, n)
+	}
+	return nodes
+}
@@ -14,6 +14,7 @@ func TestReadPatch(t *testing.T) {
 	}{{
 		patch: s(`[{"op":"add","path":"/foo","value":1}]`),
 		diff: s(
+			`^ {"Unchecked":true,"Upsert":true}`,
 			`@ ["foo"]`,
 			`+ 1`,
 		),
@@ -44,10 +45,129 @@ func TestReadPatch(t *testing.T) {
 			`+ 2`,
 		),
 	}, {
-		patch:   s(`[{"op":"test","path":"/foo","value":1}]`),
-		wantErr: true,
+		patch: s(`[{"op":"test","path":"/foo","value":1}]`),
+		diff: s(
+			`@ ["foo"]`,
+			`- 1`,
+			`+ 1`,
+		),
+	}, {
+		patch: s(`[{"op":"remove","path":"/foo","value":1}]`),
+		diff: s(
+			`^ {"Unchecked":true}`,
+			`@ ["foo"]`,
+			`+`,
+		),
+	}, {
+		patch: s(`[{"op":"remove","path":"/foo"}]`),
+		diff: s(
+			`^ {"Unchecked":true}`,
+			`@ ["foo"]`,
+			`+`,
+		),
+	}, {
+		patch: s(`[{"op":"replace","path":"/foo","value":2}]`),
+		diff: s(
+			`^ {"Unchecked":true}`,
+			`@ ["foo"]`,
+			`+ 2`,
+		),
+	}, {
+		patch: s(
+			`[{"op":"test","path":"/foo","value":1},`,
+			`{"op":"replace","path":"/foo","value":2}]`,
+		),
+		diff: s(
+			`@ ["foo"]`,
+			`- 1`,
+			`+ 2`,
+		),
+	}, {
+		patch: s(
+			`[{"op":"test","path":"/foo","value":1},`,
+			`{"op":"remove","path":"/foo"}]`,
+		),
+		diff: s(
+			`@ ["foo"]`,
+			`- 1`,
+		),
+	}, {
+		patch: s(
+			`[{"op":"replace","path":"/foo","value":2},`,
+			`{"op":"add","path":"/bar","value":3}]`,
+		),
+		diff: s(
+			`^ {"Unchecked":true}`,
+			`@ ["foo"]`,
+			`+ 2`,
+			`^ {"Unchecked":true,"Upsert":true}`,
+			`@ ["bar"]`,
+			`+ 3`,
+		),
+	}, {
+		patch: s(`[{"op":"replace","path":"/foo/0","value":2}]`),
+		diff: s(
+			`^ {"Unchecked":true}`,
+			`@ ["foo",0]`,
+			`+ 2`,
+		),
+	}, {
+		patch: s(`[{"op":"move","from":"/foo","path":"/bar"}]`),
+		diff: s(
+			`@ ["bar"]`,
+			`< ["foo"]`,
+		),
+	}, {
+		patch: s(`[{"op":"copy","from":"/foo","path":"/bar/-"}]`),
+		diff: s(
+			`@ ["bar",-1]`,
+			`= ["foo"]`,
+		),
+	}, {
+		patch: s(
+			`[{"op":"test","path":"/foo","value":1},`,
+			`{"op":"remove","path":"/bar","value":1}]`,
+		),
+		diff: s(
+			`@ ["foo"]`,
+			`- 1`,
+			`+ 1`,
+			`^ {"Unchecked":true}`,
+			`@ ["bar"]`,
+			`+`,
+		),
+	}, {
+		patch: s(
+			`[{"op":"test","path":"/0","value":1},`,
+			`{"op":"test","path":"/5","value":2},`,
+			`{"op":"replace","path":"/5","value":3}]`,
+		),
+		diff: s(
+			`@ [0]`,
+			`- 1`,
+			`+ 1`,
+			`@ [5]`,
+			`- 2`,
+			`+ 3`,
+		),
+	}, {
+		patch: s(
+			`[{"op":"test","path":"/0","value":1},`,
+			`{"op":"test","path":"/foo","value":2},`,
+			`{"op":"add","path":"/1","value":3}]`,
+		),
+		diff: s(
+			`@ [0]`,
+			`- 1`,
+			`+ 1`,
+			`@ ["foo"]`,
+			`- 2`,
+			`+ 2`,
+			`@ [1]`,
+			`+ 3`,
+		),
 	}, {
-		patch:   s(`[{"op":"remove","path":"/foo","value":1}]`),
+		patch:   s(`[{"op":"frobnicate","path":"/foo","value":1}]`),
 		wantErr: true,
 	}}
 
@@ -100,6 +220,22 @@ func TestReadMerge(t *testing.T) {
 			`@ []`,
 			`+ [1,2,3]`,
 		),
+	}, {
+		patch: `{"c":{"e":1,"d":null},"b":2,"a":3}`,
+		diff: s(
+			`^ {"Merge":true}`,
+			`@ ["a"]`,
+			`+ 3`,
+			`^ {"Merge":true}`,
+			`@ ["b"]`,
+			`+ 2`,
+			`^ {"Merge":true}`,
+			`@ ["c","d"]`,
+			`+`,
+			`^ {"Merge":true}`,
+			`@ ["c","e"]`,
+			`+ 1`,
+		),
 	}}
 
 	for _, c := range cases {
@@ -383,6 +519,8 @@ func TestReadDiffErrors(t *testing.T) {
 		{name: "check error before path", input: "@ [\"a\"]\n- 1\n- 2\n@ [\"b\"]\n- 3\n"},
 		// readDiff: invalid JSON after @
 		{name: "invalid json after path", input: "@ {bad\n"},
+		// checkDiffElement: wildcards only belong in PathOptions
+		{name: "wildcard in path", input: "@ [\"a\",null]\n- 1\n"},
 		// readDiff: invalid JSON in before context
 		{name: "invalid json in before context", input: "@ [0,1]\n {bad\n"},
 		// readDiff: invalid JSON in after context
@@ -468,12 +606,11 @@ func TestReadPatchStringErrors(t *testing.T) {
 		input string
 	}{
 		{name: "invalid json", input: "not json"},
-		{name: "test without remove", input: `[{"op":"test","path":"/foo","value":1}]`},
-		{name: "test remove path mismatch", input: `[{"op":"test","path":"/foo","value":1},{"op":"remove","path":"/bar","value":1}]`},
 		{name: "test remove value mismatch", input: `[{"op":"test","path":"/foo","value":1},{"op":"remove","path":"/foo","value":2}]`},
-		{name: "unknown op", input: `[{"op":"move","path":"/foo","value":1}]`},
-		// readPatchDiffElement: empty patch after context consumed
-		{name: "empty after context", input: `[{"op":"test","path":"/0","value":1},{"op":"test","path":"/1","value":2}]`},
+		{name: "unknown op", input: `[{"op":"frobnicate","path":"/foo","value":1}]`},
+		{name: "move without from", input: `[{"op":"move","path":"/foo","value":1}]`},
+		{name: "invalid pointer in replace", input: `[{"op":"replace","path":"no-slash","value":1}]`},
+		{name: "invalid pointer in remove", input: `[{"op":"remove","path":"no-slash"}]`},
 		// readPatchDiffElement: readPointer error in test case
 		{name: "invalid pointer in test", input: `[{"op":"test","path":"no-slash","value":1},{"op":"remove","path":"no-slash","value":1}]`},
 		// readPatchDiffElement: readPointer error in add case
@@ -488,12 +625,6 @@ func TestReadPatchStringErrors(t *testing.T) {
 		{name: "context invalid third pointer", input: `[{"op":"test","path":"/0","value":1},{"op":"test","path":"/1","value":2},{"op":"add","path":"bad","value":3}]`},
 		// setPatchDiffElementContext: empty path on third element
 		{name: "context third empty path", input: `[{"op":"test","path":"/0","value":1},{"op":"test","path":"/1","value":2},{"op":"add","path":"","value":3}]`},
-		// setPatchDiffElementContext: default in first switch with len>2 falls through to second switch
-		{name: "context default fallthrough", input: `[{"op":"test","path":"/0","value":1},{"op":"test","path":"/5","value":2},{"op":"replace","path":"/5","value":3}]`},
-		// setPatchDiffElementContext: second path not PathIndex (line 338)
-		{name: "context second not index", input: `[{"op":"test","path":"/0","value":1},{"op":"test","path":"/foo","value":2},{"op":"add","path":"/1","value":3}]`},
-		// setPatchDiffElementContext: second switch default with add op and thirdIndex > secondIndex (line 417)
-		{name: "context second switch default", input: `[{"op":"test","path":"/0","value":1},{"op":"test","path":"/5","value":2},{"op":"add","path":"/7","value":3}]`},
 		// readPatchDiffElement: readPointer error in single-element test (line 446)
 		{name: "single test invalid pointer", input: `[{"op":"test","path":"no-slash","value":1}]`},
 	}
@@ -607,3 +738,218 @@ func TestReadPatchDiffElementEdgeCases(t *testing.T) {
 		t.Fatal("expected error for unsupported add value type")
 	}
 }
+
+func TestApplyPatchAllOps(t *testing.T) {
+	cases := []struct {
+		name    string
+		doc     string
+		patch   string
+		want    string
+		wantErr bool
+	}{{
+		name:  "replace object member",
+		doc:   `{"a":1,"b":2}`,
+		patch: `[{"op":"replace","path":"/a","value":3}]`,
+		want:  `{"a":3,"b":2}`,
+	}, {
+		name:  "remove object member",
+		doc:   `{"a":1,"b":2}`,
+		patch: `[{"op":"remove","path":"/a"}]`,
+		want:  `{"b":2}`,
+	}, {
+		name:  "replace list element",
+		doc:   `{"a":[1,2,3]}`,
+		patch: `[{"op":"replace","path":"/a/1","value":4}]`,
+		want:  `{"a":[1,4,3]}`,
+	}, {
+		name:  "remove list element",
+		doc:   `{"a":[1,2,3]}`,
+		patch: `[{"op":"remove","path":"/a/1"}]`,
+		want:  `{"a":[1,3]}`,
+	}, {
+		name:  "replace member of list element",
+		doc:   `[{"a":1}]`,
+		patch: `[{"op":"replace","path":"/0/a","value":2}]`,
+		want:  `[{"a":2}]`,
+	}, {
+		name:  "replace after a list edit",
+		doc:   `{"a":[1,2]}`,
+		patch: `[{"op":"add","path":"/a/0","value":0},{"op":"replace","path":"/a/1","value":5}]`,
+		want:  `{"a":[0,5,2]}`,
+	}, {
+		name:  "test and remove list element with value",
+		doc:   `[1,2,3]`,
+		patch: `[{"op":"test","path":"/2","value":3},{"op":"remove","path":"/2"}]`,
+		want:  `[1,2]`,
+	}, {
+		name:  "test and replace",
+		doc:   `{"a":[1,2,3]}`,
+		patch: `[{"op":"test","path":"/a/1","value":2},{"op":"replace","path":"/a/1","value":5}]`,
+		want:  `{"a":[1,5,3]}`,
+	}, {
+		name:  "add to end of list",
+		doc:   `[1]`,
+		patch: `[{"op":"add","path":"/-","value":2}]`,
+		want:  `[1,2]`,
+	}, {
+		name:  "add several to end of list",
+		doc:   `[1,2,3]`,
+		patch: `[{"op":"add","path":"/-","value":9},{"op":"add","path":"/-","value":10}]`,
+		want:  `[1,2,3,9,10]`,
+	}, {
+		name:  "add several at an index",
+		doc:   `[1,2,3]`,
+		patch: `[{"op":"add","path":"/1","value":9},{"op":"add","path":"/1","value":10}]`,
+		want:  `[1,10,9,2,3]`,
+	}, {
+		name:  "add replaces an object member",
+		doc:   `{"a":1,"b":2}`,
+		patch: `[{"op":"add","path":"/a","value":3}]`,
+		want:  `{"a":3,"b":2}`,
+	}, {
+		name:  "add replaces the document",
+		doc:   `{"a":1}`,
+		patch: `[{"op":"add","path":"","value":[2]}]`,
+		want:  `[2]`,
+	}, {
+		name:  "add to an empty document",
+		doc:   ``,
+		patch: `[{"op":"add","path":"","value":{"a":1}}]`,
+		want:  `{"a":1}`,
+	}, {
+		name:  "add a member of a list element",
+		doc:   `[{"a":1}]`,
+		patch: `[{"op":"add","path":"/0/b","value":2}]`,
+		want:  `[{"a":1,"b":2}]`,
+	}, {
+		name:    "add to a missing object",
+		doc:     `{"a":1}`,
+		patch:   `[{"op":"add","path":"/b/c","value":2}]`,
+		wantErr: true,
+	}, {
+		name:    "replace missing object member",
+		doc:     `{"a":1}`,
+		patch:   `[{"op":"replace","path":"/b","value":2}]`,
+		wantErr: true,
+	}, {
+		name:    "remove missing object member",
+		doc:     `{"a":1}`,
+		patch:   `[{"op":"remove","path":"/b"}]`,
+		wantErr: true,
+	}, {
+		name:  "test passes",
+		doc:   `{"a":[1,2]}`,
+		patch: `[{"op":"test","path":"/a","value":[1,2]},{"op":"add","path":"/b","value":1}]`,
+		want:  `{"a":[1,2],"b":1}`,
+	}, {
+		name:    "test fails",
+		doc:     `{"a":1}`,
+		patch:   `[{"op":"test","path":"/a","value":2}]`,
+		wantErr: true,
+	}, {
+		name:    "remove missing list element",
+		doc:     `[1]`,
+		patch:   `[{"op":"remove","path":"/1"}]`,
+		wantErr: true,
+	}, {
+		name:    "replace end of list",
+		doc:     `[1]`,
+		patch:   `[{"op":"replace","path":"/-","value":2}]`,
+		wantErr: true,
+	}}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			doc, err := ReadJsonString(c.doc)
+			require.NoError(t, err)
+			d, err := ReadPatchString(c.patch)
+			require.NoError(t, err)
+			got, err := doc.Patch(d)
+			if c.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, c.want, got.Json())
+		})
+	}
+}
+
+func TestReadRenderedPatch(t *testing.T) {
+	cases := []struct {
+		name string
+		a    string
+		b    string
+	}{{
+		name: "before and after context of several removals",
+		a:    `[[],[],3,{"a":{"a":2},"c":[3,2,3,2,2]},[]]`,
+		b:    `[[0,3,[],{}],[]]`,
+	}}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			a, err := ReadJsonString(c.a)
+			require.NoError(t, err)
+			b, err := ReadJsonString(c.b)
+			require.NoError(t, err)
+			patch, err := a.Diff(b).RenderPatch()
+			require.NoError(t, err)
+			d, err := ReadPatchString(patch)
+			require.NoError(t, err)
+			got, err := a.Patch(d)
+			require.NoError(t, err)
+			require.Equal(t, b.Json(), got.Json())
+		})
+	}
+}
+
+func TestReadDiffMergeFalse(t *testing.T) {
+	d, err := ReadDiffString(s(
+		`^ {"Merge":true}`,
+		`@ ["a"]`,
+		`+ 1`,
+		`^ {"Merge":false}`,
+		`@ ["b"]`,
+		`+ 2`,
+	))
+	require.NoError(t, err)
+	require.Len(t, d, 2)
+	require.True(t, d[0].Metadata.Merge)
+	require.False(t, d[1].Metadata.Merge)
+	require.Empty(t, d[1].Options)
+}
+
+func TestReadDiffMergeFalseKeepsOtherOptions(t *testing.T) {
+	d, err := ReadDiffString(s(
+		`^ "SET"`,
+		`^ {"Merge":true}`,
+		`@ ["a",{}]`,
+		`+ 1`,
+		`^ {"Merge":false}`,
+		`@ ["b",{}]`,
+		`+ 2`,
+	))
+	require.NoError(t, err)
+	require.Len(t, d, 2)
+	require.False(t, d[1].Metadata.Merge)
+	require.Equal(t, []Option{SET}, d[1].Options)
+}
+
+func TestReadJsonHunksErrors(t *testing.T) {
+	for _, bad := range []string{
+		`{}`,
+		`[{"Path":["a"],"Unknown":1}]`,
+		`[{"Add":[1]}]`,
+		`[{"Path":null}]`,
+		`[{"Path":["a"],"Options":["UNKNOWN"]}]`,
+		`[{"Path":["a"],"Add":[1],"Delete":true}]`,
+		`[{"Path":["a"],"From":"a"}]`,
+		`[{"Path":["a"],"From":["b"],"Add":[1]}]`,
+		`[{"Path":["a"],"Copy":true}]`,
+		`[{"Path":["a"],"Add":[1,2]}]`,
+		`[{"Path":["a",null],"Add":[1]}]`,
+		`[{"Path":["a"],"From":[[null]]}]`,
+	} {
+		if _, err := ReadJsonHunksString(bad); err == nil {
+			t.Errorf("wanted error reading %v", bad)
+		}
+	}
+}
@@ -53,7 +53,8 @@ func (d DiffElement) Render(opts ...Option) string {
 	o := refine(&options{retain: opts}, nil)
 	isColorWords := checkOption[colorWordsOption](o)
 	isColor := checkOption[colorOption](o) || isColorWords
-	isMerge := checkOption[mergeOption](o) || d.Metadata.Merge
+	metadata := d.Metadata
+	isMerge := checkOption[mergeOption](o) || metadata.Merge
 	b := bytes.NewBuffer(nil)
 	// Render options from the Options field if present, otherwise fall back to metadata
 	if len(d.Options) > 0 {
@@ -65,24 +66,37 @@ func (d DiffElement) Render(opts ...Option) string {
 			}
 			b.WriteString(fmt.Sprintf("^ %s\n", string(optJson)))
 		}
+		// Options stand in for Merge but not for Unchecked.
+		b.WriteString(Metadata{Unchecked: metadata.Unchecked, Upsert: metadata.Upsert}.Render())
 	} else {
 		// Check if any of the passed global options would make metadata redundant
 		shouldSkipMetadata := false
 		for _, opt := range opts {
-			if _, isMerge := opt.(mergeOption); isMerge && d.Metadata.Merge {
+			if _, isMerge := opt.(mergeOption); isMerge && metadata.Merge {
 				shouldSkipMetadata = true
 				break
 			}
 		}
 
-		if !shouldSkipMetadata {
-			// Fall back to rendering metadata for backward compatibility
-			b.WriteString(d.Metadata.Render())
+		if shouldSkipMetadata {
+			metadata.Merge = false
 		}
+		// Fall back to rendering metadata for backward compatibility
+		b.WriteString(metadata.Render())
 	}
 	b.WriteString("@ ")
 	b.Write([]byte(d.Path.JsonNode().Json()))
 	b.WriteString("\n")
+	if d.From != nil {
+		if d.Copy {
+			b.WriteString("= ")
+		} else {
+			b.WriteString("< ")
+		}
+		b.Write([]byte(d.From.JsonNode().Json()))
+		b.WriteString("\n")
+		return b.String()
+	}
 
 	// Check if this is a single string diff. If COLOR_WORDS is set, compute the common
 	// sequence for a character-level diff. This LCS is O(n^2) in time and memory so it
@@ -146,7 +160,7 @@ func (d DiffElement) Render(opts ...Option) string {
 	}
 	for _, newValue := range d.Add {
 		if isVoid(newValue) {
-			if isMerge {
+			if isMerge || metadata.Unchecked {
 				// Merge deletion is writing void to a node.
 				if isColor {
 					b.WriteString(colorGreen)
@@ -211,7 +225,15 @@ func (d Diff) Render(opts ...Option) string {
 		}
 	}
 
+	isMerge := checkOption[mergeOption](&options{retain: opts})
+	inheritedMerge := false
 	for _, element := range d {
+		merge := element.Metadata.Merge
+		if inheritedMerge && !isMerge && !merge && len(element.Options) == 0 {
+			// Metadata is inherited by following hunks so it must be reset.
+			b.WriteString(renderMetadataField(metadataMerge{Merge: false}))
+		}
+		inheritedMerge = merge
 		b.WriteString(element.Render(opts...))
 	}
 	return b.String()
@@ -228,9 +250,40 @@ func (d Diff) RenderPatch() (string, error) {
 		if err != nil {
 			return "", err
 		}
+		if element.From != nil {
+			from, err := writePointer(element.From.JsonNode().(jsonArray))
+			if err != nil {
+				return "", err
+			}
+			op := "move"
+			if element.Copy {
+				op = "copy"
+			}
+			patch = append(patch, patchElement{
+				Op:   op,
+				From: from,
+				Path: path,
+			})
+			continue
+		}
 		if len(element.Remove) == 0 && len(element.Add) == 0 {
 			return "", fmt.Errorf("cannot render empty diff element as JSON Patch op")
 		}
+		if element.Metadata.Merge || element.Metadata.Unchecked {
+			// These hunks set or delete a value without testing it.
+			if len(element.Remove) > 0 || len(element.Add) > 1 {
+				return "", fmt.Errorf("cannot render merge diff element with multiple values as JSON Patch op")
+			}
+			op := patchElement{Op: "add", Path: path, Value: element.Add[0]}
+			switch {
+			case isVoid(element.Add[0]):
+				op = patchElement{Op: "remove", Path: path}
+			case element.Metadata.Unchecked && !element.Metadata.Upsert:
+				op.Op = "replace"
+			}
+			patch = append(patch, op)
+			continue
+		}
 		// Test context before
 		lenBefore := len(element.Before)
 		if lenBefore > 1 {
@@ -342,3 +395,71 @@ func (d Diff) RenderMerge() (string, error) {
 	}
 	return mergePatch.Json(), nil
 }
+
+// RenderJsonHunks renders the Diff as a JSON array with one object per
+// DiffElement, keeping every field. Void values are flagged with
+// StartOfList (first Before), EndOfList (last After) and Delete (the
+// only Add) and cannot appear anywhere else.
+func (d Diff) RenderJsonHunks() (string, error) {
+	hunks := []jsonHunk{}
+	for _, element := range d {
+		h := jsonHunk{
+			Metadata: element.Metadata,
+			Path:     json.RawMessage(element.Path.JsonNode().Json()),
+			Copy:     element.Copy,
+		}
+		for _, opt := range element.Options {
+			optJson, err := json.Marshal(opt)
+			if err != nil { //jd:nocover — options always marshal
+				return "", err
+			}
+			h.Options = append(h.Options, optJson)
+		}
+		if element.From != nil {
+			h.From = json.RawMessage(element.From.JsonNode().Json())
+		}
+		before, after, add := element.Before, element.After, element.Add
+		if len(before) > 0 && isVoid(before[0]) {
+			h.StartOfList = true
+			before = before[1:]
+		}
+		if len(after) > 0 && isVoid(after[len(after)-1]) {
+			h.EndOfList = true
+			after = after[:len(after)-1]
+		}
+		if len(add) == 1 && isVoid(add[0]) {
+			h.Delete = true
+			add = nil
+		}
+		var err error
+		if h.Before, err = jsonValues("Before", before); err != nil {
+			return "", err
+		}
+		if h.Remove, err = jsonValues("Remove", element.Remove); err != nil {
+			return "", err
+		}
+		if h.Add, err = jsonValues("Add", add); err != nil {
+			return "", err
+		}
+		if h.After, err = jsonValues("After", after); err != nil {
+			return "", err
+		}
+		hunks = append(hunks, h)
+	}
+	hunksJson, err := json.Marshal(hunks)
+	if err != nil { //jd:nocover — all values are already valid JSON
+		return "", err
+	}
+	return string(hunksJson), nil
+}
+
+func jsonValues(field string, nodes []JsonNode) ([]json.RawMessage, error) {
+	values := []json.RawMessage{}
+	for _, n := range nodes {
+		if isVoid(n) {
+			return nil, fmt.Errorf("cannot render void value in %v as JSON", field)
+		}
+		values = append(values, json.RawMessage(n.Json()))
+	}
+	return values, nil
+}
@@ -1152,3 +1152,229 @@ func TestRenderMergeWithPatchError(t *testing.T) {
 		t.Fatal("expected error from RenderMerge with remove value in merge diff")
 	}
 }
+
+func TestRenderPatchWithoutTest(t *testing.T) {
+	patch := `[{"op":"replace","path":"/a","value":1},{"op":"remove","path":"/b"},{"op":"replace","path":"/c/0","value":2},{"op":"remove","path":"/c/1"}]`
+	d, err := ReadPatchString(patch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := d.RenderPatch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `[{"op":"replace","path":"/a","value":1},{"op":"remove","path":"/b"},{"op":"replace","path":"/c/0","value":2},{"op":"remove","path":"/c/1"}]`
+	if got != want {
+		t.Errorf("got %v. want %v", got, want)
+	}
+	_, err = Diff{{
+		Metadata: Metadata{Merge: true},
+		Path:     Path{PathKey("a")},
+		Add:      []JsonNode{jsonNumber(1), jsonNumber(2)},
+	}}.RenderPatch()
+	if err == nil {
+		t.Errorf("wanted error rendering merge hunk with multiple values")
+	}
+}
+
+func TestRenderJsonHunks(t *testing.T) {
+	cases := []struct {
+		name string
+		diff []string
+		want string
+	}{{
+		name: "empty",
+		diff: ss(),
+		want: `[]`,
+	}, {
+		name: "object",
+		diff: ss(
+			`@ ["a"]`,
+			`- 1`,
+			`+ {"b":null}`,
+		),
+		want: `[{"Metadata":{"Merge":false},"Path":["a"],"Before":[],"Remove":[1],"Add":[{"b":null}],"After":[]}]`,
+	}, {
+		name: "list context",
+		diff: ss(
+			`@ [0]`,
+			`[`,
+			`- 1`,
+			`  2`,
+			`@ [1]`,
+			`  2`,
+			`+ 3`,
+			`]`,
+		),
+		want: `[{"Metadata":{"Merge":false},"Path":[0],"Before":[],"Remove":[1],"Add":[],"After":[2],"StartOfList":true},` +
+			`{"Metadata":{"Merge":false},"Path":[1],"Before":[2],"Remove":[],"Add":[3],"After":[],"EndOfList":true}]`,
+	}, {
+		name: "merge delete",
+		diff: ss(
+			`^ {"Merge":true}`,
+			`@ ["a"]`,
+			`+`,
+		),
+		want: `[{"Metadata":{"Merge":true},"Options":["MERGE"],"Path":["a"],"Before":[],"Remove":[],"Add":[],"After":[],"Delete":true}]`,
+	}, {
+		name: "options and set path",
+		diff: ss(
+			`^ "SET"`,
+			`@ ["a",{}]`,
+			`- 1`,
+			`- 2`,
+		),
+		want: `[{"Metadata":{"Merge":false},"Options":["SET"],"Path":["a",{}],"Before":[],"Remove":[1,2],"Add":[],"After":[]}]`,
+	}, {
+		name: "move and copy",
+		diff: ss(
+			`@ ["b"]`,
+			`< ["a"]`,
+			`@ ["c"]`,
+			`= ["b"]`,
+		),
+		want: `[{"Metadata":{"Merge":false},"Path":["b"],"Before":[],"Remove":[],"Add":[],"After":[],"From":["a"]},` +
+			`{"Metadata":{"Merge":false},"Path":["c"],"Before":[],"Remove":[],"Add":[],"After":[],"From":["b"],"Copy":true}]`,
+	}}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			d, err := ReadDiffString(strings.Join(c.diff, "\n"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := d.RenderJsonHunks()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != c.want {
+				t.Errorf("got %v. want %v", got, c.want)
+			}
+			read, err := ReadJsonHunksString(got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if read.Render() != d.Render() {
+				t.Errorf("round trip got:\n%v\nwant:\n%v", read.Render(), d.Render())
+			}
+		})
+	}
+}
+
+func TestRenderUnchecked(t *testing.T) {
+	cases := []struct {
+		name       string
+		patch      string
+		wantNative string
+		wantHunks  string
+	}{{
+		name:       "replace object member",
+		patch:      `[{"op":"replace","path":"/a","value":2}]`,
+		wantNative: s(`^ {"Unchecked":true}`, `@ ["a"]`, `+ 2`),
+		wantHunks:  `[{"Metadata":{"Merge":false,"Unchecked":true},"Path":["a"],"Before":[],"Remove":[],"Add":[2],"After":[]}]`,
+	}, {
+		name:       "replace list element",
+		patch:      `[{"op":"replace","path":"/l/0","value":2}]`,
+		wantNative: s(`^ {"Unchecked":true}`, `@ ["l",0]`, `+ 2`),
+		wantHunks:  `[{"Metadata":{"Merge":false,"Unchecked":true},"Path":["l",0],"Before":[],"Remove":[],"Add":[2],"After":[]}]`,
+	}, {
+		name:       "remove list element",
+		patch:      `[{"op":"remove","path":"/l/0"}]`,
+		wantNative: s(`^ {"Unchecked":true}`, `@ ["l",0]`, `+`),
+		wantHunks:  `[{"Metadata":{"Merge":false,"Unchecked":true},"Path":["l",0],"Before":[],"Remove":[],"Add":[],"After":[],"Delete":true}]`,
+	}, {
+		name:       "add the document",
+		patch:      `[{"op":"add","path":"","value":{"b":2}}]`,
+		wantNative: s(`^ {"Unchecked":true,"Upsert":true}`, `@ []`, `+ {"b":2}`),
+		wantHunks:  `[{"Metadata":{"Merge":false,"Unchecked":true,"Upsert":true},"Path":[],"Before":[],"Remove":[],"Add":[{"b":2}],"After":[]}]`,
+	}, {
+		name:  "not inherited",
+		patch: `[{"op":"replace","path":"/a","value":2},{"op":"test","path":"/b","value":1},{"op":"remove","path":"/b","value":1}]`,
+		wantNative: s(
+			`^ {"Unchecked":true}`,
+			`@ ["a"]`,
+			`+ 2`,
+			`@ ["b"]`,
+			`- 1`,
+		),
+		wantHunks: `[{"Metadata":{"Merge":false,"Unchecked":true},"Path":["a"],"Before":[],"Remove":[],"Add":[2],"After":[]},` +
+			`{"Metadata":{"Merge":false},"Path":["b"],"Before":[],"Remove":[1],"Add":[],"After":[]}]`,
+	}}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			d, err := ReadPatchString(c.patch)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := d.Render()
+			if got != c.wantNative {
+				t.Errorf("got %q. want %q", got, c.wantNative)
+			}
+			read, err := ReadDiffString(got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if read.Render() != got || read[0].Metadata != d[0].Metadata {
+				t.Errorf("round trip got %q. want %q", read.Render(), got)
+			}
+			hunks, err := d.RenderJsonHunks()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if hunks != c.wantHunks {
+				t.Errorf("got %v. want %v", hunks, c.wantHunks)
+			}
+			read, err = ReadJsonHunksString(hunks)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if read.Render() != got {
+				t.Errorf("json hunks round trip got %q. want %q", read.Render(), got)
+			}
+		})
+	}
+}
+
+func TestRenderUncheckedAfterMerge(t *testing.T) {
+	d := Diff{{
+		Metadata: Metadata{Merge: true},
+		Path:     Path{PathKey("a")},
+		Add:      []JsonNode{jsonNumber(1)},
+	}, {
+		Metadata: Metadata{Unchecked: true},
+		Path:     Path{PathKey("l"), PathIndex(0)},
+		Add:      []JsonNode{jsonNumber(2)},
+	}}
+	got := d.Render()
+	want := s(
+		`^ {"Merge":true}`,
+		`@ ["a"]`,
+		`+ 1`,
+		`^ {"Merge":false}`,
+		`^ {"Unchecked":true}`,
+		`@ ["l",0]`,
+		`+ 2`,
+	)
+	if got != want {
+		t.Errorf("got %q. want %q", got, want)
+	}
+	read, err := ReadDiffString(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if read[1].Metadata != (Metadata{Unchecked: true}) {
+		t.Errorf("got %+v. want unchecked only", read[1].Metadata)
+	}
+}
+
+func TestRenderJsonHunksErrors(t *testing.T) {
+	for _, d := range []Diff{
+		{{Path: Path{PathIndex(0)}, Before: []JsonNode{jsonNumber(1), voidNode{}}}},
+		{{Path: Path{PathIndex(0)}, Remove: []JsonNode{voidNode{}}}},
+		{{Path: Path{PathSet{}}, Add: []JsonNode{jsonNumber(1), voidNode{}}}},
+		{{Path: Path{PathIndex(0)}, After: []JsonNode{voidNode{}, jsonNumber(1)}}},
+	} {
+		if got, err := d.RenderJsonHunks(); err == nil {
+			t.Errorf("wanted error. got %v", got)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package jd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// KubernetesIdentity identifies Kubernetes resources by their API
+// version, kind, namespace and name.
+var KubernetesIdentity = []Path{
+	{PathKey("apiVersion")},
+	{PathKey("kind")},
+	{PathKey("metadata"), PathKey("namespace")},
+	{PathKey("metadata"), PathKey("name")},
+}
+
+// ReadYamlDocumentsFile reads a file as a stream of YAML documents. See
+// ReadYamlDocumentsString.
+func ReadYamlDocumentsFile(filename string, identity ...Path) (JsonNode, error) {
+	bytes, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return ReadYamlDocumentsString(string(bytes), identity...)
+}
+
+// ReadYamlDocumentsString reads a string as a stream of YAML documents
+// separated by "---" and constructs an object of the documents keyed
+// by their identity, so that diffing two streams pairs documents by
+// identity rather than by position. The identity of a document is a
+// JSON array of the values found at the identity paths, like
+// ["apps/v1","Deployment","prod","web"], with null for missing values.
+// KubernetesIdentity is used when no paths are given. Documents without
+// any identity value are keyed by their position like "#2". Empty
+// documents are ignored and two documents with the same identity are
+// an error.
+func ReadYamlDocumentsString(s string, identity ...Path) (JsonNode, error) {
+	docs, _, err := readYamlDocuments(s, identity)
+	if err != nil {
+		return nil, err
+	}
+	if len(docs) == 0 {
+		return voidNode{}, nil
+	}
+	return docs, nil
+}
+
+// readYamlDocuments reads the documents of s and their keys in the
+// order of the stream.
+func readYamlDocuments(s string, identity []Path) (jsonObject, []string, error) {
+	if len(identity) == 0 {
+		identity = KubernetesIdentity
+	}
+	docs := newJsonObject()
+	keys := []string{}
+	dec := yaml.NewDecoder(strings.NewReader(s))
+	for i := 0; ; i++ {
+		var v interface{}
+		err := dec.Decode(&v)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if v == nil {
+			continue
+		}
+		n, err := NewJsonNode(v)
+		if err != nil {
+			return nil, nil, err
+		}
+		key, ok := documentIdentity(n, identity)
+		if !ok {
+			key = fmt.Sprintf("#%v", i)
+		}
+		if _, ok := docs[key]; ok {
+			return nil, nil, fmt.Errorf("duplicate document identity %v", key)
+		}
+		docs[key] = n
+		keys = append(keys, key)
+	}
+	return docs, keys, nil
+}
+
+// documentIdentity returns the identity of n as a JSON array, and
+// false when none of its identity values are found.
+func documentIdentity(n JsonNode, identity []Path) (string, bool) {
+	values := jsonArray{}
+	found := false
+	for _, p := range identity {
+		v, err := getPath(n, p)
+		if err != nil {
+			v = jsonNull{}
+		} else {
+			found = true
+		}
+		values = append(values, v)
+	}
+	return values.Json(), found
+}
+
+// YamlDocuments renders an object of documents read by
+// ReadYamlDocumentsString as a stream of YAML documents. Documents
+// found in the stream src, read with the same identity paths, keep
+// their order and the others follow in order of their identity.
+func YamlDocuments(n JsonNode, src string, identity ...Path) (string, error) {
+	if isVoid(n) {
+		return "", nil
+	}
+	docs, ok := n.(jsonObject)
+	if !ok {
+		return "", fmt.Errorf("expected an object of documents. got %v", n.Json())
+	}
+	_, order, err := readYamlDocuments(src, identity)
+	if err != nil {
+		return "", err
+	}
+	seen := map[string]bool{}
+	keys := make([]string, 0, len(docs))
+	for _, k := range order {
+		if _, ok := docs[k]; ok {
+			seen[k] = true
+			keys = append(keys, k)
+		}
+	}
+	added := []string{}
+	for k := range docs {
+		if !seen[k] {
+			added = append(added, k)
+		}
+	}
+	sort.Strings(added)
+	out := make([]string, 0, len(docs))
+	for _, k := range append(keys, added...) {
+		out = append(out, docs[k].Yaml())
+	}
+	return strings.Join(out, "---\n"), nil
+}
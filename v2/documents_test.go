@@ -0,0 +1,196 @@
+package jd
+
+import (
+	"testing"
+)
+
+func TestReadYamlDocuments(t *testing.T) {
+	a := s(
+		`apiVersion: apps/v1`,
+		`kind: Deployment`,
+		`metadata:`,
+		`  name: web`,
+		`  namespace: prod`,
+		`spec:`,
+		`  replicas: 1`,
+		`---`,
+		`apiVersion: v1`,
+		`kind: Namespace`,
+		`metadata:`,
+		`  name: prod`,
+		`---`,
+		`- not a resource`,
+		`---`,
+	)
+	b := s(
+		`apiVersion: v1`,
+		`kind: Namespace`,
+		`metadata:`,
+		`  name: prod`,
+		`---`,
+		`apiVersion: apps/v1`,
+		`kind: Deployment`,
+		`metadata:`,
+		`  name: web`,
+		`  namespace: prod`,
+		`spec:`,
+		`  replicas: 2`,
+		`---`,
+		`apiVersion: v1`,
+		`kind: Service`,
+		`metadata:`,
+		`  name: web`,
+		`  namespace: prod`,
+	)
+	na, err := ReadYamlDocumentsString(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nb, err := ReadYamlDocumentsString(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := na.Diff(nb)
+	want := s(
+		`@ ["#2"]`,
+		`- ["not a resource"]`,
+		`@ ["[\"apps/v1\",\"Deployment\",\"prod\",\"web\"]","spec","replicas"]`,
+		`- 1`,
+		`+ 2`,
+		`@ ["[\"v1\",\"Service\",\"prod\",\"web\"]"]`,
+		`+ {"apiVersion":"v1","kind":"Service","metadata":{"name":"web","namespace":"prod"}}`,
+	)
+	if got := d.Render(); got != want {
+		t.Errorf("got diff:\n%v\nwant:\n%v", got, want)
+	}
+	patched, err := na.Patch(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := YamlDocuments(patched, a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = s(
+		`apiVersion: apps/v1`,
+		`kind: Deployment`,
+		`metadata:`,
+		`    name: web`,
+		`    namespace: prod`,
+		`spec:`,
+		`    replicas: 2`,
+		`---`,
+		`apiVersion: v1`,
+		`kind: Namespace`,
+		`metadata:`,
+		`    name: prod`,
+		`---`,
+		`apiVersion: v1`,
+		`kind: Service`,
+		`metadata:`,
+		`    name: web`,
+		`    namespace: prod`,
+	)
+	if got != want {
+		t.Errorf("got documents:\n%v\nwant:\n%v", got, want)
+	}
+	roundTrip, err := ReadYamlDocumentsString(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !roundTrip.Equals(nb) {
+		t.Errorf("got %v after round trip. want %v", roundTrip.Json(), nb.Json())
+	}
+}
+
+func TestReadYamlDocumentsIdentity(t *testing.T) {
+	n, err := ReadYamlDocumentsString(s(
+		`id: 1`,
+		`v: a`,
+		`---`,
+		`id: 2`,
+		`v: b`,
+	), Path{PathKey("id")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"[1]":{"id":1,"v":"a"},"[2]":{"id":2,"v":"b"}}`; n.Json() != want {
+		t.Errorf("got %v. want %v", n.Json(), want)
+	}
+}
+
+func TestReadYamlDocumentsAmbiguousIdentity(t *testing.T) {
+	// Joined by "/" both identities would read "a/b/c".
+	n, err := ReadYamlDocumentsString(s(
+		`{x: a/b, y: c}`,
+		`---`,
+		`{x: a, y: b/c}`,
+		`---`,
+		`{y: a/b/c}`,
+	), Path{PathKey("x")}, Path{PathKey("y")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"[\"a\",\"b/c\"]":{"x":"a","y":"b/c"},"[\"a/b\",\"c\"]":{"x":"a/b","y":"c"},"[null,\"a/b/c\"]":{"y":"a/b/c"}}`
+	if n.Json() != want {
+		t.Errorf("got %v. want %v", n.Json(), want)
+	}
+}
+
+func TestYamlDocumentsOrder(t *testing.T) {
+	src := s(`id: 2`, `---`, `id: 3`, `---`, `id: 1`)
+	a, err := ReadYamlDocumentsString(src, Path{PathKey("id")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ReadYamlDocumentsString(s(`id: 1`, `---`, `id: 5`, `---`, `id: 2`, `---`, `id: 4`), Path{PathKey("id")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	patched, err := a.Patch(a.Diff(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := YamlDocuments(patched, src, Path{PathKey("id")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The documents of src keep their order and new ones follow.
+	want := s(`id: 2`, `---`, `id: 1`, `---`, `id: 4`, `---`, `id: 5`)
+	if got != want {
+		t.Errorf("got documents:\n%v\nwant:\n%v", got, want)
+	}
+}
+
+func TestReadYamlDocumentsEmpty(t *testing.T) {
+	for _, in := range []string{``, `---`, "---\n---\n"} {
+		n, err := ReadYamlDocumentsString(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !isVoid(n) {
+			t.Errorf("got %v reading %q. want void", n.Json(), in)
+		}
+		if got, _ := YamlDocuments(n, in); got != "" {
+			t.Errorf("got %q rendering void. want empty", got)
+		}
+	}
+}
+
+func TestReadYamlDocumentsErrors(t *testing.T) {
+	for _, in := range []string{
+		s(`kind: A`, `---`, `kind: A`),
+		s(`a: [`),
+		s(`1: a`),
+	} {
+		if _, err := ReadYamlDocumentsString(in, Path{PathKey("kind")}); err == nil {
+			t.Errorf("wanted error reading %q", in)
+		}
+	}
+	if _, err := YamlDocuments(jsonArray{}, ``); err == nil {
+		t.Errorf("wanted error rendering an array as documents")
+	}
+	if _, err := YamlDocuments(jsonObject{}, `a: [`); err == nil {
+		t.Errorf("wanted error reading the order of invalid documents")
+	}
+}
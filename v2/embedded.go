@@ -0,0 +1,115 @@
+package jd
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+type embeddedOption struct{}
+
+// EMBEDDED compares and diffs strings holding JSON or YAML documents
+// by the documents they hold. Two strings which both decode to an
+// object or array, as JSON or else both as YAML, are equal when their
+// documents are, and their diff descends into the documents under a
+// PathEmbedded path element. Patch decodes the string, applies the
+// nested hunks and encodes the result again, compactly for JSON, so
+// the formatting of a patched string is not preserved.
+//
+// Strings are never decoded by a merge diff, which cannot address the
+// inside of a string.
+var EMBEDDED = embeddedOption{}
+
+func (o embeddedOption) isOption() {}
+func (o embeddedOption) MarshalJSON() ([]byte, error) {
+	return json.Marshal("EMBEDDED")
+}
+
+// embeddedFormats are tried in order. JSON comes first since every
+// JSON document is also YAML.
+var embeddedFormats = []PathEmbedded{"json", "yaml"}
+
+// decode returns the object or array held by s in format e.
+func (e PathEmbedded) decode(s jsonString) (JsonNode, bool) {
+	read := ReadYamlString
+	if e == "json" {
+		read = ReadJsonString
+	}
+	n, err := read(string(s))
+	if err != nil {
+		return nil, false
+	}
+	switch n.(type) {
+	case jsonObject, jsonArray:
+		return n, true
+	}
+	return nil, false
+}
+
+// encode returns n written in format e.
+func (e PathEmbedded) encode(n JsonNode) jsonString {
+	if e == "json" {
+		return jsonString(n.Json())
+	}
+	return jsonString(n.Yaml())
+}
+
+// embeddedPair returns the documents held by n1 and n2 in the first
+// format which decodes both when EMBEDDED applies in o.
+func embeddedPair(n1, n2 JsonNode, o *options) (JsonNode, JsonNode, PathEmbedded, bool) {
+	if _, ok := getOption[embeddedOption](o); !ok {
+		return nil, nil, "", false
+	}
+	s1, ok1 := n1.(jsonString)
+	s2, ok2 := n2.(jsonString)
+	if !ok1 || !ok2 {
+		return nil, nil, "", false
+	}
+	for _, e := range embeddedFormats {
+		d1, ok1 := e.decode(s1)
+		d2, ok2 := e.decode(s2)
+		if ok1 && ok2 {
+			return d1, d2, e, true
+		}
+	}
+	return nil, nil, "", false
+}
+
+// embeddedDocument returns the document held by s in the first format
+// which decodes it when EMBEDDED applies in o.
+func embeddedDocument(s jsonString, o *options) (JsonNode, PathEmbedded, bool) {
+	if _, ok := getOption[embeddedOption](o); !ok {
+		return nil, "", false
+	}
+	for _, e := range embeddedFormats {
+		if d, ok := e.decode(s); ok {
+			return d, e, true
+		}
+	}
+	return nil, "", false
+}
+
+// patchEmbedded applies a hunk whose path steps from s into the
+// document it holds in format e.
+func patchEmbedded(
+	s jsonString,
+	e PathEmbedded,
+	pathBehind, pathAhead Path,
+	before, oldValues, newValues, after []JsonNode,
+	strategy patchStrategy,
+) (JsonNode, error) {
+	d, ok := e.decode(s)
+	if !ok {
+		return nil, fmt.Errorf(
+			"found %v at %v: expected embedded %v document",
+			s.Json(), pathBehind, e)
+	}
+	pathBehind = append(pathBehind.clone(), e)
+	n, err := d.patch(pathBehind, pathAhead, before, oldValues, newValues, after, strategy)
+	if err != nil {
+		return nil, err
+	}
+	if isVoid(n) {
+		return nil, fmt.Errorf("cannot remove the embedded document at %v", pathBehind)
+	}
+	return e.encode(n), nil
+}
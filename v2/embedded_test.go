@@ -0,0 +1,356 @@
+package jd
+
+import (
+	"testing"
+)
+
+func TestEmbeddedDiff(t *testing.T) {
+	cases := []struct {
+		name    string
+		options []Option
+		a       string
+		b       string
+		want    []string
+	}{{
+		name:    "json",
+		options: []Option{EMBEDDED},
+		a:       `{"policy":"{\"a\":1,\"b\":[1,2]}"}`,
+		b:       `{"policy":"{\"b\":[1,3], \"a\":1}"}`,
+		want: ss(
+			`@ ["policy",["json"],"b",1]`,
+			`  1`,
+			`- 2`,
+			`+ 3`,
+			`]`,
+		),
+	}, {
+		name:    "formatting only",
+		options: []Option{EMBEDDED},
+		a:       `{"policy":"{\"a\":1,\"b\":2}"}`,
+		b:       `{"policy":"{\n  \"b\": 2,\n  \"a\": 1\n}"}`,
+		want:    ss(),
+	}, {
+		name:    "yaml",
+		options: []Option{EMBEDDED},
+		a:       `{"values":"a: 1\nb: 2\n"}`,
+		b:       `{"values":"a: 1\nb: 3\n"}`,
+		want: ss(
+			`@ ["values",["yaml"],"b"]`,
+			`- 2`,
+			`+ 3`,
+		),
+	}, {
+		name:    "json and yaml",
+		options: []Option{EMBEDDED},
+		a:       `"{\"a\":1}"`,
+		b:       `"a: 2"`,
+		want: ss(
+			`@ [["yaml"],"a"]`,
+			`- 1`,
+			`+ 2`,
+		),
+	}, {
+		name:    "scalar documents",
+		options: []Option{EMBEDDED},
+		a:       `{"s":"1"}`,
+		b:       `{"s":"2"}`,
+		want: ss(
+			`@ ["s"]`,
+			`- "1"`,
+			`+ "2"`,
+		),
+	}, {
+		name:    "one side not a document",
+		options: []Option{EMBEDDED},
+		a:       `"{\"a\":1}"`,
+		b:       `"{\"a\":"`,
+		want: ss(
+			`@ []`,
+			`- "{\"a\":1}"`,
+			`+ "{\"a\":"`,
+		),
+	}, {
+		name:    "documents of different types",
+		options: []Option{EMBEDDED},
+		a:       `{"s":"{\"a\":1}"}`,
+		b:       `{"s":"[1]"}`,
+		want: ss(
+			`@ ["s",["json"]]`,
+			`- {"a":1}`,
+			`+ [1]`,
+		),
+	}, {
+		name:    "list",
+		options: []Option{EMBEDDED},
+		a:       `["x","{\"a\":1}"]`,
+		b:       `["x","{\"a\":2}"]`,
+		want: ss(
+			`@ [1,["json"],"a"]`,
+			`- 1`,
+			`+ 2`,
+		),
+	}, {
+		name:    "nested",
+		options: []Option{EMBEDDED},
+		a:       `"{\"inner\":\"[1,2]\"}"`,
+		b:       `"{\"inner\":\"[1,3]\"}"`,
+		want: ss(
+			`@ [["json"],"inner",["json"],1]`,
+			`  1`,
+			`- 2`,
+			`+ 3`,
+			`]`,
+		),
+	}, {
+		name:    "without option",
+		options: []Option{},
+		a:       `{"policy":"{\"a\":1}"}`,
+		b:       `{"policy":"{\"a\": 1}"}`,
+		want: ss(
+			`@ ["policy"]`,
+			`- "{\"a\":1}"`,
+			`+ "{\"a\": 1}"`,
+		),
+	}, {
+		name:    "path option",
+		options: []Option{PathOption(Path{PathKey("policy")}, EMBEDDED)},
+		a:       `{"policy":"{\"a\":1}","other":"{\"a\":1}"}`,
+		b:       `{"policy":"{\"a\":2}","other":"{\"a\":2}"}`,
+		want: ss(
+			`@ ["other"]`,
+			`- "{\"a\":1}"`,
+			`+ "{\"a\":2}"`,
+			`@ ["policy",["json"],"a"]`,
+			`- 1`,
+			`+ 2`,
+		),
+	}, {
+		name: "path option inside document",
+		options: []Option{
+			EMBEDDED,
+			PathOption(Path{PathKey("policy"), PathEmbedded("json"), PathKey("time")}, DIFF_OFF),
+		},
+		a: `{"policy":"{\"a\":1,\"time\":1}"}`,
+		b: `{"policy":"{\"a\":2,\"time\":2}"}`,
+		want: ss(
+			`@ ["policy",["json"],"a"]`,
+			`- 1`,
+			`+ 2`,
+		),
+	}, {
+		name: "wildcards do not select documents",
+		options: []Option{
+			EMBEDDED,
+			PathOption(Path{PathKey("policy"), PathAllValues{}}, DIFF_OFF),
+		},
+		a: `{"policy":"{\"a\":1}"}`,
+		b: `{"policy":"{\"a\":2}"}`,
+		want: ss(
+			`@ ["policy",["json"],"a"]`,
+			`- 1`,
+			`+ 2`,
+		),
+	}, {
+		name:    "set",
+		options: []Option{SET, EMBEDDED},
+		a:       `["{\"a\":1,\"b\":2}","x"]`,
+		b:       `["x","{\"b\":2, \"a\":1}"]`,
+		want:    ss(),
+	}, {
+		name:    "merge",
+		options: []Option{MERGE, EMBEDDED},
+		a:       `{"policy":"{\"a\":1}"}`,
+		b:       `{"policy":"{\"a\":2}"}`,
+		want: ss(
+			`^ {"Merge":true}`,
+			`@ ["policy"]`,
+			`+ "{\"a\":2}"`,
+		),
+	}}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ctx := newTestContext(t).withOptions(c.options...)
+			checkDiff(ctx, c.a, c.b, c.want...)
+		})
+	}
+}
+
+func TestEmbeddedEquals(t *testing.T) {
+	a, b := jsonString(`{"a":[1,2]}`), jsonString("a:\n- 1\n- 2\n")
+	if a.Equals(b) {
+		t.Errorf("%v equals %v without options", a.Json(), b.Json())
+	}
+	if !a.Equals(b, EMBEDDED) {
+		t.Errorf("%v does not equal %v with EMBEDDED", a.Json(), b.Json())
+	}
+	if a.Equals(jsonObject{"a": jsonArray{jsonNumber(1), jsonNumber(2)}}, EMBEDDED) {
+		t.Errorf("%v equals its document with EMBEDDED", a.Json())
+	}
+	if a.Equals(jsonString(`{"a":[2,1]}`), EMBEDDED) {
+		t.Errorf("%v equals its elements reordered with EMBEDDED", a.Json())
+	}
+}
+
+func TestEmbeddedPatch(t *testing.T) {
+	cases := []struct {
+		name string
+		a    string
+		diff []string
+		want string
+	}{{
+		name: "json",
+		a:    `{"policy":"{\"b\": [1, 2], \"a\": 1}"}`,
+		diff: ss(
+			`@ ["policy",["json"],"b",1]`,
+			`  1`,
+			`- 2`,
+			`+ 3`,
+			`]`,
+		),
+		want: `{"policy":"{\"a\":1,\"b\":[1,3]}"}`,
+	}, {
+		name: "yaml",
+		a:    `{"values":"a: 1\nb: 2\n"}`,
+		diff: ss(
+			`@ ["values",["yaml"],"b"]`,
+			`- 2`,
+			`+ 3`,
+		),
+		want: `{"values":"a: 1\nb: 3\n"}`,
+	}, {
+		name: "whole document",
+		a:    `["{\"a\":1}"]`,
+		diff: ss(
+			`@ [0,["json"]]`,
+			`- {"a":1}`,
+			`+ [1]`,
+		),
+		want: `["[1]"]`,
+	}, {
+		name: "nested",
+		a:    `"{\"inner\":\"[1,2]\"}"`,
+		diff: ss(
+			`@ [["json"],"inner",["json"],1]`,
+			`  1`,
+			`- 2`,
+			`+ 3`,
+			`]`,
+		),
+		want: `"{\"inner\":\"[1,3]\"}"`,
+	}}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			checkPatch(newTestContext(t), c.a, c.want, c.diff...)
+		})
+	}
+}
+
+func TestEmbeddedPatchError(t *testing.T) {
+	cases := []struct {
+		name string
+		a    string
+		diff []string
+	}{{
+		name: "not a document",
+		a:    `{"policy":"a: 1"}`,
+		diff: ss(
+			`@ ["policy",["json"],"a"]`,
+			`- 1`,
+			`+ 2`,
+		),
+	}, {
+		name: "wrong value",
+		a:    `{"policy":"{\"a\":1}"}`,
+		diff: ss(
+			`@ ["policy",["json"],"a"]`,
+			`- 2`,
+			`+ 3`,
+		),
+	}, {
+		name: "remove document",
+		a:    `{"policy":"{\"a\":1}"}`,
+		diff: ss(
+			`@ ["policy",["json"]]`,
+			`- {"a":1}`,
+		),
+	}}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			checkPatchError(newTestContext(t), c.a, c.diff...)
+		})
+	}
+}
+
+func TestEmbeddedRoundTrip(t *testing.T) {
+	a, err := ReadJsonString(`{"env":"{\"image\":\"web:1\",\"ports\":[80]}","cfg":"replicas: 1\n"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ReadJsonString(`{"env":"{\"ports\":[80,443],\"image\":\"web:2\"}","cfg":"replicas: 3\n"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := ReadDiffString(a.Diff(b, EMBEDDED).Render())
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := a.Patch(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Equals(b, EMBEDDED) {
+		t.Errorf("patched %v. want %v", got.Json(), b.Json())
+	}
+	if _, err := d.RenderPatch(); err == nil {
+		t.Errorf("rendered a JSON Patch into an embedded document")
+	}
+}
+
+func TestEmbeddedSetRoundTrip(t *testing.T) {
+	cases := []struct {
+		name    string
+		options []Option
+		a       string
+		b       string
+	}{{
+		name:    "set document replaced",
+		options: []Option{SET, EMBEDDED},
+		a:       `{"r":"[1,2]"}`,
+		b:       `{"r":"{\"a\":2}"}`,
+	}, {
+		name:    "multiset document replaced",
+		options: []Option{MULTISET, EMBEDDED},
+		a:       `{"r":"[1,2]"}`,
+		b:       `{"r":"{\"a\":2}"}`,
+	}, {
+		name:    "set in document",
+		options: []Option{SET, EMBEDDED},
+		a:       `{"r":"{\"x\":[3,1,2]}"}`,
+		b:       `{"r":"{\"x\":[2,4]}"}`,
+	}, {
+		name:    "multiset in document",
+		options: []Option{MULTISET, EMBEDDED},
+		a:       `{"r":"{\"x\":[3,1,1]}"}`,
+		b:       `{"r":"{\"x\":[1,4]}"}`,
+	}, {
+		name:    "set of documents and values",
+		options: []Option{SET, EMBEDDED},
+		a:       `[1,[],"[]"]`,
+		b:       `[{"a":1}]`,
+	}, {
+		name:    "set of documents and objects",
+		options: []Option{SET, EMBEDDED},
+		a:       `["{}",3,{}]`,
+		b:       `[[],{"c":1}]`,
+	}, {
+		name:    "multiset of equal documents",
+		options: []Option{MULTISET, EMBEDDED},
+		a:       `{"q":["a: 1","{\"a\":1}"]}`,
+		b:       `{"q":[1]}`,
+	}}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			checkRoundTrip(t, c.a, c.b, c.options)
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package jd
+
+import (
+	"fmt"
+)
+
+// ParseError is a diff in native jd format which could not be read.
+// Line and Col are 1-based. Col is the offending character when it is
+// known and otherwise the start of the line (1) or of its value (2).
+type ParseError struct {
+	Line    int
+	Col     int
+	Message string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("invalid diff at line %v. %v", e.Line, e.Message)
+}
+
+// PatchConflictError is a hunk which does not apply because the value
+// it expects at Path, to remove or as context, is not the value found
+// there. It usually means the patched value changed since the diff was
+// made. A void Expected or Actual is the absence of a value.
+type PatchConflictError struct {
+	Path     Path
+	Expected JsonNode
+	Actual   JsonNode
+}
+
+func (e *PatchConflictError) Error() string {
+	return fmt.Sprintf("found %v at %v: expected %v",
+		describeValue(e.Actual), e.Path, describeValue(e.Expected))
+}
+
+// PathNotFoundError is a hunk which does not apply because its Path,
+// or the Path of its context, leads to no value.
+type PathNotFoundError struct {
+	Path Path
+}
+
+func (e *PathNotFoundError) Error() string {
+	return fmt.Sprintf("no value at %v", e.Path)
+}
+
+func describeValue(n JsonNode) string {
+	if n == nil || isVoid(n) {
+		return "nothing"
+	}
+	return n.Json()
+}
@@ -0,0 +1,248 @@
+package jd
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseError(t *testing.T) {
+	cases := []struct {
+		name     string
+		diff     []string
+		wantLine int
+		wantCol  int
+		wantErr  string
+	}{{
+		name:     "unexpected line",
+		diff:     ss(`@ ["a"]`, `^ "SET"`),
+		wantLine: 2,
+		wantCol:  1,
+		wantErr:  `invalid diff at line 2. Unexpected ^. Expecting one of [[   - + < =]`,
+	}, {
+		name:     "syntax error in value",
+		diff:     ss(`@ ["a"]`, `- {"b":x}`),
+		wantLine: 2,
+		wantCol:  8,
+		wantErr:  `invalid diff at line 2. Invalid value. invalid character 'x' looking for beginning of value`,
+	}, {
+		name:     "two values",
+		diff:     ss(`@ ["a"]`, `+ 1 2`),
+		wantLine: 2,
+		wantCol:  2,
+		wantErr:  `invalid diff at line 2. Invalid value. invalid data after top-level value`,
+	}, {
+		name:     "unterminated path",
+		diff:     ss(`@ ["a"`),
+		wantLine: 1,
+		wantCol:  7,
+		wantErr:  `invalid diff at line 1. Invalid path. unexpected EOF`,
+	}, {
+		name:     "invalid path",
+		diff:     ss(`@ {}`),
+		wantLine: 1,
+		wantCol:  2,
+		wantErr:  `invalid diff at line 1. path must be an array. got jd.jsonObject`,
+	}, {
+		name:     "unexpected end",
+		diff:     ss(`@ ["a"]`),
+		wantLine: 3,
+		wantCol:  1,
+		wantErr:  `invalid diff at line 3. Unexpected end of diff. Expecting -, +, < or =.`,
+	}}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := ReadDiffString(joinLines(c.diff))
+			var parseErr *ParseError
+			if !errors.As(err, &parseErr) {
+				t.Fatalf("wanted a ParseError. got %v", err)
+			}
+			if parseErr.Line != c.wantLine || parseErr.Col != c.wantCol {
+				t.Errorf("wanted line %v col %v. got line %v col %v",
+					c.wantLine, c.wantCol, parseErr.Line, parseErr.Col)
+			}
+			if err.Error() != c.wantErr {
+				t.Errorf("wanted error %q. got %q", c.wantErr, err.Error())
+			}
+		})
+	}
+}
+
+func joinLines(lines []string) string {
+	s := ""
+	for _, l := range lines {
+		s += l + "\n"
+	}
+	return s
+}
+
+func TestPatchConflictError(t *testing.T) {
+	cases := []struct {
+		name         string
+		a            string
+		diff         []string
+		wantPath     string
+		wantExpected string
+		wantActual   string
+		wantErr      string
+	}{{
+		name:         "object value",
+		a:            `{"a":1}`,
+		diff:         ss(`@ ["a"]`, `- 2`, `+ 3`),
+		wantPath:     `["a"]`,
+		wantExpected: `2`,
+		wantActual:   `1`,
+		wantErr:      `found 1 at [a]: expected 2`,
+	}, {
+		name:         "added where a value is",
+		a:            `{"a":1}`,
+		diff:         ss(`@ ["a"]`, `+ 3`),
+		wantPath:     `["a"]`,
+		wantExpected: ``,
+		wantActual:   `1`,
+		wantErr:      `found 1 at [a]: expected nothing`,
+	}, {
+		name:         "list value",
+		a:            `[1,2,3]`,
+		diff:         ss(`@ [1]`, `  1`, `- 4`, `  3`),
+		wantPath:     `[1]`,
+		wantExpected: `4`,
+		wantActual:   `2`,
+	}, {
+		name:         "before context",
+		a:            `[1,2,3]`,
+		diff:         ss(`@ [1]`, `  4`, `- 2`, `  3`),
+		wantPath:     `[0]`,
+		wantExpected: `4`,
+		wantActual:   `1`,
+	}, {
+		name:         "after context",
+		a:            `[1,2,3]`,
+		diff:         ss(`@ [1]`, `  1`, `- 2`, `  4`),
+		wantPath:     `[1]`,
+		wantExpected: `4`,
+		wantActual:   `3`,
+	}, {
+		name:         "whole list",
+		a:            `{"a":[1]}`,
+		diff:         ss(`@ ["a"]`, `- [2]`, `+ 1`),
+		wantPath:     `["a"]`,
+		wantExpected: `[2]`,
+		wantActual:   `[1]`,
+	}, {
+		name:         "set",
+		a:            `[1,2]`,
+		diff:         ss(`^ "SET"`, `@ [{}]`, `- 3`),
+		wantPath:     `[]`,
+		wantExpected: `3`,
+		wantActual:   ``,
+		wantErr:      `found nothing at []: expected 3`,
+	}, {
+		name:         "multiset",
+		a:            `[1,2]`,
+		diff:         ss(`^ "MULTISET"`, `@ [[]]`, `- 3`),
+		wantPath:     `[]`,
+		wantExpected: `3`,
+		wantActual:   ``,
+	}}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			a := mustParse(t, c.a)
+			d, err := ReadDiffString(joinLines(c.diff))
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = a.Patch(d)
+			var conflictErr *PatchConflictError
+			if !errors.As(err, &conflictErr) {
+				t.Fatalf("wanted a PatchConflictError. got %v", err)
+			}
+			if got := conflictErr.Path.JsonNode().Json(); got != c.wantPath {
+				t.Errorf("wanted path %v. got %v", c.wantPath, got)
+			}
+			if got := conflictErr.Expected.Json(); got != c.wantExpected {
+				t.Errorf("wanted expected %v. got %v", c.wantExpected, got)
+			}
+			if got := conflictErr.Actual.Json(); got != c.wantActual {
+				t.Errorf("wanted actual %v. got %v", c.wantActual, got)
+			}
+			if c.wantErr != "" && err.Error() != c.wantErr {
+				t.Errorf("wanted error %q. got %q", c.wantErr, err.Error())
+			}
+		})
+	}
+	err := &PatchConflictError{Path: Path{PathKey("a")}, Expected: jsonNumber(1)}
+	if got, want := err.Error(), "found nothing at [a]: expected 1"; got != want {
+		t.Errorf("wanted error %q. got %q", want, got)
+	}
+}
+
+func TestPathNotFoundError(t *testing.T) {
+	cases := []struct {
+		name     string
+		a        string
+		diff     []string
+		wantPath string
+		wantErr  string
+	}{{
+		name:     "index",
+		a:        `[[1]]`,
+		diff:     ss(`@ [3,0]`, `- 1`),
+		wantPath: `[3]`,
+		wantErr:  `no value at [3]`,
+	}, {
+		name:     "remove",
+		a:        `[1]`,
+		diff:     ss(`@ [1]`, `  1`, `- 2`),
+		wantPath: `[1]`,
+	}, {
+		name:     "before context",
+		a:        `[1]`,
+		diff:     ss(`@ [0]`, `  0`, `- 1`),
+		wantPath: `[-1]`,
+	}, {
+		name:     "before context past the end",
+		a:        `[1]`,
+		diff:     ss(`@ [3]`, `  1`, `+ 2`),
+		wantPath: `[2]`,
+	}, {
+		name:     "after context",
+		a:        `[1]`,
+		diff:     ss(`@ [0]`, `- 1`, `  2`),
+		wantPath: `[0]`,
+	}, {
+		name:     "set keys",
+		a:        `[{"id":1,"a":1}]`,
+		diff:     ss(`^ {"keys":["id"]}`, `@ [{"id":2},"a"]`, `- 1`, `+ 2`),
+		wantPath: `[{"id":2}]`,
+	}, {
+		name:     "move from",
+		a:        `{"a":1}`,
+		diff:     ss(`@ ["c"]`, `< ["b"]`),
+		wantPath: `["b"]`,
+	}, {
+		name:     "move from index",
+		a:        `{"a":[1]}`,
+		diff:     ss(`@ ["c"]`, `< ["a",1]`),
+		wantPath: `["a",1]`,
+	}}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			a := mustParse(t, c.a)
+			d, err := ReadDiffString(joinLines(c.diff))
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = a.Patch(d)
+			var notFoundErr *PathNotFoundError
+			if !errors.As(err, &notFoundErr) {
+				t.Fatalf("wanted a PathNotFoundError. got %v", err)
+			}
+			if got := notFoundErr.Path.JsonNode().Json(); got != c.wantPath {
+				t.Errorf("wanted path %v. got %v", c.wantPath, got)
+			}
+			if c.wantErr != "" && err.Error() != c.wantErr {
+				t.Errorf("wanted error %q. got %q", c.wantErr, err.Error())
+			}
+		})
+	}
+}
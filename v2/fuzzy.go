@@ -0,0 +1,125 @@
+package jd
+
+import (
+	"errors"
+)
+
+// HunkResult is how PatchFuzzy applied one hunk.
+type HunkResult struct {
+	// Path is where the hunk was applied, which differs from its own
+	// Path by Offset.
+	Path Path
+
+	// Offset was added to the last list index of the hunk's Path to
+	// find values and context matching the hunk.
+	Offset int
+
+	// Err is why the hunk was rejected, or nil when it was applied.
+	Err error
+}
+
+// PatchFuzzy applies d to n like Patch, tolerating a value which has
+// drifted since the Diff was made, in the manner of GNU patch. A hunk
+// which does not match at its Path is tried with the last list index
+// of its Path moved up to maxOffset places either way, starting from
+// the offset of the previous hunk in the same list. Hunks which still
+// do not match are rejected and the rest are applied. PatchFuzzy
+// returns the patched value, the result of each hunk and the rejected
+// hunks as a Diff.
+//
+// Only conflicting values and missing paths are searched for. Each
+// hunk is tried against a copy of the value so a rejected hunk leaves
+// no trace.
+func PatchFuzzy(n JsonNode, d Diff, maxOffset int) (JsonNode, []HunkResult, Diff) {
+	results := make([]HunkResult, len(d))
+	rejected := Diff{}
+	// offsets are the last offsets applied in each list.
+	offsets := map[string]int{}
+	for i, de := range d {
+		patched, result := patchFuzzyHunk(n, de, maxOffset, offsets)
+		results[i] = result
+		if result.Err != nil {
+			rejected = append(rejected, de)
+			continue
+		}
+		n = patched
+	}
+	return n, results, rejected
+}
+
+func patchFuzzyHunk(n JsonNode, de DiffElement, maxOffset int, offsets map[string]int) (JsonNode, HunkResult) {
+	at := -1
+	for i, e := range de.Path {
+		if _, ok := e.(PathIndex); ok {
+			at = i
+		}
+	}
+	if at < 0 || de.Path[at] == PathIndex(-1) || de.From != nil || de.Metadata.Unchecked || de.Metadata.Merge {
+		patched, err := patchAll(cloneNode(n), Diff{de})
+		return patched, HunkResult{Path: de.Path, Err: err}
+	}
+	list := de.Path[:at].JsonNode().Json()
+	index := int(de.Path[at].(PathIndex))
+	var firstErr error
+	for _, offset := range fuzzOffsets(offsets[list], maxOffset) {
+		if index+offset < 0 {
+			continue
+		}
+		shifted := de
+		shifted.Path = de.Path.clone()
+		shifted.Path[at] = PathIndex(index + offset)
+		patched, err := patchAll(cloneNode(n), Diff{shifted})
+		if err == nil {
+			offsets[list] = offset
+			return patched, HunkResult{Path: shifted.Path, Offset: offset}
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+		var conflictErr *PatchConflictError
+		var notFoundErr *PathNotFoundError
+		if !errors.As(err, &conflictErr) && !errors.As(err, &notFoundErr) {
+			break
+		}
+	}
+	return nil, HunkResult{Path: de.Path, Err: firstErr}
+}
+
+// fuzzOffsets are the offsets to try in order of distance from start.
+func fuzzOffsets(start, maxOffset int) []int {
+	offsets := []int{start}
+	for k := 1; k <= maxOffset; k++ {
+		offsets = append(offsets, start+k, start-k)
+	}
+	return offsets
+}
+
+// cloneNode copies the objects and arrays of n, which Patch may
+// change in place.
+func cloneNode(n JsonNode) JsonNode {
+	switch n := n.(type) {
+	case jsonObject:
+		o := make(jsonObject, len(n))
+		for k, v := range n {
+			o[k] = cloneNode(v)
+		}
+		return o
+	case jsonArray:
+		return jsonArray(cloneNodes(n))
+	case jsonList:
+		return jsonList(cloneNodes(n))
+	case jsonSet:
+		return jsonSet(cloneNodes(n))
+	case jsonMultiset:
+		return jsonMultiset(cloneNodes(n))
+	}
+	return n
+}
+
+func cloneNodes(ns []JsonNode) []JsonNode {
+	c := make([]JsonNode, len(ns))
+	for i, n := range ns {
+		c[i] = cloneNode(n)
+	}
+	return c
+}
@@ -0,0 +1,183 @@
+package jd
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestPatchFuzzy(t *testing.T) {
+	cases := []struct {
+		name         string
+		a            string
+		diff         []string
+		maxOffset    int
+		want         string
+		wantOffsets  []int
+		wantRejected []string
+	}{{
+		name:        "no drift",
+		a:           `[1,2,3]`,
+		diff:        ss(`@ [1]`, `  1`, `- 2`, `+ 4`, `  3`),
+		want:        `[1,4,3]`,
+		wantOffsets: []int{0},
+	}, {
+		name:        "drifted list",
+		a:           `{"a":[0,0,1,2,3]}`,
+		diff:        ss(`@ ["a",1]`, `  1`, `- 2`, `+ 4`, `  3`),
+		maxOffset:   3,
+		want:        `{"a":[0,0,1,4,3]}`,
+		wantOffsets: []int{2},
+	}, {
+		name:        "drifted backwards",
+		a:           `[2,3]`,
+		diff:        ss(`@ [2]`, `  2`, `- 3`),
+		maxOffset:   1,
+		want:        `[2]`,
+		wantOffsets: []int{-1},
+	}, {
+		name: "offset carried to the next hunk",
+		a:    `[0,0,0,1,2,3,4,5,6]`,
+		diff: ss(
+			`@ [1]`, `  1`, `- 2`, `  3`,
+			`@ [3]`, `  4`, `- 5`, `  6`,
+		),
+		maxOffset:   3,
+		want:        `[0,0,0,1,3,4,6]`,
+		wantOffsets: []int{3, 3},
+	}, {
+		name: "rejected value",
+		a:    `{"a":1,"b":[1,2]}`,
+		diff: ss(
+			`@ ["a"]`, `- 2`, `+ 3`,
+			`@ ["b",1]`, `  1`, `- 2`, `]`,
+		),
+		maxOffset:    2,
+		want:         `{"a":1,"b":[1]}`,
+		wantOffsets:  []int{0, 0},
+		wantRejected: ss(`@ ["a"]`, `- 2`, `+ 3`),
+	}, {
+		name:         "rejected beyond the offset",
+		a:            `[0,0,0,1,2,3]`,
+		diff:         ss(`@ [1]`, `  1`, `- 2`, `  3`),
+		maxOffset:    1,
+		want:         `[0,0,0,1,2,3]`,
+		wantOffsets:  []int{0},
+		wantRejected: ss(`@ [1]`, `  1`, `- 2`, `  3`),
+	}, {
+		name:         "rejected at the start",
+		a:            `[1]`,
+		diff:         ss(`@ [0]`, `- 2`),
+		maxOffset:    1,
+		want:         `[1]`,
+		wantOffsets:  []int{0},
+		wantRejected: ss(`@ [0]`, `- 2`),
+	}, {
+		name:         "not a list",
+		a:            `{"a":{"b":1}}`,
+		diff:         ss(`@ ["a",0]`, `- 1`),
+		maxOffset:    2,
+		want:         `{"a":{"b":1}}`,
+		wantOffsets:  []int{0},
+		wantRejected: ss(`@ ["a",0]`, `- 1`),
+	}, {
+		name:        "append",
+		a:           `[1]`,
+		diff:        ss(`@ [-1]`, `+ 2`),
+		maxOffset:   2,
+		want:        `[1,2]`,
+		wantOffsets: []int{0},
+	}, {
+		name:        "move",
+		a:           `{"a":[1],"b":2}`,
+		diff:        ss(`@ ["c"]`, `< ["a"]`),
+		maxOffset:   2,
+		want:        `{"b":2,"c":[1]}`,
+		wantOffsets: []int{0},
+	}}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			a := mustParse(t, c.a)
+			d, err := ReadDiffString(joinLines(c.diff))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, results, rejected := PatchFuzzy(a, d, c.maxOffset)
+			if got.Json() != c.want {
+				t.Errorf("got %v. want %v", got.Json(), c.want)
+			}
+			offsets := []int{}
+			for _, r := range results {
+				offsets = append(offsets, r.Offset)
+			}
+			if !reflect.DeepEqual(offsets, c.wantOffsets) {
+				t.Errorf("got offsets %v. want %v", offsets, c.wantOffsets)
+			}
+			if got, want := rejected.Render(), joinLines(c.wantRejected); got != want {
+				t.Errorf("got rejected:\n%v\nwant:\n%v", got, want)
+			}
+		})
+	}
+}
+
+func TestPatchFuzzyResults(t *testing.T) {
+	a := mustParse(t, `{"a":[0,1,2],"b":1}`)
+	d, err := ReadDiffString(joinLines(ss(
+		`@ ["a",0]`, `  1`, `- 2`, `]`,
+		`@ ["b"]`, `- 2`,
+	)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, results, _ := PatchFuzzy(a, d, 2)
+	if got := results[0].Path.JsonNode().Json(); got != `["a",2]` {
+		t.Errorf("got path %v. want [\"a\",2]", got)
+	}
+	if results[0].Err != nil {
+		t.Errorf("wanted no error. got %v", results[0].Err)
+	}
+	var conflictErr *PatchConflictError
+	if !errors.As(results[1].Err, &conflictErr) {
+		t.Errorf("wanted a PatchConflictError. got %v", results[1].Err)
+	}
+}
+
+func TestPatchFuzzyLeavesRejectedHunks(t *testing.T) {
+	// The hunk removes an element before its after context fails.
+	// The list must be as it was.
+	a := mustParse(t, `{"a":[1,2,3]}`)
+	d, err := ReadDiffString(joinLines(ss(`@ ["a",1]`, `  1`, `- 2`, `  4`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _, rejected := PatchFuzzy(a, d, 0)
+	if len(rejected) != 1 {
+		t.Fatalf("wanted the hunk rejected. got %v", rejected.Render())
+	}
+	if got.Json() != `{"a":[1,2,3]}` {
+		t.Errorf("got %v. want the value unchanged", got.Json())
+	}
+}
+
+func TestCloneNode(t *testing.T) {
+	nodes := []JsonNode{
+		jsonObject{"a": jsonArray{jsonNumber(1)}},
+		jsonArray{jsonObject{"a": jsonNumber(1)}},
+		jsonList{jsonNumber(1)},
+		jsonSet{jsonNumber(1)},
+		jsonMultiset{jsonNumber(1)},
+		jsonString("a"),
+	}
+	for _, n := range nodes {
+		c := cloneNode(n)
+		if !reflect.DeepEqual(c, n) {
+			t.Errorf("%T: got %v. want %v", n, c.Json(), n.Json())
+		}
+	}
+	o := jsonObject{"a": jsonList{jsonNumber(1)}}
+	c := cloneNode(o).(jsonObject)
+	c["a"].(jsonList)[0] = jsonNumber(2)
+	if o.Json() != `{"a":[1]}` {
+		t.Errorf("changed the original to %v", o.Json())
+	}
+}
@@ -7,6 +7,7 @@ toolchain go1.24.13
 require (
 	github.com/stretchr/testify v1.11.1
 	go.yaml.in/yaml/v3 v3.0.4
+	golang.org/x/text v0.34.0
 )
 
 require (
@@ -0,0 +1,381 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/josephburnett/jd/v2"
+)
+
+// apiServer serves the diff, patch and translate endpoints of
+// -serve-api. Each endpoint takes a POST of a JSON or YAML object and
+// answers with the result or a JSON apiError.
+type apiServer struct {
+	maxBytes  int64
+	timeout   time.Duration
+	endpoints map[string]apiEndpoint
+}
+
+// apiEndpoint handles the body of one request. It should give up when
+// ctx is done, checking ctx between steps which do not take it, so no
+// work goes on after the request has timed out.
+type apiEndpoint func(ctx context.Context, req apiRequest) (apiResponse, error)
+
+// apiRequest is a request body read as JSON. A YAML body is translated
+// to JSON first.
+type apiRequest struct {
+	body []byte
+	yaml bool
+}
+
+type apiResponse struct {
+	contentType string
+	body        string
+}
+
+// apiError is a failed request. It is written as
+// {"error":{"code":CODE,"message":MESSAGE}} with its HTTP status. The
+// typed errors of the jd library add their type and the fields which
+// locate them.
+type apiError struct {
+	status   int
+	Code     string          `json:"code,omitempty"`
+	Message  string          `json:"message"`
+	Type     string          `json:"type,omitempty"`
+	Line     int             `json:"line,omitempty"`
+	Col      int             `json:"col,omitempty"`
+	Path     json.RawMessage `json:"path,omitempty"`
+	Expected json.RawMessage `json:"expected,omitempty"`
+	Actual   json.RawMessage `json:"actual,omitempty"`
+}
+
+func (e *apiError) Error() string {
+	return e.Message
+}
+
+func newApiError(status int, code string, err error) *apiError {
+	e := &apiError{status: status, Code: code, Message: err.Error()}
+	var (
+		parseErr    *jd.ParseError
+		conflictErr *jd.PatchConflictError
+		notFoundErr *jd.PathNotFoundError
+	)
+	switch {
+	case errors.As(err, &parseErr):
+		e.Type = "parse_error"
+		e.Line, e.Col = parseErr.Line, parseErr.Col
+	case errors.As(err, &conflictErr):
+		e.Type = "patch_conflict"
+		e.Path = json.RawMessage(conflictErr.Path.JsonNode().Json())
+		e.Expected = json.RawMessage(conflictErr.Expected.Json())
+		e.Actual = json.RawMessage(conflictErr.Actual.Json())
+	case errors.As(err, &notFoundErr):
+		e.Type = "path_not_found"
+		e.Path = json.RawMessage(notFoundErr.Path.JsonNode().Json())
+	}
+	return e
+}
+
+// diffContentTypes are the media types of each diff format.
+var diffContentTypes = map[string]string{
+	"":           "text/plain; charset=utf-8",
+	"jd":         "text/plain; charset=utf-8",
+	"patch":      "application/json-patch+json",
+	"merge":      "application/merge-patch+json",
+	"json-hunks": "application/json",
+}
+
+func newApiServer(maxBytes int64, timeout time.Duration) *apiServer {
+	return &apiServer{
+		maxBytes: maxBytes,
+		timeout:  timeout,
+		endpoints: map[string]apiEndpoint{
+			"/diff":      apiDiff,
+			"/patch":     apiPatch,
+			"/translate": apiTranslate,
+		},
+	}
+}
+
+func (s *apiServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	endpoint, ok := s.endpoints[r.URL.Path]
+	if !ok {
+		writeApiError(w, newApiError(http.StatusNotFound, "not_found",
+			fmt.Errorf("no endpoint %v", r.URL.Path)))
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeApiError(w, newApiError(http.StatusMethodNotAllowed, "method_not_allowed",
+			fmt.Errorf("%v requires POST. got %v", r.URL.Path, r.Method)))
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, s.maxBytes))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeApiError(w, newApiError(http.StatusRequestEntityTooLarge, "too_large",
+				fmt.Errorf("request body exceeds %v bytes", s.maxBytes)))
+			return
+		}
+		writeApiError(w, newApiError(http.StatusBadRequest, "invalid_request", err))
+		return
+	}
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	req := apiRequest{
+		body: body,
+		yaml: mediaType == "application/yaml" || mediaType == "application/x-yaml" || mediaType == "text/yaml",
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), s.timeout)
+	defer cancel()
+	type result struct {
+		res apiResponse
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		// A panic here would not be recovered by net/http, which
+		// only guards the handler goroutine.
+		defer func() {
+			if r := recover(); r != nil {
+				done <- result{err: fmt.Errorf("%v", r)}
+			}
+		}()
+		res, err := endpoint(ctx, req)
+		done <- result{res, err}
+	}()
+	timeout := newApiError(http.StatusServiceUnavailable, "timeout",
+		fmt.Errorf("request took longer than %v", s.timeout))
+	select {
+	case <-ctx.Done():
+		writeApiError(w, timeout)
+	case result := <-done:
+		if result.err != nil {
+			var e *apiError
+			if errors.Is(result.err, context.DeadlineExceeded) {
+				e = timeout
+			} else if !errors.As(result.err, &e) {
+				e = newApiError(http.StatusInternalServerError, "internal", result.err)
+			}
+			writeApiError(w, e)
+			return
+		}
+		w.Header().Set("Content-Type", result.res.contentType)
+		io.WriteString(w, result.res.body)
+	}
+}
+
+func writeApiError(w http.ResponseWriter, e *apiError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(e.status)
+	json.NewEncoder(w).Encode(map[string]*apiError{"error": e})
+}
+
+// decode reads the request body into v, rejecting unknown fields.
+func (req apiRequest) decode(v any) error {
+	body := req.body
+	if req.yaml {
+		n, err := jd.ReadYamlString(string(body))
+		if err != nil {
+			return newApiError(http.StatusBadRequest, "invalid_request", err)
+		}
+		body = []byte(n.Json())
+	}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return newApiError(http.StatusBadRequest, "invalid_request", err)
+	}
+	return nil
+}
+
+// document reads the value of the request field name.
+func (req apiRequest) document(name string, raw json.RawMessage) (jd.JsonNode, error) {
+	if len(raw) == 0 {
+		return nil, newApiError(http.StatusBadRequest, "invalid_request",
+			fmt.Errorf("missing %q", name))
+	}
+	n, err := jd.ReadJsonString(string(raw))
+	if err != nil { //jd:nocover — the body was already decoded as JSON
+		return nil, newApiError(http.StatusBadRequest, "invalid_document", err)
+	}
+	return n, nil
+}
+
+// render writes n as the request was written, in JSON or YAML.
+func (req apiRequest) render(n jd.JsonNode, options ...jd.Option) apiResponse {
+	if req.yaml {
+		return apiResponse{"application/yaml", n.Yaml(options...)}
+	}
+	return apiResponse{"application/json", n.Json(options...)}
+}
+
+// readApiOptions reads an options array in the syntax of -opts.
+func readApiOptions(raw json.RawMessage) ([]jd.Option, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	options, err := jd.ReadOptionsString(string(raw))
+	if err == nil {
+		err = jd.ValidateOptions(options)
+	}
+	if err != nil {
+		return nil, newApiError(http.StatusBadRequest, "invalid_options", err)
+	}
+	return options, nil
+}
+
+// readApiDiff reads the diff in field name written in format. A diff
+// in the jd format is given as a string. The JSON formats may be given
+// either as a string or as the JSON value itself.
+func readApiDiff(name string, raw json.RawMessage, format string) (jd.Diff, error) {
+	if len(raw) == 0 {
+		return nil, newApiError(http.StatusBadRequest, "invalid_request",
+			fmt.Errorf("missing %q", name))
+	}
+	text := string(raw)
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		text = s
+	}
+	diff, err := readDiff(text, format)
+	if err != nil {
+		return nil, newApiError(http.StatusBadRequest, "invalid_diff", err)
+	}
+	return diff, nil
+}
+
+func checkApiFormat(format string) error {
+	if _, ok := diffContentTypes[format]; !ok {
+		return newApiError(http.StatusBadRequest, "invalid_format",
+			fmt.Errorf("invalid format: %q", format))
+	}
+	return nil
+}
+
+// diffRequest asks for the diff of A and B.
+type diffRequest struct {
+	A       json.RawMessage `json:"a"`
+	B       json.RawMessage `json:"b"`
+	Options json.RawMessage `json:"options"`
+	Format  string          `json:"format"`
+}
+
+func apiDiff(ctx context.Context, req apiRequest) (apiResponse, error) {
+	var body diffRequest
+	if err := req.decode(&body); err != nil {
+		return apiResponse{}, err
+	}
+	if err := checkApiFormat(body.Format); err != nil {
+		return apiResponse{}, err
+	}
+	options, err := readApiOptions(body.Options)
+	if err != nil {
+		return apiResponse{}, err
+	}
+	if body.Format == "merge" {
+		options = append(options, jd.MERGE)
+	}
+	a, err := req.document("a", body.A)
+	if err != nil {
+		return apiResponse{}, err
+	}
+	b, err := req.document("b", body.B)
+	if err != nil {
+		return apiResponse{}, err
+	}
+	options = jd.InferKeys(a, b, options...)
+	diff, err := a.DiffContext(ctx, b, options...)
+	if err != nil {
+		return apiResponse{}, err
+	}
+	if err := ctx.Err(); err != nil {
+		return apiResponse{}, err
+	}
+	out, _, err := renderDiff(diff, body.Format, options)
+	if err != nil {
+		return apiResponse{}, newApiError(http.StatusUnprocessableEntity, "render_failed", err)
+	}
+	return apiResponse{diffContentTypes[body.Format], out}, nil
+}
+
+// patchRequest asks for Document patched by Patch. As with -p, the
+// Options are those used to render the result.
+type patchRequest struct {
+	Document json.RawMessage `json:"document"`
+	Patch    json.RawMessage `json:"patch"`
+	Options  json.RawMessage `json:"options"`
+	Format   string          `json:"format"`
+}
+
+func apiPatch(ctx context.Context, req apiRequest) (apiResponse, error) {
+	var body patchRequest
+	if err := req.decode(&body); err != nil {
+		return apiResponse{}, err
+	}
+	if err := checkApiFormat(body.Format); err != nil {
+		return apiResponse{}, err
+	}
+	options, err := readApiOptions(body.Options)
+	if err != nil {
+		return apiResponse{}, err
+	}
+	diff, err := readApiDiff("patch", body.Patch, body.Format)
+	if err != nil {
+		return apiResponse{}, err
+	}
+	n, err := req.document("document", body.Document)
+	if err != nil {
+		return apiResponse{}, err
+	}
+	n, err = n.PatchContext(ctx, diff)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return apiResponse{}, err
+	}
+	if err != nil {
+		return apiResponse{}, newApiError(http.StatusUnprocessableEntity, "patch_failed", err)
+	}
+	if err := ctx.Err(); err != nil {
+		return apiResponse{}, err
+	}
+	return req.render(n, options...), nil
+}
+
+// translateRequest asks for Diff, written in format From, in format
+// To.
+type translateRequest struct {
+	Diff json.RawMessage `json:"diff"`
+	From string          `json:"from"`
+	To   string          `json:"to"`
+}
+
+func apiTranslate(ctx context.Context, req apiRequest) (apiResponse, error) {
+	var body translateRequest
+	if err := req.decode(&body); err != nil {
+		return apiResponse{}, err
+	}
+	for _, format := range []string{body.From, body.To} {
+		if err := checkApiFormat(format); err != nil {
+			return apiResponse{}, err
+		}
+	}
+	diff, err := readApiDiff("diff", body.Diff, body.From)
+	if err != nil {
+		return apiResponse{}, err
+	}
+	if err := ctx.Err(); err != nil {
+		return apiResponse{}, err
+	}
+	out, _, err := renderDiff(diff, body.To, nil)
+	if err != nil {
+		return apiResponse{}, newApiError(http.StatusUnprocessableEntity, "render_failed", err)
+	}
+	return apiResponse{diffContentTypes[body.To], out}, nil
+}
@@ -0,0 +1,429 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestApi(t *testing.T) {
+	cases := []struct {
+		name            string
+		method          string
+		path            string
+		contentType     string
+		body            string
+		wantStatus      int
+		wantContentType string
+		wantBody        string
+	}{{
+		name:            "diff",
+		path:            "/diff",
+		body:            `{"a":{"foo":"bar"},"b":{"foo":"baz"}}`,
+		wantStatus:      http.StatusOK,
+		wantContentType: "text/plain; charset=utf-8",
+		wantBody: s(
+			`@ ["foo"]`,
+			`- "bar"`,
+			`+ "baz"`,
+		),
+	}, {
+		name:            "diff with options",
+		path:            "/diff",
+		body:            `{"a":[1,2],"b":[2,1,3],"options":["SET"]}`,
+		wantStatus:      http.StatusOK,
+		wantContentType: "text/plain; charset=utf-8",
+		wantBody: s(
+			`^ "SET"`,
+			`@ [{}]`,
+			`+ 3`,
+		),
+	}, {
+		name:            "diff in patch format",
+		path:            "/diff",
+		body:            `{"a":{"foo":"bar"},"b":{"foo":"baz"},"format":"patch"}`,
+		wantStatus:      http.StatusOK,
+		wantContentType: "application/json-patch+json",
+		wantBody:        `[{"op":"test","path":"/foo","value":"bar"},{"op":"remove","path":"/foo","value":"bar"},{"op":"add","path":"/foo","value":"baz"}]`,
+	}, {
+		name:            "diff in merge format",
+		path:            "/diff",
+		body:            `{"a":{"foo":"bar","x":1},"b":{"foo":"baz"},"format":"merge"}`,
+		wantStatus:      http.StatusOK,
+		wantContentType: "application/merge-patch+json",
+		wantBody:        `{"foo":"baz","x":null}`,
+	}, {
+		name:            "diff yaml",
+		path:            "/diff",
+		contentType:     "application/yaml",
+		body:            "a:\n  foo: bar\nb:\n  foo: baz\noptions: [COERCE]\n",
+		wantStatus:      http.StatusOK,
+		wantContentType: "text/plain; charset=utf-8",
+		wantBody: s(
+			`^ "COERCE"`,
+			`@ ["foo"]`,
+			`- "bar"`,
+			`+ "baz"`,
+		),
+	}, {
+		name:            "patch",
+		path:            "/patch",
+		body:            `{"document":{"foo":"bar"},"patch":"@ [\"foo\"]\n- \"bar\"\n+ \"baz\"\n"}`,
+		wantStatus:      http.StatusOK,
+		wantContentType: "application/json",
+		wantBody:        `{"foo":"baz"}`,
+	}, {
+		name:            "patch with RFC 6902 value",
+		path:            "/patch",
+		body:            `{"document":{"foo":"bar"},"patch":[{"op":"replace","path":"/foo","value":"baz"}],"format":"patch"}`,
+		wantStatus:      http.StatusOK,
+		wantContentType: "application/json",
+		wantBody:        `{"foo":"baz"}`,
+	}, {
+		name:            "patch with options",
+		path:            "/patch",
+		body:            `{"document":[3,1,1],"patch":"","options":["SET"]}`,
+		wantStatus:      http.StatusOK,
+		wantContentType: "application/json",
+		wantBody:        `[3,1]`,
+	}, {
+		name:            "patch yaml",
+		path:            "/patch",
+		contentType:     "text/yaml; charset=utf-8",
+		body:            "document:\n  foo: bar\npatch: {foo: baz}\nformat: merge\n",
+		wantStatus:      http.StatusOK,
+		wantContentType: "application/yaml",
+		wantBody:        "foo: baz\n",
+	}, {
+		name:            "translate",
+		path:            "/translate",
+		body:            `{"diff":"@ [\"foo\"]\n- \"bar\"\n+ \"baz\"\n","to":"patch"}`,
+		wantStatus:      http.StatusOK,
+		wantContentType: "application/json-patch+json",
+		wantBody:        `[{"op":"test","path":"/foo","value":"bar"},{"op":"remove","path":"/foo","value":"bar"},{"op":"add","path":"/foo","value":"baz"}]`,
+	}, {
+		name:            "translate merge to jd",
+		path:            "/translate",
+		body:            `{"diff":{"foo":"baz"},"from":"merge"}`,
+		wantStatus:      http.StatusOK,
+		wantContentType: "text/plain; charset=utf-8",
+		wantBody: s(
+			`^ {"Merge":true}`,
+			`@ ["foo"]`,
+			`+ "baz"`,
+		),
+	}, {
+		name:            "unknown endpoint",
+		path:            "/merge3",
+		body:            `{}`,
+		wantStatus:      http.StatusNotFound,
+		wantContentType: "application/json",
+		wantBody:        `{"error":{"code":"not_found","message":"no endpoint /merge3"}}` + "\n",
+	}, {
+		name:            "wrong method",
+		method:          http.MethodGet,
+		path:            "/diff",
+		wantStatus:      http.StatusMethodNotAllowed,
+		wantContentType: "application/json",
+		wantBody:        `{"error":{"code":"method_not_allowed","message":"/diff requires POST. got GET"}}` + "\n",
+	}, {
+		name:            "too large",
+		path:            "/diff",
+		body:            `{"a":"` + strings.Repeat("x", 100) + `"}`,
+		wantStatus:      http.StatusRequestEntityTooLarge,
+		wantContentType: "application/json",
+		wantBody:        `{"error":{"code":"too_large","message":"request body exceeds 64 bytes"}}` + "\n",
+	}, {
+		name:            "unknown field",
+		path:            "/diff",
+		body:            `{"a":1,"c":2}`,
+		wantStatus:      http.StatusBadRequest,
+		wantContentType: "application/json",
+		wantBody:        `{"error":{"code":"invalid_request","message":"json: unknown field \"c\""}}` + "\n",
+	}, {
+		name:            "invalid yaml",
+		path:            "/patch",
+		contentType:     "application/x-yaml",
+		body:            "a: [",
+		wantStatus:      http.StatusBadRequest,
+		wantContentType: "application/json",
+		wantBody:        `{"error":{"code":"invalid_request","message":"yaml: line 1: did not find expected node content"}}` + "\n",
+	}, {
+		name:            "missing document",
+		path:            "/diff",
+		body:            `{"a":1}`,
+		wantStatus:      http.StatusBadRequest,
+		wantContentType: "application/json",
+		wantBody:        `{"error":{"code":"invalid_request","message":"missing \"b\""}}` + "\n",
+	}, {
+		name:            "missing a",
+		path:            "/diff",
+		body:            `{}`,
+		wantStatus:      http.StatusBadRequest,
+		wantContentType: "application/json",
+		wantBody:        `{"error":{"code":"invalid_request","message":"missing \"a\""}}` + "\n",
+	}, {
+		name:            "invalid options",
+		path:            "/diff",
+		body:            `{"a":1,"b":2,"options":[{"precision":-1}]}`,
+		wantStatus:      http.StatusBadRequest,
+		wantContentType: "application/json",
+		wantBody:        `{"error":{"code":"invalid_options","message":"precision must not be negative"}}` + "\n",
+	}, {
+		name:            "invalid patch options",
+		path:            "/patch",
+		body:            `{"document":1,"patch":"","options":[{"precision":-1}]}`,
+		wantStatus:      http.StatusBadRequest,
+		wantContentType: "application/json",
+		wantBody:        `{"error":{"code":"invalid_options","message":"precision must not be negative"}}` + "\n",
+	}, {
+		name:            "invalid format",
+		path:            "/diff",
+		body:            `{"a":1,"b":2,"format":"xml"}`,
+		wantStatus:      http.StatusBadRequest,
+		wantContentType: "application/json",
+		wantBody:        `{"error":{"code":"invalid_format","message":"invalid format: \"xml\""}}` + "\n",
+	}, {
+		name:            "unrenderable diff",
+		path:            "/diff",
+		body:            `{"a":[1],"b":[2],"options":["SET"],"format":"patch"}`,
+		wantStatus:      http.StatusUnprocessableEntity,
+		wantContentType: "application/json",
+		wantBody:        `{"error":{"code":"render_failed","message":"JSON Pointer does not support set-based paths. Use jd format instead of patch"}}` + "\n",
+	}, {
+		name:            "patch bad request",
+		path:            "/patch",
+		body:            `[]`,
+		wantStatus:      http.StatusBadRequest,
+		wantContentType: "application/json",
+		wantBody:        `{"error":{"code":"invalid_request","message":"json: cannot unmarshal array into Go value of type main.patchRequest"}}` + "\n",
+	}, {
+		name:            "patch invalid format",
+		path:            "/patch",
+		body:            `{"format":"xml"}`,
+		wantStatus:      http.StatusBadRequest,
+		wantContentType: "application/json",
+		wantBody:        `{"error":{"code":"invalid_format","message":"invalid format: \"xml\""}}` + "\n",
+	}, {
+		name:            "missing patch",
+		path:            "/patch",
+		body:            `{"document":1}`,
+		wantStatus:      http.StatusBadRequest,
+		wantContentType: "application/json",
+		wantBody:        `{"error":{"code":"invalid_request","message":"missing \"patch\""}}` + "\n",
+	}, {
+		name:            "invalid patch",
+		path:            "/patch",
+		body:            `{"document":1,"patch":"@ ["}`,
+		wantStatus:      http.StatusBadRequest,
+		wantContentType: "application/json",
+		wantBody:        `{"error":{"code":"invalid_diff","message":"invalid diff at line 1. Invalid path. unexpected EOF","type":"parse_error","line":1,"col":4}}` + "\n",
+	}, {
+		name:            "patch missing document",
+		path:            "/patch",
+		body:            `{"patch":""}`,
+		wantStatus:      http.StatusBadRequest,
+		wantContentType: "application/json",
+		wantBody:        `{"error":{"code":"invalid_request","message":"missing \"document\""}}` + "\n",
+	}, {
+		name:            "stale patch",
+		path:            "/patch",
+		body:            `{"document":{"foo":"qux"},"patch":"@ [\"foo\"]\n- \"bar\"\n+ \"baz\"\n"}`,
+		wantStatus:      http.StatusUnprocessableEntity,
+		wantContentType: "application/json",
+		wantBody:        `{"error":{"code":"patch_failed","message":"found \"qux\" at [foo]: expected \"bar\"","type":"patch_conflict","path":["foo"],"expected":"bar","actual":"qux"}}` + "\n",
+	}, {
+		name:            "translate bad request",
+		path:            "/translate",
+		body:            `{"from":`,
+		wantStatus:      http.StatusBadRequest,
+		wantContentType: "application/json",
+		wantBody:        `{"error":{"code":"invalid_request","message":"unexpected EOF"}}` + "\n",
+	}, {
+		name:            "translate invalid format",
+		path:            "/translate",
+		body:            `{"to":"yaml"}`,
+		wantStatus:      http.StatusBadRequest,
+		wantContentType: "application/json",
+		wantBody:        `{"error":{"code":"invalid_format","message":"invalid format: \"yaml\""}}` + "\n",
+	}, {
+		name:            "translate missing diff",
+		path:            "/translate",
+		body:            `{}`,
+		wantStatus:      http.StatusBadRequest,
+		wantContentType: "application/json",
+		wantBody:        `{"error":{"code":"invalid_request","message":"missing \"diff\""}}` + "\n",
+	}, {
+		name:            "untranslatable diff",
+		path:            "/translate",
+		body:            `{"diff":"@ [\"foo\"]\n- 1\n","to":"merge"}`,
+		wantStatus:      http.StatusUnprocessableEntity,
+		wantContentType: "application/json",
+		wantBody:        `{"error":{"code":"render_failed","message":"cannot render non-merge element as merge"}}` + "\n",
+	}}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			maxBytes := int64(1 << 20)
+			if c.name == "too large" {
+				maxBytes = 64
+			}
+			server := newApiServer(maxBytes, time.Minute)
+			method := c.method
+			if method == "" {
+				method = http.MethodPost
+			}
+			req := httptest.NewRequest(method, c.path, strings.NewReader(c.body))
+			if c.contentType != "" {
+				req.Header.Set("Content-Type", c.contentType)
+			}
+			rec := httptest.NewRecorder()
+			server.ServeHTTP(rec, req)
+			if rec.Code != c.wantStatus {
+				t.Errorf("wanted status %v. got %v", c.wantStatus, rec.Code)
+			}
+			if got := rec.Header().Get("Content-Type"); got != c.wantContentType {
+				t.Errorf("wanted content type %q. got %q", c.wantContentType, got)
+			}
+			if got := rec.Body.String(); got != c.wantBody {
+				t.Errorf("wanted body %q. got %q", c.wantBody, got)
+			}
+		})
+	}
+}
+
+func TestApiError(t *testing.T) {
+	var err error = newApiError(http.StatusBadRequest, "invalid_request", errors.New("missing \"a\""))
+	if got := err.Error(); got != `missing "a"` {
+		t.Errorf("wanted error message %q. got %q", `missing "a"`, got)
+	}
+}
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) {
+	return 0, errors.New("connection reset")
+}
+
+func TestApiFailures(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+	server := newApiServer(1<<20, 10*time.Millisecond)
+	server.endpoints["/slow"] = func(context.Context, apiRequest) (apiResponse, error) {
+		<-block
+		return apiResponse{}, nil
+	}
+	server.endpoints["/broken"] = func(context.Context, apiRequest) (apiResponse, error) {
+		return apiResponse{}, errors.New("broken")
+	}
+	server.endpoints["/expired"] = func(context.Context, apiRequest) (apiResponse, error) {
+		return apiResponse{}, context.DeadlineExceeded
+	}
+	server.endpoints["/panic"] = func(context.Context, apiRequest) (apiResponse, error) {
+		panic("boom")
+	}
+	cases := []struct {
+		name       string
+		req        *http.Request
+		wantStatus int
+		wantBody   string
+	}{{
+		name:       "timeout",
+		req:        httptest.NewRequest(http.MethodPost, "/slow", strings.NewReader(`{}`)),
+		wantStatus: http.StatusServiceUnavailable,
+		wantBody:   `{"error":{"code":"timeout","message":"request took longer than 10ms"}}` + "\n",
+	}, {
+		name:       "given up",
+		req:        httptest.NewRequest(http.MethodPost, "/expired", strings.NewReader(`{}`)),
+		wantStatus: http.StatusServiceUnavailable,
+		wantBody:   `{"error":{"code":"timeout","message":"request took longer than 10ms"}}` + "\n",
+	}, {
+		name:       "internal error",
+		req:        httptest.NewRequest(http.MethodPost, "/broken", strings.NewReader(`{}`)),
+		wantStatus: http.StatusInternalServerError,
+		wantBody:   `{"error":{"code":"internal","message":"broken"}}` + "\n",
+	}, {
+		name:       "panic",
+		req:        httptest.NewRequest(http.MethodPost, "/panic", strings.NewReader(`{}`)),
+		wantStatus: http.StatusInternalServerError,
+		wantBody:   `{"error":{"code":"internal","message":"boom"}}` + "\n",
+	}, {
+		name:       "unreadable body",
+		req:        httptest.NewRequest(http.MethodPost, "/diff", failingReader{}),
+		wantStatus: http.StatusBadRequest,
+		wantBody:   `{"error":{"code":"invalid_request","message":"connection reset"}}` + "\n",
+	}}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			server.ServeHTTP(rec, c.req)
+			if rec.Code != c.wantStatus {
+				t.Errorf("wanted status %v. got %v", c.wantStatus, rec.Code)
+			}
+			if got := rec.Body.String(); got != c.wantBody {
+				t.Errorf("wanted body %q. got %q", c.wantBody, got)
+			}
+		})
+	}
+}
+
+// expireAfter is a context which expires once its Err has been checked
+// n times.
+type expireAfter struct {
+	context.Context
+	n int
+}
+
+func (c *expireAfter) Err() error {
+	c.n--
+	if c.n < 0 {
+		return context.DeadlineExceeded
+	}
+	return nil
+}
+
+func TestApiExpired(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 0)
+	defer cancel()
+	diff := apiRequest{body: []byte(`{"a":[1,2],"b":[2,3]}`)}
+	if _, err := apiDiff(ctx, diff); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("wanted diff to give up. got %v", err)
+	}
+	patch := apiRequest{body: []byte(`{"document":[1],"patch":"@ [0]\n- 1\n"}`)}
+	if _, err := apiPatch(ctx, patch); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("wanted patch to give up. got %v", err)
+	}
+	// Expire after the diff or patch, before the result is rendered.
+	cases := []struct {
+		name     string
+		endpoint apiEndpoint
+		body     string
+		checks   int
+	}{{
+		name:     "diff",
+		endpoint: apiDiff,
+		body:     `{"a":[1],"b":[2]}`,
+		checks:   1,
+	}, {
+		name:     "patch",
+		endpoint: apiPatch,
+		body:     `{"document":[1],"patch":"@ [0]\n- 1\n"}`,
+		checks:   1,
+	}, {
+		name:     "translate",
+		endpoint: apiTranslate,
+		body:     `{"diff":"@ [0]\n- 1\n","to":"patch"}`,
+	}}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ctx := &expireAfter{context.Background(), c.checks}
+			_, err := c.endpoint(ctx, apiRequest{body: []byte(c.body)})
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("wanted %v to give up. got %v", c.name, err)
+			}
+		})
+	}
+}
@@ -2,17 +2,21 @@ package main
 
 import (
 	"bufio"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"io"
+	"io/fs"
 	"log"
 	"math/rand"
 	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/josephburnett/jd/v2"
 	"github.com/josephburnett/jd/v2/internal/web/serve"
@@ -21,26 +25,67 @@ import (
 const version = "HEAD"
 
 var (
-	color         = flag.Bool("color", false, "Print color diff")
-	colorWords    = flag.Bool("color-words", false, "Print color diff with character-level highlighting")
-	format        = flag.String("f", "", "Diff format (jd, patch, merge)")
-	gitDiffDriver = flag.Bool("git-diff-driver", false, "Use jd as a git diff driver.")
-	mset          = flag.Bool("mset", false, "Arrays as multisets")
-	opts          = flag.String("opts", "[]", "JSON array of options")
-	output        = flag.String("o", "", "Output file")
-	patch         = flag.Bool("p", false, "Patch mode")
-	port          = flag.Int("port", 0, "Serve web UI on port")
-	precision     = flag.Float64("precision", 0, "Maximum absolute difference for numbers to be equal")
-	set           = flag.Bool("set", false, "Arrays as sets")
-	setkeys       = flag.String("setkeys", "", "Keys to identify set objects")
-	translate     = flag.String("t", "", "Translate mode")
-	ver           = flag.Bool("version", false, "Print version and exit")
-	yaml          = flag.Bool("yaml", false, "Read and write YAML")
+	apiMaxBody     = flag.Int64("api-max-body", 10<<20, "Maximum request body in bytes of -serve-api")
+	apiTimeout     = flag.Duration("api-timeout", 30*time.Second, "Maximum time to answer a request of -serve-api")
+	autoKeys       = flag.Bool("autokeys", false, "Infer keys to identify objects in arrays")
+	coerce         = flag.Bool("coerce", false, "Compare strings holding numbers, booleans and null as typed values")
+	color          = flag.Bool("color", false, "Print color diff")
+	colorWords     = flag.Bool("color-words", false, "Print color diff with character-level highlighting")
+	docs           = flag.Bool("docs", false, "Read and write YAML streams of documents paired by identity")
+	docsIdentity   = flag.String("docs-identity", "", "JSON array of paths identifying YAML documents")
+	format         = flag.String("f", "", "Diff format (jd, patch, merge, json-hunks)")
+	fuzz           = flag.Int("fuzz", -1, "Patch a drifted document, searching N places either way for each list hunk")
+	gitDiffDriver  = flag.Bool("git-diff-driver", false, "Use jd as a git diff driver.")
+	gitMergeDriver = flag.Bool("git-merge-driver", false, "Use jd as a git merge driver.")
+	jsonErrors     = flag.Bool("json-errors", false, "Print errors as JSON")
+	keepFormat     = flag.Bool("keep-format", false, "Patch the source text keeping key order, formatting and YAML comments")
+	merge3         = flag.Bool("merge3", false, "Three-way merge mode")
+	moves          = flag.Bool("moves", false, "Detect moved and copied objects and arrays")
+	mset           = flag.Bool("mset", false, "Arrays as multisets")
+	opts           = flag.String("opts", "[]", "JSON array of options")
+	output         = flag.String("o", "", "Output file")
+	patch          = flag.Bool("p", false, "Patch mode")
+	port           = flag.Int("port", 0, "Serve web UI on port")
+	recursive      = flag.Bool("r", false, "Recursively diff directories")
+	serveApiFlag   = flag.Bool("serve-api", false, "Serve the diff, patch and translate HTTP API on -port")
+	precision      = flag.Float64("precision", 0, "Maximum absolute difference for numbers to be equal")
+	rej            = flag.String("rej", "", "File for the hunks rejected by -fuzz")
+	reverse        = flag.Bool("R", false, "Reverse the patch")
+	set            = flag.Bool("set", false, "Arrays as sets")
+	similarity     = flag.Float64("similarity", 0, "Minimum fraction of equal leaves to diff array elements in place")
+	setkeys        = flag.String("setkeys", "", "Keys to identify set objects")
+	stat           = flag.Bool("stat", false, "Print diff statistics")
+	stream         = flag.Bool("stream", false, "Diff large JSON files incrementally")
+	translate      = flag.String("t", "", "Translate mode")
+	ver            = flag.Bool("version", false, "Print version and exit")
+	yaml           = flag.Bool("yaml", false, "Read and write YAML")
 
 	// This is here so that existing user commands that provide -v2 don't fail.
 	_ = flag.Bool("v2", true, "Use the jd v2 library (deprecated, has no effect)")
+
+	include, exclude pathPatterns
+
+	// identity is parsed from -docs-identity.
+	identity []jd.Path
 )
 
+func init() {
+	flag.Var(&include, "include", "Only diff paths matching PATTERN (repeatable)")
+	flag.Var(&exclude, "exclude", "Do not diff paths matching PATTERN (repeatable)")
+}
+
+// pathPatterns collect the values of a repeated flag.
+type pathPatterns []string
+
+func (p *pathPatterns) String() string {
+	return strings.Join(*p, " ")
+}
+
+func (p *pathPatterns) Set(s string) error {
+	*p = append(*p, s)
+	return nil
+}
+
 func main() {
 	if filepath.Base(os.Args[0]) == "jd-github-action" {
 		fmt.Println("Running as GitHub Action...")
@@ -52,6 +97,16 @@ func main() {
 		fmt.Printf("jd version %v\n", version)
 		return
 	}
+	if *serveApiFlag {
+		if *port == 0 || len(flag.Args()) > 0 {
+			errorfAndExit("The HTTP API (-serve-api) requires -port and does not support arguments")
+		}
+		err := serveApi(strconv.Itoa(*port))
+		if err != nil {
+			errorAndExit(err)
+		}
+		return
+	}
 	if *port != 0 {
 		if len(flag.Args()) > 0 {
 			errorfAndExit("The web UI (-port) does not support arguments")
@@ -70,6 +125,10 @@ func main() {
 	if err != nil {
 		errorAndExit(err)
 	}
+	identity, err = parseIdentity()
+	if err != nil {
+		errorAndExit(err)
+	}
 	if *gitDiffDriver {
 		err := printGitDiffDriver(options)
 		if err != nil {
@@ -78,6 +137,13 @@ func main() {
 		os.Exit(0)
 		return
 	}
+	if *gitMergeDriver {
+		err := printGitMergeDriver(options)
+		if err != nil {
+			errorAndExit(err)
+		}
+		return
+	}
 	mode := diffMode
 	if *patch {
 		mode = patchMode
@@ -85,10 +151,45 @@ func main() {
 	if *translate != "" {
 		mode = translateMode
 	}
+	if *merge3 {
+		mode = merge3Mode
+	}
 	if *patch && *translate != "" {
 		errorfAndExit("Patch and translate modes cannot be used together.")
 	}
-	var a, b string
+	if *merge3 && (*patch || *translate != "") {
+		errorfAndExit("Merge3 mode cannot be used with patch or translate modes.")
+	}
+	if *stat && (mode != diffMode || *stream || *recursive) {
+		errorfAndExit("Stat mode can only be used to diff two files in memory.")
+	}
+	if *docs && (mode == translateMode || mode == merge3Mode || *stream) {
+		errorfAndExit("Document streams (-docs) can only be used to diff and patch in memory.")
+	}
+	if *keepFormat && (mode != patchMode || *docs) {
+		errorfAndExit("Keeping the format (-keep-format) can only be used to patch a single document.")
+	}
+	if *fuzz >= 0 && (mode != patchMode || *keepFormat) {
+		errorfAndExit("Fuzzy patching (-fuzz) can only be used in patch mode without -keep-format.")
+	}
+	if *reverse && mode != patchMode {
+		errorfAndExit("Reversing (-R) can only be used in patch mode.")
+	}
+	if *recursive {
+		if mode != diffMode || *stream {
+			errorfAndExit("Recursive mode can only be used to diff in memory.")
+		}
+		printDirDiff(options)
+		return
+	}
+	if *stream {
+		if mode != diffMode {
+			errorfAndExit("Stream mode can only be used to diff.")
+		}
+		printStreamDiff(options)
+		return
+	}
+	var a, b, c string
 	switch mode {
 	case diffMode, patchMode:
 		switch len(flag.Args()) {
@@ -110,6 +211,13 @@ func main() {
 		default:
 			printUsageAndExit()
 		}
+	case merge3Mode:
+		if len(flag.Args()) != 3 {
+			printUsageAndExit()
+		}
+		a = readFile(flag.Arg(0))
+		b = readFile(flag.Arg(1))
+		c = readFile(flag.Arg(2))
 	}
 	switch mode {
 	case diffMode:
@@ -118,6 +226,8 @@ func main() {
 		printPatch(a, b, options)
 	case translateMode:
 		printTranslation(a)
+	case merge3Mode:
+		printMerge3(a, b, c, options)
 	}
 }
 
@@ -127,6 +237,7 @@ const (
 	diffMode      mode = "diff"
 	patchMode     mode = "patch"
 	translateMode mode = "trans"
+	merge3Mode    mode = "merge3"
 )
 
 func serveWeb(port string) error {
@@ -138,6 +249,16 @@ func serveWeb(port string) error {
 	return http.ListenAndServe(":"+port, nil)
 }
 
+func serveApi(port string) error {
+	server := &http.Server{
+		Addr:              ":" + port,
+		Handler:           newApiServer(*apiMaxBody, *apiTimeout),
+		ReadHeaderTimeout: *apiTimeout,
+	}
+	log.Printf("Serving the API on http://localhost:%v...", port)
+	return server.ListenAndServe()
+}
+
 func parseOptions() ([]jd.Option, error) {
 	options, err := jd.ReadOptionsString(*opts)
 	if err != nil {
@@ -167,16 +288,78 @@ func parseOptions() ([]jd.Option, error) {
 	if *precision != 0.0 {
 		options = append(options, jd.Precision(*precision))
 	}
+	if *moves {
+		options = append(options, jd.DETECT_MOVES)
+	}
+	if *similarity != 0.0 {
+		options = append(options, jd.Similarity(*similarity))
+	}
+	if *autoKeys {
+		options = append(options, jd.AUTO_KEYS)
+	}
+	if *coerce {
+		options = append(options, jd.COERCE)
+	}
+	// Later options win so exclusions apply inside inclusions.
+	if len(include) > 0 {
+		options = append(options, jd.PathOption(jd.Path{}, jd.DIFF_OFF))
+	}
+	for _, pattern := range include {
+		p, err := readPathPattern(pattern)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, jd.PathOption(p, jd.DIFF_ON))
+	}
+	for _, pattern := range exclude {
+		p, err := readPathPattern(pattern)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, jd.PathOption(p, jd.DIFF_OFF))
+	}
 	if err := jd.ValidateOptions(options); err != nil {
 		return nil, err
 	}
 	return options, nil
 }
 
+// readPathPattern reads a path written as a JSON array in which a bare
+// * (outside of strings) matches any object key or list index.
+func readPathPattern(pattern string) (jd.Path, error) {
+	b := &strings.Builder{}
+	inString, escaped := false, false
+	for _, r := range pattern {
+		switch {
+		case escaped:
+			escaped = false
+		case inString && r == '\\':
+			escaped = true
+		case r == '"':
+			inString = !inString
+		case !inString && r == '*':
+			b.WriteString("null")
+			continue
+		}
+		b.WriteRune(r)
+	}
+	n, err := jd.ReadJsonString(b.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid path pattern %q: %v", pattern, err)
+	}
+	p, err := jd.NewPath(n)
+	if err != nil {
+		return nil, fmt.Errorf("invalid path pattern %q: %v", pattern, err)
+	}
+	return p, nil
+}
+
 func printUsageAndExit() {
 	for _, line := range []string{
 		``,
 		`Usage: jd [OPTION]... FILE1 [FILE2]`,
+		`       jd -r [OPTION]... DIR1 DIR2`,
+		`       jd -merge3 [OPTION]... BASE OURS THEIRS`,
 		`Diff and patch JSON files.`,
 		``,
 		`Prints the diff of FILE1 and FILE2 to STDOUT.`,
@@ -194,22 +377,91 @@ func printUsageAndExit() {
 		`  -color       Print color diff.`,
 		`  -color-words Print color diff with character-level highlighting.`,
 		`  -p           Apply patch FILE1 to FILE2 or STDIN.`,
+		`  -R           In patch mode, undo patch FILE1, taking the patched FILE2`,
+		`               back to the original. Merge patches cannot be reversed.`,
+		`  -r           Recursively diff the .json, .yaml and .yml files in DIR1 and`,
+		`               DIR2, paired by relative path and read by their extension.`,
+		`               Each file's hunks follow a ^ {"file":PATH} header. An added or`,
+		`               removed file is a hunk at [] adding or removing the document.`,
+		`  -merge3      Three-way merge FILE2 and FILE3 with common ancestor FILE1.`,
+		`               Prints the merged value. Conflicting changes keep FILE2 and`,
+		`               are printed to STDERR as a diff which would take FILE3.`,
+		`  -git-diff-driver  Run as a git diff driver (see README).`,
+		`  -git-merge-driver Run as a git merge driver with arguments %O %A %B %L %P.`,
+		`               Writes the merged value to %A. Conflicts are written to`,
+		`               %P.jd-conflicts and exit with status 1.`,
 		`  -o=FILE3     Write to FILE3 instead of STDOUT.`,
+		`  -json-errors Print errors to STDERR as a JSON object like the errors of`,
+		`               -serve-api. Errors reading a diff, conflicting with the`,
+		`               patched value or finding no value have a "type" of`,
+		`               "parse_error", "patch_conflict" or "path_not_found" and`,
+		`               fields locating them.`,
 		`  -opts='[]'   JSON array of options. Supports global options and PathOptions.`,
-		`               Global: ["SET"], ["MULTISET"], [{"precision":0.1}], [{"keys":["id"]}], ["DIFF_ON"], ["DIFF_OFF"]`,
+		`               Global: ["SET"], ["MULTISET"], [{"precision":0.1}], [{"relative":0.01}], [{"ulp":4}], [{"keys":["id"]}], ["DIFF_ON"], ["DIFF_OFF"], ["DETECT_MOVES"], ["AUTO_KEYS"], [{"similarity":0.5}], ["COERCE"]`,
+		`               Strings: ["IGNORE_CASE"], ["TRIM_SPACE"], ["COLLAPSE_SPACE"], ["NFC"], ["NFKC"], [{"pattern":"^\\d+$"}], ["EMBEDDED"]`,
 		`               PathOptions target specific paths: [{"@":["path"],"^":["SET"]}]`,
+		`               In PathOption paths null matches any key or index and [null]`,
+		`               matches any key.`,
 		`               Example: [{"@":["users"],"^":["SET"]},{"@":["scores",0],"^":[{"precision":0.1}]}]`,
+		`  -include=PATTERN Only diff values matching PATTERN, a JSON array path in`,
+		`               which * matches any key or index. May be repeated.`,
+		`               Example: -include='["spec",*,"image"]'`,
+		`  -exclude=PATTERN Do not diff values matching PATTERN. May be repeated and`,
+		`               applies inside -include.`,
+		`               Example: -exclude='["items",*,"metadata","generation"]'`,
 		`  -set         Treat arrays as sets. Same as -opts='["SET"]'.`,
 		`  -mset        Treat arrays as multisets (bags). Same as -opts='["MULTISET"]'.`,
 		`  -setkeys     Keys to identify set objects. Same as -opts='[{"keys":["key1","key2"]}]'.`,
+		`  -autokeys    Compare arrays of objects as sets keyed by a field like "id"`,
+		`               or "name" found in every element. The chosen keys are`,
+		`               printed as PathOptions. Same as -opts='["AUTO_KEYS"]'.`,
+		`  -coerce      Compare strings holding a number, boolean or null as the typed`,
+		`               value, so "1" equals 1. Same as -opts='["COERCE"]'.`,
+		`  -moves       Detect objects and arrays moved or copied between object keys.`,
+		`               Same as -opts='["DETECT_MOVES"]'.`,
+		`  -stat        Print the number of values added, removed and changed under`,
+		`               each top-level key instead of the diff. With -f json prints`,
+		`               the counts for every path prefix as JSON.`,
+		`  -stream      Diff JSON files incrementally without reading them into memory.`,
+		`               Hunks are written as they are found. Only the jd format is`,
+		`               supported and moves are not detected.`,
 		`  -yaml        Read and write YAML instead of JSON.`,
+		`  -fuzz=N      In patch mode, apply FILE1 to a FILE2 which has drifted since`,
+		`               the diff was made. A hunk which does not match is tried with`,
+		`               the last list index of its path moved up to N places either`,
+		`               way. Hunks which still do not match are rejected, written to`,
+		`               -rej and reported to STDERR, and the rest are applied. Exits`,
+		`               with status 1 if any hunk was rejected.`,
+		`  -rej=FILE    Where -fuzz writes rejected hunks as a jd diff. Defaults to`,
+		`               FILE2.rej, or FILE1.rej when patching STDIN.`,
+		`  -keep-format In patch mode, edit the text of FILE2 instead of rewriting it.`,
+		`               Key order, whitespace, number formatting and YAML comments`,
+		`               of untouched values are kept.`,
+		`  -docs        Read and write streams of YAML documents separated by ---.`,
+		`               Documents are paired by identity rather than position and`,
+		`               diffed under a key like ["apps/v1","Deployment","prod","web"].`,
+		`  -docs-identity='[["kind"],["metadata","name"]]'`,
+		`               JSON array of paths identifying documents. The default is`,
+		`               apiVersion, kind, metadata.namespace and metadata.name.`,
 		`  -port=N      Serve web UI on port N`,
+		`  -serve-api   Serve an HTTP API on -port instead of the web UI. POST a JSON`,
+		`               or YAML object to /diff {"a","b","options","format"}, /patch`,
+		`               {"document","patch","options","format"} or /translate`,
+		`               {"diff","from","to"}. Formats are those of -f. Errors are JSON`,
+		`               objects.`,
+		`  -api-max-body=N Maximum request body of -serve-api in bytes (default 10 MiB).`,
+		`  -api-timeout=D  Maximum time to answer a request of -serve-api (default 30s).`,
 		`  -precision=N Maximum absolute difference for numbers to be equal.`,
 		`               Same as -opts='[{"precision":N}]'. Example: -precision=0.00001`,
-		`  -f=FORMAT    Read and write diff in FORMAT "jd" (default), "patch" (RFC 6902) or`,
-		`               "merge" (RFC 7386)`,
+		`  -similarity=N Diff objects and arrays in a list in place when they have`,
+		`               at least N (0 to 1) of their values in common, instead of`,
+		`               removing one and adding the other.`,
+		`               Same as -opts='[{"similarity":N}]'. Example: -similarity=0.5`,
+		`  -f=FORMAT    Read and write diff in FORMAT "jd" (default), "patch" (RFC 6902),`,
+		`               "merge" (RFC 7386) or "json-hunks" (every hunk field as JSON)`,
 		`  -t=FORMATS   Translate FILE1 between FORMATS. Supported formats are "jd",`,
-		`               "patch" (RFC 6902), "merge" (RFC 7386), "json" and "yaml".`,
+		`               "patch" (RFC 6902), "merge" (RFC 7386), "json-hunks", "json"`,
+		`               and "yaml".`,
 		`               FORMATS are provided as a pair separated by "2". E.g.`,
 		`               "yaml2json" or "jd2patch".`,
 		``,
@@ -220,9 +472,21 @@ func printUsageAndExit() {
 		`  jd -set a.json b.json`,
 		`  jd -f patch a.json b.json`,
 		`  jd -f merge a.json b.json`,
+		`  jd -f json-hunks a.json b.json`,
+		`  jd -moves -f patch a.json b.json`,
+		`  jd -autokeys -yaml old-deployment.yaml new-deployment.yaml`,
+		`  jd -p -keep-format patch config.json`,
+		`  jd -p -fuzz=3 patch config.json`,
+		`  jd -p -R patch config.json`,
+		`  jd -r manifests-a manifests-b`,
+		`  jd -docs old-render.yaml new-render.yaml`,
+		`  jd -stat a.json b.json`,
+		`  jd -stream big-a.json big-b.json`,
+		`  jd -merge3 base.json ours.json theirs.json`,
 		`  jd -opts='[{"@":["items"],"^":["SET"]}]' a.json b.json`,
 		`  jd -opts='[{"@":["temperature"],"^":[{"precision":0.1}]}]' a.json b.json`,
 		`  jd -opts='[{"@":["timestamp"],"^":["DIFF_OFF"]}]' a.json b.json`,
+		`  jd -exclude='["items",*,"metadata","generation"]' a.json b.json`,
 		`  jd -opts='[{"@":[],"^":["DIFF_OFF"]},{"@":["userdata"],"^":["DIFF_ON"]}]' a.json b.json`,
 		``,
 		`Version: ` + version,
@@ -250,6 +514,137 @@ func printDiff(a, b string, options []jd.Option) {
 	os.Exit(0)
 }
 
+func printStreamDiff(options []jd.Option) {
+	if *yaml || *docs {
+		errorfAndExit("Stream mode only supports JSON.")
+	}
+	if *format != "" && *format != "jd" {
+		errorfAndExit("Stream mode only supports the jd format.")
+	}
+	var a, b io.Reader
+	switch len(flag.Args()) {
+	case 1:
+		a = openFile(flag.Arg(0))
+		b = bufio.NewReader(os.Stdin)
+	case 2:
+		a = openFile(flag.Arg(0))
+		b = openFile(flag.Arg(1))
+	default:
+		printUsageAndExit()
+	}
+	options = append([]jd.Option{jd.File(flag.Arg(0))}, options...)
+	if *colorWords {
+		options = append(options, jd.COLOR_WORDS)
+	} else if *color {
+		options = append(options, jd.COLOR)
+	}
+	out := os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			errorAndExit(err)
+		}
+		out = f
+	}
+	w := bufio.NewWriter(out)
+	haveDiff, err := jd.DiffStream(a, b, w, options...)
+	if flushErr := w.Flush(); err == nil {
+		err = flushErr
+	}
+	if err != nil {
+		errorAndExit(err)
+	}
+	if haveDiff {
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+func printDirDiff(options []jd.Option) {
+	if len(flag.Args()) != 2 {
+		printUsageAndExit()
+	}
+	if *format != "" && *format != "jd" {
+		errorfAndExit("Recursive mode only supports the jd format.")
+	}
+	dirA, dirB := flag.Arg(0), flag.Arg(1)
+	filesA, err := listFiles(dirA)
+	if err != nil {
+		errorAndExit(err)
+	}
+	filesB, err := listFiles(dirB)
+	if err != nil {
+		errorAndExit(err)
+	}
+	names := []string{}
+	for name := range filesA {
+		names = append(names, name)
+	}
+	for name := range filesB {
+		if !filesA[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	out := &strings.Builder{}
+	haveDiff := false
+	for _, name := range names {
+		// A file only on one side is diffed against void, so it
+		// reads back as a hunk adding or removing the whole document.
+		a, b := "", ""
+		if filesA[name] {
+			a = readFile(filepath.Join(dirA, name))
+		}
+		if filesB[name] {
+			b = readFile(filepath.Join(dirB, name))
+		}
+		str, fileHaveDiff, err := diffInputs(a, b, readFileInput(name),
+			append([]jd.Option{jd.File(name)}, options...))
+		if err != nil {
+			errorfAndExit("%v: %v", name, err)
+		}
+		out.WriteString(str)
+		haveDiff = haveDiff || fileHaveDiff
+	}
+	if *output == "" {
+		fmt.Print(out.String())
+	} else {
+		os.WriteFile(*output, []byte(out.String()), 0644)
+	}
+	if haveDiff {
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+// listFiles returns the slash separated paths of the regular JSON and
+// YAML files below dir, relative to dir.
+func listFiles(dir string) (map[string]bool, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%v is not a directory", dir)
+	}
+	files := map[string]bool{}
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() || !isDataFile(path) {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = true
+		return nil
+	})
+	return files, err
+}
+
 func printGitDiffDriver(options []jd.Option) error {
 	if len(flag.Args()) != 7 {
 		return fmt.Errorf("Git diff driver expects exactly 7 arguments.")
@@ -266,25 +661,107 @@ func printGitDiffDriver(options []jd.Option) error {
 	return nil
 }
 
-func diff(a, b string, options []jd.Option) (string, bool, error) {
-	var aNode, bNode jd.JsonNode
+// printGitMergeDriver merges %A with %B using the common ancestor %O
+// and writes the result back to %A. Unresolved conflicts are written
+// to a sidecar jd diff next to %P which would take their side when
+// applied to the merged file. The marker size %L is ignored because
+// the merged file never contains conflict markers.
+func printGitMergeDriver(options []jd.Option) error {
+	if len(flag.Args()) != 5 {
+		return fmt.Errorf("Git merge driver expects exactly 5 arguments.")
+	}
+	ancestor, current, other, pathname := flag.Arg(0), flag.Arg(1), flag.Arg(2), flag.Arg(4)
+	isYaml := *yaml
+	switch strings.ToLower(filepath.Ext(pathname)) {
+	case ".yaml", ".yml":
+		isYaml = true
+	}
+	var texts [3]string
+	var nodes [3]jd.JsonNode
+	for i, filename := range []string{ancestor, current, other} {
+		texts[i] = readFile(filename)
+		var err error
+		if isYaml {
+			nodes[i], err = jd.ReadYamlString(texts[i])
+		} else {
+			nodes[i], err = jd.ReadJsonString(texts[i])
+		}
+		if err != nil {
+			return err
+		}
+	}
+	merged, conflicts := jd.Merge3(nodes[0], nodes[1], nodes[2], options...)
+	// The merged changes are patched into the text of the current
+	// version, keeping its formatting.
+	changes := nodes[1].Diff(merged)
+	var out string
 	var err error
-	if *yaml {
-		aNode, err = jd.ReadYamlString(a)
+	if isYaml {
+		out, err = jd.PatchYamlText(texts[1], changes)
 	} else {
-		aNode, err = jd.ReadJsonString(a)
+		out, err = jd.PatchJsonText(texts[1], changes)
 	}
 	if err != nil {
-		return "", false, err
+		return err
 	}
-	if *yaml {
-		bNode, err = jd.ReadYamlString(b)
-	} else {
-		bNode, err = jd.ReadJsonString(b)
+	err = os.WriteFile(current, []byte(out), 0644)
+	if err != nil {
+		return err
 	}
+	if len(conflicts) > 0 {
+		sidecar := pathname + ".jd-conflicts"
+		str := conflicts.Diff().Render()
+		err := os.WriteFile(sidecar, []byte(str), 0644)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "CONFLICT (content): Merge conflict in %v. See %v.\n", pathname, sidecar)
+		fmt.Fprint(os.Stderr, str)
+		os.Exit(1)
+	}
+	os.Exit(0)
+	return nil
+}
+
+// isDataFile reports whether name has the extension of a JSON or YAML
+// file.
+func isDataFile(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".json", ".yaml", ".yml":
+		return true
+	}
+	return false
+}
+
+// readFileInput returns the reader of the file name in recursive
+// mode, chosen by its extension rather than -yaml.
+func readFileInput(name string) func(string) (jd.JsonNode, error) {
+	switch {
+	case strings.ToLower(filepath.Ext(name)) == ".json":
+		return jd.ReadJsonString
+	case *docs:
+		return func(s string) (jd.JsonNode, error) {
+			return jd.ReadYamlDocumentsString(s, identity...)
+		}
+	}
+	return jd.ReadYamlString
+}
+
+func diff(a, b string, options []jd.Option) (string, bool, error) {
+	return diffInputs(a, b, readInput, options)
+}
+
+// diffInputs diffs a and b, each read by read.
+func diffInputs(a, b string, read func(string) (jd.JsonNode, error), options []jd.Option) (string, bool, error) {
+	aNode, err := read(a)
 	if err != nil {
 		return "", false, err
 	}
+	bNode, err := read(b)
+	if err != nil {
+		return "", false, err
+	}
+	options = jd.InferKeys(aNode, bNode, options...)
 	diff := aNode.Diff(bNode, options...)
 	var renderOptions []jd.Option
 	// Include all the original options to show in the header
@@ -294,11 +771,20 @@ func diff(a, b string, options []jd.Option) (string, bool, error) {
 	} else if *color {
 		renderOptions = append(renderOptions, jd.COLOR)
 	}
+	if *stat {
+		return renderStats(diff)
+	}
+	return renderDiff(diff, *format, renderOptions)
+}
+
+// renderDiff renders diff in format and reports whether it has hunks.
+func renderDiff(diff jd.Diff, format string, renderOptions []jd.Option) (string, bool, error) {
 	var (
 		str      string
 		haveDiff bool
+		err      error
 	)
-	switch *format {
+	switch format {
 	case "", "jd":
 		str = diff.Render(renderOptions...)
 		if str != "" {
@@ -320,52 +806,212 @@ func diff(a, b string, options []jd.Option) (string, bool, error) {
 		if str != "{}" {
 			haveDiff = true
 		}
+	case "json-hunks":
+		str, err = diff.RenderJsonHunks()
+		if err != nil {
+			return "", false, err
+		}
+		if str != "[]" {
+			haveDiff = true
+		}
 	default:
-		return "", false, fmt.Errorf("Invalid format: %q", *format)
+		return "", false, fmt.Errorf("Invalid format: %q", format)
 	}
 	return str, haveDiff, nil
 }
 
-func printPatch(p, a string, options []jd.Option) {
-	var diff jd.Diff
-	var err error
-	switch *format {
+// readDiff reads p as a diff in format.
+func readDiff(p, format string) (jd.Diff, error) {
+	switch format {
 	case "", "jd":
-		diff, err = jd.ReadDiffString(p)
+		return jd.ReadDiffString(p)
 	case "patch":
-		diff, err = jd.ReadPatchString(p)
+		return jd.ReadPatchString(p)
 	case "merge":
-		diff, err = jd.ReadMergeString(p)
+		return jd.ReadMergeString(p)
+	case "json-hunks":
+		return jd.ReadJsonHunksString(p)
+	}
+	return nil, fmt.Errorf("Invalid format: %q", format)
+}
+
+// readInput reads s as JSON, YAML or a stream of YAML documents.
+func readInput(s string) (jd.JsonNode, error) {
+	switch {
+	case *docs:
+		return jd.ReadYamlDocumentsString(s, identity...)
+	case *yaml:
+		return jd.ReadYamlString(s)
+	}
+	return jd.ReadJsonString(s)
+}
+
+// parseIdentity reads the paths of -docs-identity.
+func parseIdentity() ([]jd.Path, error) {
+	if *docsIdentity == "" {
+		return nil, nil
+	}
+	if !*docs {
+		return nil, fmt.Errorf("-docs-identity requires -docs")
+	}
+	var paths []interface{}
+	if err := json.Unmarshal([]byte(*docsIdentity), &paths); err != nil {
+		return nil, fmt.Errorf("-docs-identity must be a JSON array of paths")
+	}
+	identity := []jd.Path{}
+	for _, p := range paths {
+		pn, err := jd.NewJsonNode(p)
+		if err != nil {
+			return nil, err
+		}
+		path, err := jd.NewPath(pn)
+		if err != nil {
+			return nil, err
+		}
+		identity = append(identity, path)
+	}
+	return identity, nil
+}
+
+func renderStats(diff jd.Diff) (string, bool, error) {
+	stats := diff.Stats()
+	switch *format {
+	case "", "jd":
+		return stats.Render(), len(diff) > 0, nil
+	case "json":
+		return stats.Json(), len(diff) > 0, nil
 	default:
-		errorfAndExit("Invalid format: %q", *format)
+		return "", false, fmt.Errorf("Invalid stat format: %q", *format)
 	}
+}
+
+func printPatch(p, a string, options []jd.Option) {
+	diff, err := readDiff(p, *format)
 	if err != nil {
 		errorAndExit(err)
 	}
-	var aNode jd.JsonNode
-	if *yaml {
-		aNode, err = jd.ReadYamlString(a)
-	} else {
-		aNode, err = jd.ReadJsonString(a)
+	if *reverse {
+		diff, err = diff.Reverse()
+		if err != nil {
+			errorAndExit(err)
+		}
+	}
+	if *keepFormat {
+		printText(a, diff)
 	}
+	aNode, err := readInput(a)
 	if err != nil {
 		errorAndExit(err)
 	}
-	bNode, err := aNode.Patch(diff)
+	exitCode := 0
+	var bNode jd.JsonNode
+	if *fuzz >= 0 {
+		bNode, exitCode = patchFuzzy(aNode, diff)
+	} else {
+		bNode, err = aNode.Patch(diff)
+		if err != nil {
+			errorAndExit(err)
+		}
+	}
+	var out string
+	switch {
+	case *docs:
+		out, err = jd.YamlDocuments(bNode, a, identity...)
+		if err != nil {
+			errorAndExit(err)
+		}
+	case *yaml:
+		out = bNode.Yaml(options...)
+	default:
+		out = bNode.Json(options...)
+	}
+	if *output == "" {
+		fmt.Print(out)
+	} else {
+		os.WriteFile(*output, []byte(out), 0644)
+	}
+	os.Exit(exitCode)
+}
+
+// patchFuzzy applies the hunks of diff which match a within -fuzz
+// places, reporting moved and rejected hunks to STDERR like GNU patch.
+// Rejected hunks are written to -rej, by default the patched file (or
+// the diff when patching STDIN) with a .rej suffix. It returns exit
+// code 1 when hunks were rejected.
+func patchFuzzy(a jd.JsonNode, diff jd.Diff) (jd.JsonNode, int) {
+	b, results, rejected := jd.PatchFuzzy(a, diff, *fuzz)
+	for i, r := range results {
+		switch {
+		case r.Err != nil:
+			fmt.Fprintf(os.Stderr, "Hunk #%v FAILED at %v: %v\n", i+1, r.Path.JsonNode().Json(), r.Err)
+		case r.Offset != 0:
+			fmt.Fprintf(os.Stderr, "Hunk #%v succeeded at %v (offset %v).\n", i+1, r.Path.JsonNode().Json(), r.Offset)
+		}
+	}
+	if len(rejected) == 0 {
+		return b, 0
+	}
+	rejFile := *rej
+	if rejFile == "" {
+		rejFile = flag.Arg(len(flag.Args())-1) + ".rej"
+	}
+	if err := os.WriteFile(rejFile, []byte(rejected.Render()), 0644); err != nil {
+		errorAndExit(err)
+	}
+	fmt.Fprintf(os.Stderr, "%v out of %v hunks FAILED -- saving rejects to file %v\n",
+		len(rejected), len(diff), rejFile)
+	return b, 1
+}
+
+// printText patches the source text of a in place of its values.
+func printText(a string, diff jd.Diff) {
+	var out string
+	var err error
+	if *yaml {
+		out, err = jd.PatchYamlText(a, diff)
+	} else {
+		out, err = jd.PatchJsonText(a, diff)
+	}
 	if err != nil {
 		errorAndExit(err)
 	}
+	if *output == "" {
+		fmt.Print(out)
+	} else {
+		os.WriteFile(*output, []byte(out), 0644)
+	}
+	os.Exit(0)
+}
+
+func printMerge3(base, ours, theirs string, options []jd.Option) {
+	var nodes [3]jd.JsonNode
+	for i, s := range []string{base, ours, theirs} {
+		var err error
+		if *yaml {
+			nodes[i], err = jd.ReadYamlString(s)
+		} else {
+			nodes[i], err = jd.ReadJsonString(s)
+		}
+		if err != nil {
+			errorAndExit(err)
+		}
+	}
+	merged, conflicts := jd.Merge3(nodes[0], nodes[1], nodes[2], options...)
 	var out string
 	if *yaml {
-		out = bNode.Yaml(options...)
+		out = merged.Yaml(options...)
 	} else {
-		out = bNode.Json(options...)
+		out = merged.Json(options...)
 	}
 	if *output == "" {
 		fmt.Print(out)
 	} else {
 		os.WriteFile(*output, []byte(out), 0644)
 	}
+	if len(conflicts) > 0 {
+		fmt.Fprint(os.Stderr, conflicts.Diff().Render())
+		os.Exit(1)
+	}
 	os.Exit(0)
 }
 
@@ -402,6 +1048,21 @@ func printTranslation(a string) {
 			errorAndExit(err)
 		}
 		out = patch.Render()
+	case "jd2json-hunks":
+		diff, err := jd.ReadDiffString(a)
+		if err != nil {
+			errorAndExit(err)
+		}
+		out, err = diff.RenderJsonHunks()
+		if err != nil {
+			errorAndExit(err)
+		}
+	case "json-hunks2jd":
+		diff, err := jd.ReadJsonHunksString(a)
+		if err != nil {
+			errorAndExit(err)
+		}
+		out = diff.Render()
 	case "json2yaml":
 		node, err := jd.ReadJsonString(a)
 		if err != nil {
@@ -426,29 +1087,40 @@ func printTranslation(a string) {
 }
 
 func errorAndExit(err error) {
-	errorfAndExit("%v", err.Error())
+	if *jsonErrors {
+		// Written like the errors of -serve-api, without a code.
+		json.NewEncoder(os.Stderr).Encode(map[string]*apiError{"error": newApiError(0, "", err)})
+		os.Exit(2)
+	}
+	log.Print(err.Error())
+	os.Exit(2)
 }
 
 func errorfAndExit(msg string, args ...interface{}) {
-	log.Printf(msg, args...)
-	os.Exit(2)
+	errorAndExit(fmt.Errorf(msg, args...))
 }
 
 func readFile(filename string) string {
 	bytes, err := os.ReadFile(filename)
 	if err != nil {
-		log.Print(err.Error())
-		os.Exit(2)
+		errorAndExit(err)
 	}
 	return string(bytes)
 }
 
+func openFile(filename string) io.Reader {
+	f, err := os.Open(filename)
+	if err != nil {
+		errorAndExit(err)
+	}
+	return bufio.NewReader(f)
+}
+
 func readStdin() string {
 	r := bufio.NewReader(os.Stdin)
 	bytes, err := io.ReadAll(r)
 	if err != nil {
-		log.Print(err.Error())
-		os.Exit(2)
+		errorAndExit(err)
 	}
 	return string(bytes)
 }
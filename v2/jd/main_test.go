@@ -1,9 +1,9 @@
 package main
 
 import (
-	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 	"testing"
 )
@@ -31,7 +31,8 @@ func TestMain(t *testing.T) {
 		exitCode       int
 		out            *string
 		outFile        string
-		wantFileHeader string // if set, verify output starts with ^ {"file":"...<this>"}
+		wantFiles      map[string]string // file contents after running
+		wantFileHeader string            // if set, verify output starts with ^ {"file":"...<this>"}
 	}{{
 		name: "no diff",
 		files: map[string]string{
@@ -91,6 +92,222 @@ func TestMain(t *testing.T) {
 		args:     []string{"-f", "merge", "a.json", "b.json"},
 		out:      ref(`{"foo":"baz"}`),
 		exitCode: 1,
+	}, {
+		name: "diff with moves",
+		files: map[string]string{
+			"a.json": `{"foo":{"bar":[1,2]}}`,
+			"b.json": `{"baz":{"bar":[1,2]}}`,
+		},
+		args:     []string{"-moves", "-f", "patch", "a.json", "b.json"},
+		out:      ref(`[{"op":"move","from":"/foo","path":"/baz"}]`),
+		exitCode: 1,
+	}, {
+		name: "diff with inferred keys",
+		files: map[string]string{
+			"a.json": `{"users":[{"name":"ann","age":30},{"name":"bob","age":40}]}`,
+			"b.json": `{"users":[{"name":"bob","age":41},{"name":"ann","age":30}]}`,
+		},
+		args: []string{"-autokeys", "a.json", "b.json"},
+		out: ref(s(
+			`^ {"@":["users"],"^":[{"keys":["name"]}]}`,
+			`@ ["users",{"name":"bob"},"age"]`,
+			`- 40`,
+			`+ 41`,
+		)),
+		wantFileHeader: "a.json",
+		exitCode:       1,
+	}, {
+		name: "diff with coerce",
+		files: map[string]string{
+			"a.json": `{"port":"8080","tls":"true","name":"web"}`,
+			"b.json": `{"port":8080,"tls":true,"name":"api"}`,
+		},
+		args: []string{"-coerce", "a.json", "b.json"},
+		out: ref(s(
+			`^ "COERCE"`,
+			`@ ["name"]`,
+			`- "web"`,
+			`+ "api"`,
+		)),
+		wantFileHeader: "a.json",
+		exitCode:       1,
+	}, {
+		name: "diff with pattern",
+		files: map[string]string{
+			"a.json": `{"build":"b-1041","status":"ok"}`,
+			"b.json": `{"build":"b-1042","status":"failed"}`,
+		},
+		args: []string{`-opts=[{"@":["build"],"^":[{"pattern":"^b-[0-9]+$"}]}]`, "a.json", "b.json"},
+		out: ref(s(
+			`^ {"@":["build"],"^":[{"pattern":"^b-[0-9]+$"}]}`,
+			`@ ["status"]`,
+			`- "ok"`,
+			`+ "failed"`,
+		)),
+		wantFileHeader: "a.json",
+		exitCode:       1,
+	}, {
+		name: "diff with embedded documents",
+		files: map[string]string{
+			"a.json": `{"policy":"{\"Effect\":\"Allow\",\"Action\":[\"s3:GetObject\"]}"}`,
+			"b.json": `{"policy":"{\"Action\":[\"s3:GetObject\",\"s3:PutObject\"],\"Effect\":\"Allow\"}"}`,
+		},
+		args: []string{`-opts=["EMBEDDED"]`, "a.json", "b.json"},
+		out: ref(s(
+			`^ "EMBEDDED"`,
+			`@ ["policy",["json"],"Action",1]`,
+			`  "s3:GetObject"`,
+			`+ "s3:PutObject"`,
+			`]`,
+		)),
+		wantFileHeader: "a.json",
+		exitCode:       1,
+	}, {
+		name: "patch embedded document",
+		files: map[string]string{
+			"a.json": `{"values":"replicas: 1\n"}`,
+			"patch": `
+@ ["values",["yaml"],"replicas"]
+- 1
++ 3
+`,
+		},
+		args:     []string{"-p", "patch", "a.json"},
+		out:      ref(`{"values":"replicas: 3\n"}`),
+		exitCode: 0,
+	}, {
+		name: "diff set with precision",
+		files: map[string]string{
+			"a.json": `[1.001,2.5,3.2]`,
+			"b.json": `[3.205,1,2.5,4]`,
+		},
+		args: []string{"-set", "-precision=0.01", "a.json", "b.json"},
+		out: ref(s(
+			`^ "SET"`,
+			`^ {"precision":0.01}`,
+			`@ [{}]`,
+			`+ 4`,
+		)),
+		wantFileHeader: "a.json",
+		exitCode:       1,
+	}, {
+		name: "diff with relative tolerance",
+		files: map[string]string{
+			"a.json": `{"bytes":1000000000,"errors":100}`,
+			"b.json": `{"bytes":1004000000,"errors":101}`,
+		},
+		args: []string{`-opts=[{"@":["bytes"],"^":[{"relative":0.01}]}]`, "a.json", "b.json"},
+		out: ref(s(
+			`^ {"@":["bytes"],"^":[{"relative":0.01}]}`,
+			`@ ["errors"]`,
+			`- 100`,
+			`+ 101`,
+		)),
+		wantFileHeader: "a.json",
+		exitCode:       1,
+	}, {
+		name: "relative tolerance must not be negative",
+		files: map[string]string{
+			"a.json": `{}`,
+			"b.json": `{}`,
+		},
+		args:     []string{`-opts=[{"@":["bytes"],"^":[{"relative":-1}]}]`, "a.json", "b.json"},
+		exitCode: 2,
+	}, {
+		name: "diff with similarity",
+		files: map[string]string{
+			"a.json": `[{"id":1,"v":1},{"id":2,"v":1}]`,
+			"b.json": `[{"id":2,"v":2}]`,
+		},
+		args: []string{"-similarity=0.5", "a.json", "b.json"},
+		out: ref(s(
+			`^ {"similarity":0.5}`,
+			`@ [0]`,
+			`[`,
+			`- {"id":1,"v":1}`,
+			`  {"id":2,"v":1}`,
+			`@ [0,"v"]`,
+			`- 1`,
+			`+ 2`,
+		)),
+		wantFileHeader: "a.json",
+		exitCode:       1,
+	}, {
+		name: "stream diff",
+		files: map[string]string{
+			"a.json": `{"foo":[1,2,3],"bar":true}`,
+			"b.json": `{"foo":[1,3],"bar":true}`,
+		},
+		args: []string{"-stream", "a.json", "b.json"},
+		out: ref(s(
+			`@ ["foo",1]`,
+			`  1`,
+			`- 2`,
+			`  3`,
+		)),
+		exitCode:       1,
+		wantFileHeader: "a.json",
+	}, {
+		name: "stream no diff",
+		files: map[string]string{
+			"a.json": `[1,2,3]`,
+			"b.json": `[1,2,3]`,
+		},
+		args:     []string{"-stream", "a.json", "b.json"},
+		out:      ref(""),
+		exitCode: 0,
+	}, {
+		name: "json errors for a stale patch",
+		files: map[string]string{
+			"patch":  s(`@ ["foo"]`, `- "bar"`, `+ "baz"`),
+			"a.json": `{"foo":"qux"}`,
+		},
+		args: []string{"-json-errors", "-p", "patch", "a.json"},
+		out: ref(s(
+			`{"error":{"message":"found \"qux\" at [foo]: expected \"bar\"","type":"patch_conflict","path":["foo"],"expected":"bar","actual":"qux"}}`,
+		)),
+		exitCode: 2,
+	}, {
+		name: "json errors for a malformed patch",
+		files: map[string]string{
+			"patch":  s(`@ ["foo"]`, `- {"bar"}`),
+			"a.json": `{"foo":"qux"}`,
+		},
+		args: []string{"-json-errors", "-p", "patch", "a.json"},
+		out: ref(s(
+			`{"error":{"message":"invalid diff at line 2. Invalid value. invalid character '}' after object key","type":"parse_error","line":2,"col":9}}`,
+		)),
+		exitCode: 2,
+	}, {
+		name: "json errors for a missing path",
+		files: map[string]string{
+			"patch":  s(`@ [3,0]`, `- 1`),
+			"a.json": `[[1]]`,
+		},
+		args: []string{"-json-errors", "-p", "patch", "a.json"},
+		out: ref(s(
+			`{"error":{"message":"no value at [3]","type":"path_not_found","path":[3]}}`,
+		)),
+		exitCode: 2,
+	}, {
+		name: "json errors for usage",
+		args: []string{"-json-errors", "-serve-api"},
+		out: ref(s(
+			`{"error":{"message":"The HTTP API (-serve-api) requires -port and does not support arguments"}}`,
+		)),
+		exitCode: 2,
+	}, {
+		name:     "serving the API requires a port",
+		args:     []string{"-serve-api"},
+		exitCode: 2,
+	}, {
+		name: "stream only supports jd format",
+		files: map[string]string{
+			"a.json": `[]`,
+			"b.json": `[]`,
+		},
+		args:     []string{"-stream", "-f", "patch", "a.json", "b.json"},
+		exitCode: 2,
 	}, {
 		name: "exit 0 on successful patch",
 		files: map[string]string{
@@ -104,6 +321,471 @@ func TestMain(t *testing.T) {
 		args:     []string{"-p", "patch", "a.json"},
 		out:      ref(`{"foo":"baz"}`),
 		exitCode: 0,
+	}, {
+		name: "patch with RFC 6902 replace, move and test",
+		files: map[string]string{
+			"a.json": `{"foo":["bar","baz"]}`,
+			"p.json": `[{"op":"replace","path":"/foo/1","value":"qux"},{"op":"move","from":"/foo","path":"/zap"},{"op":"test","path":"/zap/0","value":"bar"}]`,
+		},
+		args:     []string{"-p", "-f", "patch", "p.json", "a.json"},
+		out:      ref(`{"zap":["bar","qux"]}`),
+		exitCode: 0,
+	}, {
+		name: "translate RFC 6902 replace and remove",
+		files: map[string]string{
+			"p.json": `[{"op":"replace","path":"/foo","value":1},{"op":"remove","path":"/bar"}]`,
+		},
+		args: []string{"-t", "patch2jd", "p.json"},
+		out: ref(s(
+			`^ {"Unchecked":true}`,
+			`@ ["foo"]`,
+			`+ 1`,
+			`^ {"Unchecked":true}`,
+			`@ ["bar"]`,
+			`+`,
+		)),
+		exitCode: 0,
+	}, {
+		name: "translate RFC 6902 replace of a list element",
+		files: map[string]string{
+			"p.json": `[{"op":"replace","path":"/l/0","value":1}]`,
+		},
+		args: []string{"-t", "patch2jd", "p.json"},
+		out: ref(s(
+			`^ {"Unchecked":true}`,
+			`@ ["l",0]`,
+			`+ 1`,
+		)),
+		exitCode: 0,
+	}, {
+		name: "patch with an unchecked hunk",
+		files: map[string]string{
+			"a.json": `{"l":[3,4]}`,
+			"p.jd": s(
+				`^ {"Unchecked":true}`,
+				`@ ["l",0]`,
+				`+ 1`,
+			),
+		},
+		args:     []string{"-p", "p.jd", "a.json"},
+		out:      ref(`{"l":[1,4]}`),
+		exitCode: 0,
+	}, {
+		name: "diff in json-hunks format",
+		files: map[string]string{
+			"a.json": `{"foo":[1,2]}`,
+			"b.json": `{"foo":[1,3]}`,
+		},
+		args:     []string{"-f", "json-hunks", "a.json", "b.json"},
+		out:      ref(`[{"Metadata":{"Merge":false},"Path":["foo",1],"Before":[1],"Remove":[2],"Add":[3],"After":[],"EndOfList":true}]`),
+		exitCode: 1,
+	}, {
+		name: "patch in json-hunks format",
+		files: map[string]string{
+			"a.json": `{"foo":[1,2]}`,
+			"p.json": `[{"Metadata":{"Merge":false},"Path":["foo",1],"Before":[1],"Remove":[2],"Add":[3],"After":[],"EndOfList":true}]`,
+		},
+		args:     []string{"-p", "-f", "json-hunks", "p.json", "a.json"},
+		out:      ref(`{"foo":[1,3]}`),
+		exitCode: 0,
+	}, {
+		name: "translate json-hunks to jd",
+		files: map[string]string{
+			"p.json": `[{"Metadata":{"Merge":true},"Path":["foo"],"Delete":true}]`,
+		},
+		args: []string{"-t", "json-hunks2jd", "p.json"},
+		out: ref(s(
+			`^ {"Merge":true}`,
+			`@ ["foo"]`,
+			`+`,
+		)),
+		exitCode: 0,
+	}, {
+		name: "translate jd to json-hunks",
+		files: map[string]string{
+			"p.diff": s(
+				`@ ["foo"]`,
+				`- 1`,
+			),
+		},
+		args:     []string{"-t", "jd2json-hunks", "p.diff"},
+		out:      ref(`[{"Metadata":{"Merge":false},"Path":["foo"],"Before":[],"Remove":[1],"Add":[],"After":[]}]`),
+		exitCode: 0,
+	}, {
+		name: "stat",
+		files: map[string]string{
+			"a.json": `{"foo":[1,2],"bar":{"baz":1}}`,
+			"b.json": `{"foo":[1,3,4],"bar":{}}`,
+		},
+		args: []string{"-stat", "a.json", "b.json"},
+		out: ref(s(
+			` ["bar"] | 1 -1`,
+			` ["foo"] | 2 +1 ~1`,
+			` 3 values: 1 added, 1 removed, 1 changed`,
+			` deepest: ["bar","baz"]`,
+		)),
+		exitCode: 1,
+	}, {
+		name: "stat as json",
+		files: map[string]string{
+			"a.json": `{"foo":1}`,
+			"b.json": `{"foo":2}`,
+		},
+		args:     []string{"-stat", "-f", "json", "a.json", "b.json"},
+		out:      ref(`{"Added":0,"Changed":1,"Deepest":["foo"],"Paths":[{"Added":0,"Changed":1,"Path":["foo"],"Removed":0}],"Removed":0}`),
+		exitCode: 1,
+	}, {
+		name: "stat no diff",
+		files: map[string]string{
+			"a.json": `{"foo":1}`,
+			"b.json": `{"foo":1}`,
+		},
+		args:     []string{"-stat", "a.json", "b.json"},
+		out:      ref(""),
+		exitCode: 0,
+	}, {
+		name: "exclude wildcard path",
+		files: map[string]string{
+			"a.json": `{"items":[{"gen":1,"name":"a"},{"gen":1,"name":"b"}]}`,
+			"b.json": `{"items":[{"gen":2,"name":"a"},{"gen":3,"name":"c"}]}`,
+		},
+		args: []string{"-exclude", `["items",*,"gen"]`, "a.json", "b.json"},
+		out: ref(s(
+			`^ {"@":["items",null,"gen"],"^":["DIFF_OFF"]}`,
+			`@ ["items",1,"name"]`,
+			`- "b"`,
+			`+ "c"`,
+		)),
+		exitCode:       1,
+		wantFileHeader: "a.json",
+	}, {
+		name: "include and exclude",
+		files: map[string]string{
+			"a.json": `{"spec":{"a":{"image":"x","tag":1},"b":{"image":"y","tag":1}},"status":1}`,
+			"b.json": `{"spec":{"a":{"image":"z","tag":2},"b":{"image":"w","tag":2}},"status":2}`,
+		},
+		args: []string{"-include", `["spec",*]`, "-exclude", `["spec",*,"tag"]`, "-exclude", `["spec","b"]`, "a.json", "b.json"},
+		out: ref(s(
+			`^ {"@":[],"^":["DIFF_OFF"]}`,
+			`^ {"@":["spec",null],"^":["DIFF_ON"]}`,
+			`^ {"@":["spec",null,"tag"],"^":["DIFF_OFF"]}`,
+			`^ {"@":["spec","b"],"^":["DIFF_OFF"]}`,
+			`@ ["spec","a","image"]`,
+			`- "x"`,
+			`+ "z"`,
+		)),
+		exitCode:       1,
+		wantFileHeader: "a.json",
+	}, {
+		name: "invalid path pattern",
+		files: map[string]string{
+			"a.json": `{}`,
+			"b.json": `{}`,
+		},
+		args:     []string{"-exclude", `["a",**]`, "a.json", "b.json"},
+		exitCode: 2,
+	}, {
+		name: "recursive diff",
+		files: map[string]string{
+			"a/same.json":      `{"a":1}`,
+			"a/changed.json":   `{"a":1}`,
+			"a/removed.json":   `[1]`,
+			"a/config.yml":     `a: [1, 2] # list`,
+			"a/notes.txt":      `not JSON`,
+			"b/same.json":      `{"a":1}`,
+			"b/changed.json":   `{"a":2}`,
+			"b/sub/added.json": `{}`,
+			"b/config.yml":     `a: [1, 3]`,
+			"b/notes.txt":      `still not JSON`,
+			"b/README":         `# jd`,
+		},
+		args: []string{"-r", "a", "b"},
+		out: ref(s(
+			`^ {"file":"changed.json"}`,
+			`@ ["a"]`,
+			`- 1`,
+			`+ 2`,
+			`^ {"file":"config.yml"}`,
+			`@ ["a",1]`,
+			`  1`,
+			`- 2`,
+			`+ 3`,
+			`]`,
+			`^ {"file":"removed.json"}`,
+			`@ []`,
+			`- [1]`,
+			`^ {"file":"sub/added.json"}`,
+			`@ []`,
+			`+ {}`,
+		)),
+		exitCode: 1,
+	}, {
+		name: "recursive diff reads back",
+		files: map[string]string{
+			"out.jd": s(
+				`^ {"file":"removed.json"}`,
+				`@ []`,
+				`- [1]`,
+				`^ {"file":"sub/added.json"}`,
+				`@ []`,
+				`+ {}`,
+			),
+		},
+		args:     []string{"-t", "jd2patch", "out.jd"},
+		out:      ref(`[{"op":"test","path":"","value":[1]},{"op":"remove","path":"","value":[1]},{"op":"add","path":"","value":{}}]`),
+		exitCode: 0,
+	}, {
+		name: "recursive diff of YAML documents",
+		files: map[string]string{
+			"a/x.yaml": s(`id: 1`, `v: a`, `---`, `id: 2`),
+			"b/x.yaml": s(`id: 2`, `---`, `id: 1`, `v: b`),
+			"a/y.json": `{"id":1}`,
+			"b/y.json": `{"id":1}`,
+		},
+		args: []string{"-r", "-docs", `-docs-identity=[["id"]]`, "a", "b"},
+		out: ref(s(
+			`^ {"file":"x.yaml"}`,
+			`@ ["[1]","v"]`,
+			`- "a"`,
+			`+ "b"`,
+		)),
+		exitCode: 1,
+	}, {
+		name: "recursive no diff",
+		files: map[string]string{
+			"a/x.json": `{"a":1}`,
+			"b/x.json": `{"a":1}`,
+		},
+		args:     []string{"-r", "a", "b"},
+		out:      ref(""),
+		exitCode: 0,
+	}, {
+		name: "diff yaml documents",
+		files: map[string]string{
+			"a.yaml": s(
+				`kind: Service`,
+				`metadata: {name: web}`,
+				`---`,
+				`kind: Deployment`,
+				`metadata: {name: web}`,
+				`spec: {replicas: 1}`,
+			),
+			"b.yaml": s(
+				`kind: Deployment`,
+				`metadata: {name: web}`,
+				`spec: {replicas: 2}`,
+			),
+		},
+		args: []string{"-docs", "a.yaml", "b.yaml"},
+		out: ref(s(
+			`@ ["[null,\"Deployment\",null,\"web\"]","spec","replicas"]`,
+			`- 1`,
+			`+ 2`,
+			`@ ["[null,\"Service\",null,\"web\"]"]`,
+			`- {"kind":"Service","metadata":{"name":"web"}}`,
+		)),
+		exitCode:       1,
+		wantFileHeader: "a.yaml",
+	}, {
+		name: "diff yaml documents by identity",
+		files: map[string]string{
+			"a.yaml": s(`id: 1`, `v: a`, `---`, `id: 2`, `v: b`),
+			"b.yaml": s(`id: 2`, `v: b`, `---`, `id: 1`, `v: c`),
+		},
+		args: []string{"-docs", `-docs-identity=[["id"]]`, "a.yaml", "b.yaml"},
+		out: ref(s(
+			`@ ["[1]","v"]`,
+			`- "a"`,
+			`+ "c"`,
+		)),
+		exitCode:       1,
+		wantFileHeader: "a.yaml",
+	}, {
+		name: "patch yaml documents",
+		files: map[string]string{
+			"p.jd": s(
+				`@ ["[null,\"Deployment\",null,\"web\"]","spec","replicas"]`,
+				`- 1`,
+				`+ 2`,
+			),
+			"a.yaml": s(
+				`kind: Service`,
+				`metadata: {name: web}`,
+				`---`,
+				`kind: Deployment`,
+				`metadata: {name: web}`,
+				`spec: {replicas: 1}`,
+			),
+		},
+		args: []string{"-docs", "-p", "p.jd", "a.yaml"},
+		out: ref(s(
+			`kind: Service`,
+			`metadata:`,
+			`    name: web`,
+			`---`,
+			`kind: Deployment`,
+			`metadata:`,
+			`    name: web`,
+			`spec:`,
+			`    replicas: 2`,
+		)),
+		exitCode: 0,
+	}, {
+		name: "patch keeping the format",
+		files: map[string]string{
+			"a.json": "{\n  \"z\": 1,\n  \"a\": [1, 2]\n}\n",
+			"p.jd": s(
+				`@ ["a",1]`,
+				`  1`,
+				`- 2`,
+				`+ 3`,
+				`]`,
+			),
+		},
+		args: []string{"-p", "-keep-format", "p.jd", "a.json"},
+		out: ref(s(
+			`{`,
+			`  "z": 1,`,
+			`  "a": [1, 3]`,
+			`}`,
+		)),
+		exitCode: 0,
+	}, {
+		name: "patch keeping the YAML format",
+		files: map[string]string{
+			"a.yaml": s(
+				`z: 1 # first`,
+				`a: 1`,
+			),
+			"p.jd": s(
+				`@ ["a"]`,
+				`- 1`,
+				`+ 2`,
+			),
+		},
+		args: []string{"-yaml", "-p", "-keep-format", "p.jd", "a.yaml"},
+		out: ref(s(
+			`z: 1 # first`,
+			`a: 2`,
+		)),
+		exitCode: 0,
+	}, {
+		name: "keeping the format requires patch mode",
+		files: map[string]string{
+			"a.json": `{}`,
+			"b.json": `{}`,
+		},
+		args:     []string{"-keep-format", "a.json", "b.json"},
+		exitCode: 2,
+	}, {
+		name: "keeping the format of an unpatchable document",
+		files: map[string]string{
+			"a.json": `{"a":1}`,
+			"p.jd": s(
+				`@ ["a"]`,
+				`- 2`,
+			),
+		},
+		args:     []string{"-p", "-keep-format", "p.jd", "a.json"},
+		exitCode: 2,
+	}, {
+		name: "reverse patch",
+		files: map[string]string{
+			"a.json": `{"a":[1,3,4],"b":2}`,
+			"p.jd": s(
+				`@ ["a",1]`,
+				`  1`,
+				`- 2`,
+				`+ 3`,
+				`  4`,
+				`@ ["b"]`,
+				`- 1`,
+				`+ 2`,
+			),
+		},
+		args:     []string{"-p", "-R", "p.jd", "a.json"},
+		out:      ref(`{"a":[1,2,4],"b":1}`),
+		exitCode: 0,
+	}, {
+		name: "reverse merge patch",
+		files: map[string]string{
+			"a.json": `{"a":1}`,
+			"p.json": `{"a":1}`,
+		},
+		args:     []string{"-p", "-R", "-f=merge", "p.json", "a.json"},
+		exitCode: 2,
+	}, {
+		name: "reverse requires patch mode",
+		files: map[string]string{
+			"a.json": `{}`,
+			"b.json": `{}`,
+		},
+		args:     []string{"-R", "a.json", "b.json"},
+		exitCode: 2,
+	}, {
+		name: "fuzzy patch",
+		files: map[string]string{
+			"a.json": `{"a":[0,0,1,2,3]}`,
+			"p.jd": s(
+				`@ ["a",1]`,
+				`  1`,
+				`- 2`,
+				`+ 4`,
+				`  3`,
+			),
+		},
+		args: []string{"-p", "-fuzz=2", "p.jd", "a.json"},
+		out: ref(s(
+			`Hunk #1 succeeded at ["a",3] (offset 2).`,
+		) + `{"a":[0,0,1,4,3]}`),
+		exitCode: 0,
+	}, {
+		name: "fuzzy patch with rejected hunks",
+		files: map[string]string{
+			"a.json": `{"a":[1,2,3],"b":1}`,
+			"p.jd": s(
+				`@ ["a",1]`,
+				`  1`,
+				`- 2`,
+				`  3`,
+				`@ ["b"]`,
+				`- 2`,
+				`+ 3`,
+			),
+		},
+		args:     []string{"-p", "-fuzz=1", "p.jd", "a.json"},
+		exitCode: 1,
+		wantFiles: map[string]string{
+			"a.json.rej": s(
+				`@ ["b"]`,
+				`- 2`,
+				`+ 3`,
+			),
+		},
+	}, {
+		name: "fuzzy patch requires patch mode",
+		files: map[string]string{
+			"a.json": `{}`,
+			"b.json": `{}`,
+		},
+		args:     []string{"-fuzz=1", "a.json", "b.json"},
+		exitCode: 2,
+	}, {
+		name: "invalid document identity",
+		files: map[string]string{
+			"a.yaml": `a: 1`,
+			"b.yaml": `a: 1`,
+		},
+		args:     []string{"-docs", `-docs-identity=["id"]`, "a.yaml", "b.yaml"},
+		exitCode: 2,
+	}, {
+		name: "recursive requires directories",
+		files: map[string]string{
+			"a.json": `{}`,
+			"b.json": `{}`,
+		},
+		args:     []string{"-r", "a.json", "b.json"},
+		exitCode: 2,
 	}, {
 		name: "exit 1 on unsuccessful patch",
 		files: map[string]string{
@@ -116,6 +798,101 @@ func TestMain(t *testing.T) {
 		},
 		args:     []string{"-p", "patch", "a.json"},
 		exitCode: 2,
+	}, {
+		name: "merge3",
+		files: map[string]string{
+			"base.json":   `{"a":1,"b":1}`,
+			"ours.json":   `{"a":2,"b":1}`,
+			"theirs.json": `{"a":1,"b":2}`,
+		},
+		args:     []string{"-merge3", "base.json", "ours.json", "theirs.json"},
+		out:      ref(`{"a":2,"b":2}`),
+		exitCode: 0,
+	}, {
+		name: "merge3 with conflicts",
+		files: map[string]string{
+			"base.json":   `{"a":1}`,
+			"ours.json":   `{"a":2}`,
+			"theirs.json": `{"a":3}`,
+		},
+		args: []string{"-merge3", "base.json", "ours.json", "theirs.json"},
+		out: ref(`{"a":2}` + s(
+			`@ ["a"]`,
+			`- 2`,
+			`+ 3`,
+		)),
+		exitCode: 1,
+	}, {
+		name: "git merge driver",
+		files: map[string]string{
+			"base":        `{"a":1,"b":1}`,
+			"ours":        `{"a":2,"b":1}`,
+			"theirs":      `{"a":1,"b":2}`,
+			"config.json": ``,
+		},
+		args:     []string{"-git-merge-driver", "base", "ours", "theirs", "7", "config.json"},
+		out:      ref(``),
+		exitCode: 0,
+		wantFiles: map[string]string{
+			"ours": `{"a":2,"b":2}`,
+		},
+	}, {
+		name: "git merge driver with conflicts",
+		files: map[string]string{
+			"base":        `{"a":1,"b":1}`,
+			"ours":        `{"a":2,"b":1}`,
+			"theirs":      `{"a":3,"b":2}`,
+			"config.json": ``,
+		},
+		args:     []string{"-git-merge-driver", "base", "ours", "theirs", "7", "config.json"},
+		exitCode: 1,
+		wantFiles: map[string]string{
+			"ours": `{"a":2,"b":2}`,
+			"config.json.jd-conflicts": s(
+				`@ ["a"]`,
+				`- 2`,
+				`+ 3`,
+			),
+		},
+	}, {
+		name: "git merge driver with yaml",
+		files: map[string]string{
+			"base":        "a: 1\nb: 1\n",
+			"ours":        "a: 2\nb: 1\n",
+			"theirs":      "a: 1\nb: 2\n",
+			"config.yaml": ``,
+		},
+		args:     []string{"-git-merge-driver", "base", "ours", "theirs", "7", "config.yaml"},
+		exitCode: 0,
+		wantFiles: map[string]string{
+			"ours": "a: 2\nb: 2\n",
+		},
+	}, {
+		name: "git merge driver keeps formatting",
+		files: map[string]string{
+			"base":        `{"b":1,"a":1}`,
+			"ours":        s(`{`, `  "b": 1,`, `  "a": 2`, `}`),
+			"theirs":      `{"b":2,"a":1,"c":[1]}`,
+			"config.json": ``,
+		},
+		args:     []string{"-git-merge-driver", "base", "ours", "theirs", "7", "config.json"},
+		exitCode: 0,
+		wantFiles: map[string]string{
+			"ours": s(`{`, `  "b": 2,`, `  "a": 2,`, `  "c": [1]`, `}`),
+		},
+	}, {
+		name: "git merge driver keeps yaml comments",
+		files: map[string]string{
+			"base":        "a: 1\nb: 1\n",
+			"ours":        "# settings\na: 2 # ours\n\nb: 1\n",
+			"theirs":      "a: 1\nb: 2\n",
+			"config.yaml": ``,
+		},
+		args:     []string{"-git-merge-driver", "base", "ours", "theirs", "7", "config.yaml"},
+		exitCode: 0,
+		wantFiles: map[string]string{
+			"ours": "# settings\na: 2 # ours\n\nb: 2\n",
+		},
 	}}
 
 	testName := t.Name()
@@ -131,7 +908,15 @@ func TestMain(t *testing.T) {
 			files := map[string]struct{}{}
 			for filename, content := range tc.files {
 				files[filename] = struct{}{}
-				err := os.WriteFile(fmt.Sprintf("%v%v%v", temp, os.PathSeparator, filename), []byte(content), 0666)
+				// Directories of files can be arguments too.
+				for dir := filepath.Dir(filename); dir != "."; dir = filepath.Dir(dir) {
+					files[dir] = struct{}{}
+				}
+				path := filepath.Join(temp, filename)
+				if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+					t.Fatalf("error creating temp directory: %v", err)
+				}
+				err := os.WriteFile(path, []byte(content), 0666)
 				if err != nil {
 					t.Fatalf("error writing temp file: %v", err)
 				}
@@ -139,7 +924,7 @@ func TestMain(t *testing.T) {
 			args := make([]string, len(tc.args))
 			for i, arg := range tc.args {
 				if _, isFile := files[arg]; isFile {
-					args[i] = fmt.Sprintf("%v%v%v", temp, os.PathSeparator, arg)
+					args[i] = filepath.Join(temp, arg)
 				} else {
 					args[i] = arg
 				}
@@ -147,7 +932,8 @@ func TestMain(t *testing.T) {
 			cmd := exec.Command(os.Args[0], "-test.run", testName)
 			cmd.Env = append(os.Environ(), jdFlags+"="+strings.Join(args, " "))
 			out, _ := cmd.CombinedOutput()
-			outStr := string(out)
+			// Paths in the output are relative to the temp directory.
+			outStr := strings.ReplaceAll(string(out), temp+string(filepath.Separator), "")
 			if tc.wantFileHeader != "" {
 				prefix := `^ {"file":"`
 				if !strings.HasPrefix(outStr, prefix) {
@@ -169,6 +955,16 @@ func TestMain(t *testing.T) {
 			if exitCode := cmd.ProcessState.ExitCode(); exitCode != tc.exitCode {
 				t.Errorf("wanted exit code %v. got %v", tc.exitCode, exitCode)
 			}
+			for filename, want := range tc.wantFiles {
+				got, err := os.ReadFile(filepath.Join(temp, filename))
+				if err != nil {
+					t.Errorf("error reading %v: %v", filename, err)
+					continue
+				}
+				if string(got) != want {
+					t.Errorf("wanted %v to contain %q. got %q", filename, want, string(got))
+				}
+			}
 		})
 	}
 }
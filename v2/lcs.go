@@ -63,6 +63,7 @@ func (lcs *lcsImpl) calcTable(ctx context.Context) (table [][]int, err error) {
 
 	sizeX := len(lcs.left) + 1
 	sizeY := len(lcs.right) + 1
+	lcs.options.budget.cells(sizeX * sizeY)
 
 	table = make([][]int, sizeX)
 	for x := 0; x < sizeX; x++ {
@@ -78,15 +79,7 @@ func (lcs *lcsImpl) calcTable(ctx context.Context) (table [][]int, err error) {
 		}
 		for x := 1; x < sizeX; x++ {
 			increment := 0
-			// Use options-aware equality check with path-specific refinement
-			leftIndex := x - 1
-			rightIndex := y - 1
-
-			// Refine options for the specific left index to handle PathOptions
-			refinedOpts := refine(lcs.options, PathIndex(leftIndex))
-			allOpts := append(refinedOpts.apply, refinedOpts.retain...)
-
-			if lcs.left[leftIndex].Equals(lcs.right[rightIndex], allOpts...) {
+			if lcs.equal(x-1, y-1) {
 				increment = 1
 			}
 			table[x][y] = max(table[x-1][y-1]+increment, table[x-1][y], table[x][y-1])
@@ -97,6 +90,14 @@ func (lcs *lcsImpl) calcTable(ctx context.Context) (table [][]int, err error) {
 	return table, nil
 }
 
+// equal reports whether left[leftIndex] equals right[rightIndex] under
+// the options refined for leftIndex, so PathOptions are honored.
+func (lcs *lcsImpl) equal(leftIndex, rightIndex int) bool {
+	refinedOpts := refine(lcs.options, PathIndex(leftIndex))
+	allOpts := append(refinedOpts.apply, refinedOpts.retain...)
+	return lcs.left[leftIndex].Equals(lcs.right[rightIndex], allOpts...)
+}
+
 // Length implements lcs.Length()
 func (lcs *lcsImpl) Length() (length int) {
 	length, _ = lcs.LengthContext(context.Background())
@@ -132,7 +133,7 @@ func (lcs *lcsImpl) IndexPairsContext(ctx context.Context) (pairs []indexPair, e
 	pairs = make([]indexPair, table[len(table)-1][len(table[0])-1])
 
 	for x, y := len(lcs.left), len(lcs.right); x > 0 && y > 0; {
-		if lcs.left[x-1].Equals(lcs.right[y-1]) {
+		if lcs.equal(x-1, y-1) {
 			pairs[table[x][y]-1] = indexPair{Left: x - 1, Right: y - 1}
 			x--
 			y--
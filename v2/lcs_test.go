@@ -169,3 +169,13 @@ func TestLCSValuesCached(t *testing.T) {
 		}
 	}
 }
+
+func TestLCSIndexPairsWithOptions(t *testing.T) {
+	left := toJsonNodes([]interface{}{1.0, 2.0, 3.0, 4.0})
+	right := toJsonNodes([]interface{}{1.01, 2.01, 3.01, 5.0})
+	l := newLcsWithOptions(left, right, refine(newOptions([]Option{Precision(0.1)}), nil))
+	want := []indexPair{{0, 0}, {1, 1}, {2, 2}}
+	if got := l.IndexPairs(); !reflect.DeepEqual(got, want) {
+		t.Errorf("IndexPairs() = %#v. Want %#v", got, want)
+	}
+}
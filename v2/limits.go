@@ -0,0 +1,176 @@
+package jd
+
+import (
+	"context"
+	"fmt"
+)
+
+// Limits bound the work of DiffContext and PatchContext. A zero field
+// is unlimited.
+type Limits struct {
+	// MaxDepth is the deepest nesting of objects and arrays allowed in
+	// an input. A scalar has depth 0 and {"a":[1]} has depth 2.
+	MaxDepth int
+
+	// MaxNodes is the most values allowed in an input, counting every
+	// object, array and scalar.
+	MaxNodes int
+
+	// MaxLcsCells is the largest table allowed when aligning two
+	// lists, whether by LCS, Myers or Similarity.
+	MaxLcsCells int
+}
+
+// LimitError is returned by DiffContext and PatchContext when an input,
+// or the work to diff it, exceeds one of its Limits.
+type LimitError struct {
+	// Limit is the name of the exceeded field of Limits.
+	Limit string
+	// Max is the value of the exceeded field.
+	Max int
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("exceeded %v of %v", e.Limit, e.Max)
+}
+
+type limitsKey struct{}
+
+// WithLimits returns a copy of ctx carrying limits for DiffContext and
+// PatchContext.
+func WithLimits(ctx context.Context, limits Limits) context.Context {
+	return context.WithValue(ctx, limitsKey{}, limits)
+}
+
+// limitsFrom returns the Limits carried by ctx, if any.
+func limitsFrom(ctx context.Context) Limits {
+	limits, _ := ctx.Value(limitsKey{}).(Limits)
+	return limits
+}
+
+// checkNode returns a LimitError if n is deeper or larger than allowed.
+func (l Limits) checkNode(n JsonNode) error {
+	nodes := 0
+	var walk func(n JsonNode, depth int) error
+	walk = func(n JsonNode, depth int) error {
+		nodes++
+		if l.MaxNodes > 0 && nodes > l.MaxNodes {
+			return &LimitError{Limit: "MaxNodes", Max: l.MaxNodes}
+		}
+		if l.MaxDepth > 0 && depth > l.MaxDepth {
+			return &LimitError{Limit: "MaxDepth", Max: l.MaxDepth}
+		}
+		var children []JsonNode
+		switch n := n.(type) {
+		case jsonObject:
+			for _, v := range n {
+				children = append(children, v)
+			}
+		case jsonArray:
+			children = n
+		case jsonList:
+			children = n
+		case jsonSet:
+			children = n
+		case jsonMultiset:
+			children = n
+		}
+		for _, c := range children {
+			if err := walk(c, depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return walk(n, 0)
+}
+
+// budgetCheckInterval is how many steps a budget takes between checks
+// of its context.
+const budgetCheckInterval = 1024
+
+// budget is the context and Limits of one DiffContext. It travels in
+// the options as a budgetOption so every processor can reach it.
+type budget struct {
+	ctx    context.Context
+	limits Limits
+	steps  int
+}
+
+// abort unwinds a diff which ran out of budget. It is recovered by
+// diffContext and by nothing else, so it never escapes DiffContext.
+type abort struct {
+	err error
+}
+
+// step counts a unit of work, aborting when the context is done. A nil
+// budget is unlimited.
+func (b *budget) step() {
+	if b == nil {
+		return
+	}
+	b.steps++
+	if b.steps%budgetCheckInterval != 0 {
+		return
+	}
+	if err := b.ctx.Err(); err != nil {
+		panic(abort{err})
+	}
+}
+
+// context returns the context of b. A nil budget has no deadline.
+func (b *budget) context() context.Context {
+	if b == nil {
+		return context.Background()
+	}
+	return b.ctx
+}
+
+// cells aborts if a table of n cells is larger than allowed.
+func (b *budget) cells(n int) {
+	if b == nil {
+		return
+	}
+	if b.limits.MaxLcsCells > 0 && n > b.limits.MaxLcsCells {
+		panic(abort{&LimitError{Limit: "MaxLcsCells", Max: b.limits.MaxLcsCells}})
+	}
+	b.step()
+}
+
+type budgetOption struct {
+	budget *budget
+}
+
+func (o budgetOption) isOption() {}
+
+// diffContext implements DiffContext for every JsonNode.
+func diffContext(ctx context.Context, a, b JsonNode, opts []Option) (d Diff, err error) {
+	limits := limitsFrom(ctx)
+	for _, n := range []JsonNode{a, b} {
+		if err := limits.checkNode(n); err != nil {
+			return nil, err
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			a, ok := r.(abort)
+			if !ok { //jd:nocover — diffs only panic to abort
+				panic(r)
+			}
+			d, err = nil, a.err
+		}
+	}()
+	opts = append([]Option{budgetOption{&budget{ctx: ctx, limits: limits}}}, opts...)
+	return a.Diff(b, opts...), nil
+}
+
+// patchContext implements PatchContext for every JsonNode.
+func patchContext(ctx context.Context, n JsonNode, d Diff) (JsonNode, error) {
+	if err := limitsFrom(ctx).checkNode(n); err != nil {
+		return nil, err
+	}
+	return patchAllContext(ctx, n, d)
+}
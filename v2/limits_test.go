@@ -0,0 +1,281 @@
+package jd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// cancelAfter is a context which is canceled once its Err has been
+// checked n times.
+type cancelAfter struct {
+	context.Context
+	n int
+}
+
+func (c *cancelAfter) Err() error {
+	c.n--
+	if c.n < 0 {
+		return context.Canceled
+	}
+	return nil
+}
+
+func numbers(from, to int) string {
+	s := []string{}
+	for i := from; i < to; i++ {
+		s = append(s, fmt.Sprint(i))
+	}
+	return "[" + strings.Join(s, ",") + "]"
+}
+
+func TestDiffContext(t *testing.T) {
+	cases := []struct {
+		name    string
+		limits  Limits
+		options []Option
+		a       string
+		b       string
+		want    []string
+	}{{
+		name: "no limits",
+		a:    `{"a":[1,2,3],"b":{"c":1}}`,
+		b:    `{"a":[1,3],"b":{"c":2}}`,
+		want: ss(
+			`@ ["a",1]`,
+			`  1`,
+			`- 2`,
+			`  3`,
+			`@ ["b","c"]`,
+			`- 1`,
+			`+ 2`,
+		),
+	}, {
+		name:   "within limits",
+		limits: Limits{MaxDepth: 2, MaxNodes: 7, MaxLcsCells: 12},
+		a:      `{"a":[1,2,3],"b":{"c":1}}`,
+		b:      `{"a":[1,3],"b":{"c":2}}`,
+		want: ss(
+			`@ ["a",1]`,
+			`  1`,
+			`- 2`,
+			`  3`,
+			`@ ["b","c"]`,
+			`- 1`,
+			`+ 2`,
+		),
+	}, {
+		name:    "options",
+		limits:  Limits{MaxLcsCells: 1},
+		options: []Option{SET},
+		a:       `{"a":[1,2,3]}`,
+		b:       `{"a":[3,4,1]}`,
+		want: ss(
+			`@ ["a",{}]`,
+			`- 2`,
+			`+ 4`,
+		),
+	}, {
+		name:    "multiset",
+		limits:  Limits{MaxLcsCells: 1},
+		options: []Option{MULTISET, Precision(0.5)},
+		a:       `[1,1,2]`,
+		b:       `[1.1,2,3]`,
+		want: ss(
+			`@ [[]]`,
+			`- 1`,
+			`+ 3`,
+		),
+	}, {
+		name:    "similarity",
+		limits:  Limits{MaxLcsCells: 16},
+		options: []Option{Similarity(0.5)},
+		a:       `[{"a":1,"b":1},{"c":1}]`,
+		b:       `[{"a":1,"b":2},{"d":1}]`,
+		want: ss(
+			`@ [0,"b"]`,
+			`- 1`,
+			`+ 2`,
+			`@ [1]`,
+			`  {"a":1,"b":2}`,
+			`- {"c":1}`,
+			`+ {"d":1}`,
+			`]`,
+		),
+	}}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			a, b := mustParse(t, c.a), mustParse(t, c.b)
+			ctx := WithLimits(context.Background(), c.limits)
+			d, err := a.DiffContext(ctx, b, c.options...)
+			if err != nil {
+				t.Fatalf("wanted no error. got %v", err)
+			}
+			want := strings.Join(c.want, "\n")
+			if len(c.want) > 0 {
+				want += "\n"
+			}
+			if got := d.Render(); got != want {
+				t.Errorf("got:\n%v\nwant:\n%v", got, want)
+			}
+			if got := a.Diff(b, c.options...).Render(); got != want {
+				t.Errorf("Diff got:\n%v\nwant:\n%v", got, want)
+			}
+		})
+	}
+}
+
+func TestDiffContextLimits(t *testing.T) {
+	cases := []struct {
+		name    string
+		limits  Limits
+		options []Option
+		a       string
+		b       string
+		want    string
+	}{{
+		name:   "depth of a",
+		limits: Limits{MaxDepth: 2},
+		a:      `{"a":[[1]]}`,
+		b:      `{}`,
+		want:   "MaxDepth",
+	}, {
+		name:   "depth of b",
+		limits: Limits{MaxDepth: 1},
+		a:      `[1]`,
+		b:      `[{"a":1}]`,
+		want:   "MaxDepth",
+	}, {
+		name:   "nodes",
+		limits: Limits{MaxNodes: 3},
+		a:      `[1,2]`,
+		b:      `[1,2,3]`,
+		want:   "MaxNodes",
+	}, {
+		name:   "lcs",
+		limits: Limits{MaxLcsCells: 15},
+		a:      `[1,2,3]`,
+		b:      `[3,2,1]`,
+		want:   "MaxLcsCells",
+	}, {
+		name:   "myers",
+		limits: Limits{MaxLcsCells: 100},
+		a:      numbers(0, 20),
+		b:      numbers(10, 30),
+		want:   "MaxLcsCells",
+	}, {
+		name:   "nested lcs",
+		limits: Limits{MaxLcsCells: 10},
+		a:      `{"a":{"b":[1,2,3]}}`,
+		b:      `{"a":{"b":[3,2,1]}}`,
+		want:   "MaxLcsCells",
+	}}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			a, b := mustParse(t, c.a), mustParse(t, c.b)
+			ctx := WithLimits(context.Background(), c.limits)
+			_, err := a.DiffContext(ctx, b, c.options...)
+			var limitErr *LimitError
+			if !errors.As(err, &limitErr) {
+				t.Fatalf("wanted a LimitError. got %v", err)
+			}
+			if limitErr.Limit != c.want {
+				t.Errorf("wanted %v exceeded. got %v", c.want, err)
+			}
+		})
+	}
+}
+
+func TestDiffContextCanceled(t *testing.T) {
+	a, b := mustParse(t, numbers(0, 2000)), mustParse(t, numbers(1, 2001))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := a.DiffContext(ctx, b); !errors.Is(err, context.Canceled) {
+		t.Errorf("wanted context.Canceled. got %v", err)
+	}
+	// Cancel after the diff has started.
+	o1, o2 := jsonObject{}, jsonObject{}
+	for i := 0; i < 2000; i++ {
+		o1[fmt.Sprint(i)] = jsonNumber(i)
+		o2[fmt.Sprint(i)] = jsonNumber(i + 1)
+	}
+	if _, err := o1.DiffContext(&cancelAfter{context.Background(), 1}, o2); !errors.Is(err, context.Canceled) {
+		t.Errorf("wanted context.Canceled. got %v", err)
+	}
+	// Cancel while the LCS table of a short list is filled.
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	a, b = mustParse(t, `[1,2,3]`), mustParse(t, `[1,3]`)
+	if _, err := a.DiffContext(&cancelAfter{canceled, 1}, b); !errors.Is(err, context.Canceled) {
+		t.Errorf("wanted context.Canceled. got %v", err)
+	}
+}
+
+func TestContextAllNodes(t *testing.T) {
+	nodes := []JsonNode{
+		jsonObject{"a": jsonNumber(1)},
+		jsonArray{jsonNumber(1)},
+		jsonList{jsonNumber(1)},
+		jsonSet{jsonNumber(1)},
+		jsonMultiset{jsonNumber(1)},
+		jsonString("a"),
+		jsonNumber(1),
+		jsonDecimal("1.50"),
+		jsonBool(true),
+		jsonNull{},
+		voidNode{},
+	}
+	ctx := WithLimits(context.Background(), Limits{MaxNodes: 2})
+	for _, n := range nodes {
+		d, err := n.DiffContext(ctx, jsonNull{})
+		if err != nil {
+			t.Errorf("%T: wanted no error. got %v", n, err)
+			continue
+		}
+		if len(d) != len(n.Diff(jsonNull{})) {
+			t.Errorf("%T: got %v. want %v", n, d.Render(), n.Diff(jsonNull{}).Render())
+		}
+		if _, err := n.PatchContext(ctx, Diff{}); err != nil {
+			t.Errorf("%T: wanted no error. got %v", n, err)
+		}
+	}
+}
+
+func TestPatchContext(t *testing.T) {
+	a := func() JsonNode { return mustParse(t, `{"a":[1,2],"b":1}`) }
+	b := mustParse(t, `{"a":[1,3],"b":2}`)
+	d := a().Diff(b)
+	got, err := a().PatchContext(WithLimits(context.Background(), Limits{MaxNodes: 5}), d)
+	if err != nil {
+		t.Fatalf("wanted no error. got %v", err)
+	}
+	if !got.Equals(b) {
+		t.Errorf("got %v. want %v", got.Json(), b.Json())
+	}
+	_, err = a().PatchContext(WithLimits(context.Background(), Limits{MaxDepth: 1}), d)
+	var limitErr *LimitError
+	if !errors.As(err, &limitErr) || limitErr.Limit != "MaxDepth" {
+		t.Errorf("wanted MaxDepth exceeded. got %v", err)
+	}
+	if _, err := a().PatchContext(&cancelAfter{context.Background(), 1}, d); !errors.Is(err, context.Canceled) {
+		t.Errorf("wanted context.Canceled. got %v", err)
+	}
+}
+
+func TestLimitError(t *testing.T) {
+	err := &LimitError{Limit: "MaxNodes", Max: 10}
+	if got, want := err.Error(), "exceeded MaxNodes of 10"; got != want {
+		t.Errorf("got %q. want %q", got, want)
+	}
+}
+
+func mustParse(t *testing.T, s string) JsonNode {
+	t.Helper()
+	n, err := ReadJsonString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
@@ -1,6 +1,7 @@
 package jd
 
 import (
+	"context"
 	"fmt"
 )
 
@@ -21,6 +22,7 @@ func (l jsonList) raw() interface{} {
 }
 
 func (l1 jsonList) Equals(n JsonNode, opts ...Option) bool {
+	opts = InferKeys(l1, n, opts...)
 	o := refine(&options{retain: opts}, nil)
 	return l1.equals(n, o)
 }
@@ -36,7 +38,7 @@ func (l1 jsonList) equals(n JsonNode, o *options) bool {
 	}
 	for i, v1 := range l1 {
 		v2 := l2[i]
-		if !v1.equals(v2, o) {
+		if !v1.equals(v2, refine(o, PathIndex(i))) {
 			return false
 		}
 	}
@@ -53,6 +55,7 @@ func (l jsonList) hashCode(opts *options) [8]byte {
 }
 
 func (l jsonList) Diff(n JsonNode, opts ...Option) Diff {
+	opts = InferKeys(l, n, opts...)
 	o := newOptions(opts)
 	return l.diff(n, make(Path, 0), o, getPatchStrategy(o))
 }
@@ -79,6 +82,9 @@ func (a jsonList) diff(
 func (a jsonList) diffEventDriven(b jsonList, path Path, opts *options, strategy patchStrategy) Diff {
 	// Step 1: Generate diff events using LCS analysis
 	events := generateListdiffEvents(a, b, opts)
+	if o, ok := getOption[similarityOption](opts); ok {
+		events = alignSimilar(a, b, events, o.threshold, opts)
+	}
 
 	// Step 2: Create processor with path structure that matches original diffRest
 	// The original algorithm used append(path, PathIndex(0)), so we need:
@@ -148,6 +154,9 @@ func sameContainerType(n1, n2 JsonNode, opts *options) bool {
 		if _, ok := c2.(jsonMultiset); ok {
 			return true
 		}
+	case jsonString:
+		_, _, _, ok := embeddedPair(c1, c2, opts)
+		return ok
 	default:
 		return false
 	}
@@ -158,6 +167,14 @@ func (l jsonList) Patch(d Diff) (JsonNode, error) {
 	return patchAll(l, d)
 }
 
+func (l jsonList) DiffContext(ctx context.Context, n JsonNode, opts ...Option) (Diff, error) {
+	return diffContext(ctx, l, n, opts)
+}
+
+func (l jsonList) PatchContext(ctx context.Context, d Diff) (JsonNode, error) {
+	return patchContext(ctx, l, d)
+}
+
 func (l jsonList) patch(pathBehind, pathAhead Path, before, removeValues, addValues, after []JsonNode, strategy patchStrategy) (JsonNode, error) {
 
 	if strategy == mergePatchStrategy {
@@ -173,7 +190,7 @@ func (l jsonList) patch(pathBehind, pathAhead Path, before, removeValues, addVal
 			return nil, fmt.Errorf("invalid diff. must declare list to replace it")
 		}
 		if len(removeValues) > 0 && !l.Equals(removeValues[0]) {
-			return nil, fmt.Errorf("wanted %v. found %v", removeValues[0], l)
+			return patchErrExpectValue(removeValues[0], l, pathBehind)
 		}
 		if len(addValues) == 0 {
 			return voidNode{}, nil
@@ -191,7 +208,7 @@ func (l jsonList) patch(pathBehind, pathAhead Path, before, removeValues, addVal
 	// Recursive case
 	if len(rest) > 0 {
 		if int(i) > len(l)-1 {
-			return nil, fmt.Errorf("patch index out of bounds: %v", i)
+			return nil, &PathNotFoundError{Path: append(pathBehind.clone(), i)}
 		}
 		patchedNode, err := l[i].patch(append(pathBehind, n), rest, nil, removeValues, addValues, nil, strategy)
 		if err != nil {
@@ -218,22 +235,21 @@ func (l jsonList) patch(pathBehind, pathAhead Path, before, removeValues, addVal
 			if bIndex == -1 && isVoid(b) {
 				continue
 			}
-			return nil, fmt.Errorf("invalid patch. before context %v out of bounds: %v", b, bIndex)
-		case bIndex >= len(l) || !b.Equals(l[bIndex]):
-			if bIndex >= len(l) {
-				return nil, fmt.Errorf("invalid patch. before context %v index %v out of bounds (list length %v)", b, bIndex, len(l))
-			}
-			return nil, fmt.Errorf("invalid patch. expected %v before. got %v", b, l[bIndex])
+			return nil, &PathNotFoundError{Path: append(pathBehind.clone(), PathIndex(bIndex))}
+		case bIndex >= len(l):
+			return nil, &PathNotFoundError{Path: append(pathBehind.clone(), PathIndex(bIndex))}
+		case !b.Equals(l[bIndex]):
+			return patchErrExpectValue(b, l[bIndex], append(pathBehind, PathIndex(bIndex)))
 		}
 	}
 
 	// Patch list
 	for len(removeValues) > 0 {
 		if int(i) > len(l)-1 {
-			return nil, fmt.Errorf("remove values out bounds: %v", i)
+			return nil, &PathNotFoundError{Path: append(pathBehind.clone(), i)}
 		}
 		if !l[i].Equals(removeValues[0]) {
-			return nil, fmt.Errorf("invalid patch. wanted %v. found %v", removeValues[0], l[i])
+			return patchErrExpectValue(removeValues[0], l[i], append(pathBehind, i))
 		}
 		l = append(l[:i], l[i+1:]...)
 		removeValues = removeValues[1:]
@@ -254,10 +270,10 @@ func (l jsonList) patch(pathBehind, pathAhead Path, before, removeValues, addVal
 			if aIndex == len(l) && isVoid(a) {
 				continue
 			}
-			return nil, fmt.Errorf("invalid patch. after context %v out of bounds: %v", a, aIndex)
+			return nil, &PathNotFoundError{Path: append(pathBehind.clone(), PathIndex(aIndex))}
 		}
 		if !a.Equals(l[aIndex]) {
-			return nil, fmt.Errorf("invalid patch. expected %v after. got %v", a, l[aIndex])
+			return patchErrExpectValue(a, l[aIndex], append(pathBehind, PathIndex(aIndex)))
 		}
 	}
 
@@ -309,6 +325,10 @@ type listDiffProcessor struct {
 	// Context tracking
 	lastProcessedElement JsonNode
 	nextAfterElement     JsonNode // The element that should appear as After context
+	// lastContainer is the container diff of lastProcessedElement, if it
+	// was one. Patching it leaves values of A which are only equal to
+	// B under the options, so the Before context is A patched.
+	lastContainer *containerPatch
 
 	// Debug info
 	debug bool
@@ -346,6 +366,7 @@ func (p *listDiffProcessor) ProcessEvents(events []diffEvent) Diff {
 
 	for i, event := range events {
 		p.debugLog("Processing event %d: %s", i, event.String())
+		p.opts.budget.step()
 		p.processEvent(event)
 	}
 
@@ -387,6 +408,7 @@ func (p *listDiffProcessor) processmatchEvent(event matchEvent) {
 	p.pathCalc.AdvanceForMatch()
 	p.contextMgr.SetCursors(event.AIndex+1, event.BIndex+1) // Move past the match
 	p.lastProcessedElement = event.Element                  // Track the matched element
+	p.lastContainer = nil
 	p.state = listIdle
 }
 
@@ -419,6 +441,7 @@ func (p *listDiffProcessor) processcontainerDiffEvent(event containerDiffEvent)
 	p.pathCalc.AdvanceForContainer()
 	p.contextMgr.SetCursors(event.AIndex+1, event.BIndex+1) // Move past the container
 	p.lastProcessedElement = event.BElement                 // After container diff, the B element is what remains
+	p.lastContainer = &containerPatch{a: event.AElement, path: subPath, diff: subDiff}
 	p.state = listIdle
 }
 
@@ -489,6 +512,9 @@ func (p *listDiffProcessor) startAccumulating() {
 
 	// Use the last processed element as Before context
 	beforeContext := []JsonNode{p.lastProcessedElement}
+	if p.lastContainer != nil {
+		beforeContext = []JsonNode{p.lastContainer.patched()}
+	}
 
 	p.currentDiff = DiffElement{
 		Path:   p.pathCalc.CurrentPath(),
@@ -523,6 +549,29 @@ func (p *listDiffProcessor) finalize() {
 	}
 }
 
+// containerPatch is an element of A and the hunks at path which diff
+// it from its element of B.
+type containerPatch struct {
+	a    JsonNode
+	path Path
+	diff Diff
+}
+
+// patched returns a copy of the element of A with its hunks applied,
+// which is how the element reads when the hunks after it apply.
+func (c *containerPatch) patched() JsonNode {
+	d := make(Diff, len(c.diff))
+	for i, de := range c.diff {
+		de.Path = de.Path[len(c.path):]
+		d[i] = de
+	}
+	n, err := cloneNode(c.a).Patch(d)
+	if err != nil { //jd:nocover — the hunks were diffed from the element
+		return c.a
+	}
+	return n
+}
+
 // pathCalculator handles path calculations and advancement during diff processing
 type pathCalculator struct {
 	basePath  Path
@@ -753,6 +802,7 @@ func myersDiff(a, b jsonList, opts *options) []myersEdit {
 	var trace [][]int
 
 	for D := 0; D <= MAX; D++ {
+		opts.budget.cells((D + 1) * len(V))
 		// Copy current V for trace
 		currentV := make([]int, len(V))
 		copy(currentV, V)
@@ -891,7 +941,10 @@ func generateListdiffEvents(a, b jsonList, opts *options) []diffEvent {
 
 	// Use LCS to find matches with options awareness
 	lcsResult := newLcsWithOptions(a, b, opts)
-	matches := lcsResult.IndexPairs()
+	matches, err := lcsResult.IndexPairsContext(opts.budget.context())
+	if err != nil {
+		panic(abort{err})
+	}
 
 	// Build events by walking through both arrays
 	var events []diffEvent
@@ -907,7 +960,7 @@ func generateListdiffEvents(a, b jsonList, opts *options) []diffEvent {
 			// We have a match - but first check if it's a container that needs diffing
 			// Refine options for this specific list index
 			refinedOpts := refine(opts, PathIndex(aIndex))
-			if sameContainerType(a[aIndex], b[bIndex], opts) &&
+			if sameContainerType(a[aIndex], b[bIndex], refinedOpts) &&
 				!a[aIndex].equals(b[bIndex], refinedOpts) {
 				// Compatible containers with differences
 				events = append(events, containerDiffEvent{
@@ -939,7 +992,7 @@ func generateListdiffEvents(a, b jsonList, opts *options) []diffEvent {
 			// Check if we should try container diffing even without LCS match
 			if aIndex < len(a) && bIndex < len(b) &&
 				aIndex < nextMatchA && bIndex < nextMatchB &&
-				sameContainerType(a[aIndex], b[bIndex], opts) {
+				sameContainerType(a[aIndex], b[bIndex], refine(opts, PathIndex(aIndex))) {
 				// Compatible containers - try container diff
 				events = append(events, containerDiffEvent{
 					AIndex:   aIndex,
@@ -0,0 +1,49 @@
+package jd
+
+// matchEqual pairs as many elements of a with elements of b as it can
+// such that equal holds for each pair. It returns the index in b paired
+// with each element of a, or -1. Earlier elements of a are preferred
+// when pairings are otherwise equally large.
+func matchEqual(a, b []JsonNode, equal func(x, y JsonNode) bool) []int {
+	candidates := make([][]int, len(a))
+	for i, x := range a {
+		for j, y := range b {
+			if equal(x, y) {
+				candidates[i] = append(candidates[i], j)
+			}
+		}
+	}
+	pairedB := make([]int, len(b))
+	for j := range pairedB {
+		pairedB[j] = -1
+	}
+	// augment looks for a path of alternating pairings from a[i] to an
+	// unpaired element of b and flips it (Kuhn's algorithm).
+	var augment func(i int, seen []bool) bool
+	augment = func(i int, seen []bool) bool {
+		for _, j := range candidates[i] {
+			if seen[j] {
+				continue
+			}
+			seen[j] = true
+			if pairedB[j] < 0 || augment(pairedB[j], seen) {
+				pairedB[j] = i
+				return true
+			}
+		}
+		return false
+	}
+	for i := range a {
+		augment(i, make([]bool, len(b)))
+	}
+	pairedA := make([]int, len(a))
+	for i := range pairedA {
+		pairedA[i] = -1
+	}
+	for j, i := range pairedB {
+		if i >= 0 {
+			pairedA[i] = j
+		}
+	}
+	return pairedA
+}
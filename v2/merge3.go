@@ -0,0 +1,300 @@
+package jd
+
+import "sort"
+
+// Conflict is a location where ours and theirs both changed the base
+// in incompatible ways. Values are lists so that conflicting runs of
+// list elements can be reported together. An empty list means the
+// value is absent on that side.
+type Conflict struct {
+
+	// Path locates the conflict in the merged JsonNode.
+	Path Path
+
+	// Base are the values in the common ancestor.
+	Base []JsonNode
+
+	// Ours are the values kept in the merged JsonNode.
+	Ours []JsonNode
+
+	// Theirs are the values which could not be merged.
+	Theirs []JsonNode
+}
+
+// Conflicts are the unresolved Conflicts of a three-way merge.
+type Conflicts []Conflict
+
+// Diff returns hunks which replace our side of each Conflict with
+// theirs when applied to the merged JsonNode.
+func (cs Conflicts) Diff() Diff {
+	d := Diff{}
+	for _, c := range cs {
+		path := c.Path.clone()
+		if len(path) > 0 {
+			if _, ok := path[len(path)-1].(PathSetKeys); ok {
+				// Whole set members are addressed by value.
+				path[len(path)-1] = PathSet{}
+			}
+		}
+		d = append(d, DiffElement{
+			Path:   path,
+			Remove: append([]JsonNode{}, c.Ours...),
+			Add:    append([]JsonNode{}, c.Theirs...),
+		})
+	}
+	return d
+}
+
+// Merge3 performs a structural three-way merge of ours and theirs
+// against their common ancestor base. Changes made on only one side
+// are applied automatically. Objects are merged key by key, Lists by
+// aligning both sides to the base with an LCS, and Sets and Multisets
+// by membership, as selected by the same Options given to Diff. Where
+// both sides changed the same location differently our value is kept
+// and a Conflict is reported.
+func Merge3(base, ours, theirs JsonNode, opts ...Option) (JsonNode, Conflicts) {
+	o := newOptions(opts)
+	o.apply = refine(o, nil).apply
+	m := &merger{conflicts: Conflicts{}}
+	n := m.merge(base, ours, theirs, make(Path, 0), o)
+	return n, m.conflicts
+}
+
+type merger struct {
+	conflicts Conflicts
+}
+
+func (m *merger) conflict(path Path, base, ours, theirs []JsonNode) {
+	m.conflicts = append(m.conflicts, Conflict{
+		Path:   path.clone(),
+		Base:   base,
+		Ours:   ours,
+		Theirs: theirs,
+	})
+}
+
+func (m *merger) merge(base, ours, theirs JsonNode, path Path, opts *options) JsonNode {
+	switch {
+	case ours.equals(theirs, opts):
+		return ours
+	case base.equals(ours, opts):
+		return theirs
+	case base.equals(theirs, opts):
+		return ours
+	case !opts.diffingOn:
+		return ours
+	}
+	b := dispatch(base, opts)
+	switch o := dispatch(ours, opts).(type) {
+	case jsonObject:
+		bo, bOk := b.(jsonObject)
+		to, tOk := dispatch(theirs, opts).(jsonObject)
+		if bOk && tOk {
+			return m.mergeObject(bo, o, to, path, opts)
+		}
+	case jsonList:
+		bl, bOk := b.(jsonList)
+		tl, tOk := dispatch(theirs, opts).(jsonList)
+		if bOk && tOk {
+			return m.mergeList(bl, o, tl, path, opts)
+		}
+	case jsonSet:
+		bs, bOk := b.(jsonSet)
+		ts, tOk := dispatch(theirs, opts).(jsonSet)
+		if bOk && tOk {
+			return m.mergeSet(bs, o, ts, path, opts)
+		}
+	case jsonMultiset:
+		bm, bOk := b.(jsonMultiset)
+		tm, tOk := dispatch(theirs, opts).(jsonMultiset)
+		if bOk && tOk {
+			return mergeMultiset(bm, o, tm, opts)
+		}
+	}
+	m.conflict(path, nodeList(base), nodeList(ours), nodeList(theirs))
+	return ours
+}
+
+func (m *merger) mergeObject(base, ours, theirs jsonObject, path Path, opts *options) JsonNode {
+	keys := map[string]bool{}
+	for _, o := range []jsonObject{base, ours, theirs} {
+		for k := range o {
+			keys[k] = true
+		}
+	}
+	sortedKeys := make([]string, 0, len(keys))
+	for k := range keys {
+		sortedKeys = append(sortedKeys, k)
+	}
+	sort.Strings(sortedKeys)
+	merged := newJsonObject()
+	for _, k := range sortedKeys {
+		v := m.merge(
+			objectValue(base, k),
+			objectValue(ours, k),
+			objectValue(theirs, k),
+			append(path.clone(), PathKey(k)),
+			refine(opts, PathKey(k)),
+		)
+		if !isVoid(v) {
+			merged[k] = v
+		}
+	}
+	return merged
+}
+
+func objectValue(o jsonObject, k string) JsonNode {
+	if v, ok := o[k]; ok {
+		return v
+	}
+	return voidNode{}
+}
+
+// mergeList is a diff3 merge. Base elements which both sides kept
+// are stable anchors. The chunks between anchors are taken from
+// whichever side changed them, merged element-wise when all three
+// are the same length, and reported as a Conflict otherwise.
+func (m *merger) mergeList(base, ours, theirs jsonList, path Path, opts *options) JsonNode {
+	toOurs := map[int]int{}
+	for _, p := range newLcsWithOptions(base, ours, opts).IndexPairs() {
+		toOurs[p.Left] = p.Right
+	}
+	toTheirs := map[int]int{}
+	for _, p := range newLcsWithOptions(base, theirs, opts).IndexPairs() {
+		toTheirs[p.Left] = p.Right
+	}
+	merged := jsonArray{}
+	var b, o, t int
+	for i := 0; i <= len(base); i++ {
+		oi, oOk := toOurs[i]
+		ti, tOk := toTheirs[i]
+		if i < len(base) && !(oOk && tOk) {
+			continue
+		}
+		if i == len(base) {
+			oi, ti = len(ours), len(theirs)
+		}
+		merged = m.mergeChunk(merged, base[b:i], ours[o:oi], theirs[t:ti], path, opts)
+		if i < len(base) {
+			merged = append(merged, ours[oi])
+		}
+		b, o, t = i+1, oi+1, ti+1
+	}
+	return merged
+}
+
+func (m *merger) mergeChunk(merged jsonArray, base, ours, theirs jsonList, path Path, opts *options) jsonArray {
+	switch {
+	case base.equals(ours, opts):
+		return append(merged, theirs...)
+	case base.equals(theirs, opts) || ours.equals(theirs, opts):
+		return append(merged, ours...)
+	case len(base) == len(ours) && len(ours) == len(theirs):
+		for i := range base {
+			index := PathIndex(len(merged))
+			merged = append(merged, m.merge(
+				base[i], ours[i], theirs[i],
+				append(path.clone(), index),
+				refine(opts, index),
+			))
+		}
+		return merged
+	}
+	m.conflict(
+		append(path.clone(), PathIndex(len(merged))),
+		[]JsonNode(base), []JsonNode(ours), []JsonNode(theirs),
+	)
+	return append(merged, ours...)
+}
+
+// mergeSet keeps members present on both sides plus members added on
+// either side. Objects with the same identity (see SetKeys) are
+// merged recursively.
+func (m *merger) mergeSet(base, ours, theirs jsonSet, path Path, opts *options) JsonNode {
+	ident := func(s jsonSet) map[[8]byte]JsonNode {
+		members := map[[8]byte]JsonNode{}
+		for _, v := range s {
+			if o, ok := v.(jsonObject); ok {
+				members[o.ident(opts)] = v
+			} else {
+				members[v.hashCode(opts)] = v
+			}
+		}
+		return members
+	}
+	bm, om, tm := ident(base), ident(ours), ident(theirs)
+	hashes := hashCodes{}
+	for _, members := range []map[[8]byte]JsonNode{bm, om, tm} {
+		for hc := range members {
+			hashes = append(hashes, hc)
+		}
+	}
+	sort.Sort(hashes)
+	merged := jsonArray{}
+	for i, hc := range hashes {
+		if i > 0 && hashes[i-1] == hc {
+			continue
+		}
+		b, o, t := setMember(bm, hc), setMember(om, hc), setMember(tm, hc)
+		memberPath := path.clone()
+		if obj, ok := firstObject(b, o, t); ok {
+			memberPath = append(memberPath, newPathSetKeys(obj, opts))
+		} else {
+			memberPath = append(memberPath, PathSet{})
+		}
+		v := m.merge(b, o, t, memberPath, opts)
+		if !isVoid(v) {
+			merged = append(merged, v)
+		}
+	}
+	return merged
+}
+
+func setMember(members map[[8]byte]JsonNode, hc [8]byte) JsonNode {
+	if v, ok := members[hc]; ok {
+		return v
+	}
+	return voidNode{}
+}
+
+func firstObject(n ...JsonNode) (jsonObject, bool) {
+	for _, n := range n {
+		if o, ok := n.(jsonObject); ok {
+			return o, true
+		}
+	}
+	return nil, false
+}
+
+// mergeMultiset applies both sides' changes in member counts to the
+// base. The same change on both sides is applied once. Counts never
+// conflict.
+func mergeMultiset(base, ours, theirs jsonMultiset, opts *options) JsonNode {
+	count := func(ms jsonMultiset, members map[[8]byte]JsonNode) map[[8]byte]int {
+		counts := map[[8]byte]int{}
+		for _, v := range ms {
+			hc := v.hashCode(opts)
+			counts[hc]++
+			members[hc] = v
+		}
+		return counts
+	}
+	members := map[[8]byte]JsonNode{}
+	bc, oc, tc := count(base, members), count(ours, members), count(theirs, members)
+	hashes := make(hashCodes, 0, len(members))
+	for hc := range members {
+		hashes = append(hashes, hc)
+	}
+	sort.Sort(hashes)
+	merged := jsonArray{}
+	for _, hc := range hashes {
+		n := oc[hc]
+		if dt := tc[hc] - bc[hc]; dt != oc[hc]-bc[hc] {
+			n += dt
+		}
+		for i := 0; i < n; i++ {
+			merged = append(merged, members[hc])
+		}
+	}
+	return merged
+}
@@ -0,0 +1,191 @@
+package jd
+
+import (
+	"testing"
+)
+
+func TestMerge3(t *testing.T) {
+	cases := []struct {
+		name      string
+		options   []Option
+		base      string
+		ours      string
+		theirs    string
+		want      string
+		conflicts []string
+	}{{
+		name:   "no changes",
+		base:   `{"a":1}`,
+		ours:   `{"a":1}`,
+		theirs: `{"a":1}`,
+		want:   `{"a":1}`,
+	}, {
+		name:   "only ours changed",
+		base:   `{"a":1}`,
+		ours:   `{"a":2}`,
+		theirs: `{"a":1}`,
+		want:   `{"a":2}`,
+	}, {
+		name:   "only theirs changed",
+		base:   `{"a":1}`,
+		ours:   `{"a":1}`,
+		theirs: `{"a":2}`,
+		want:   `{"a":2}`,
+	}, {
+		name:   "same change on both sides",
+		base:   `{"a":1}`,
+		ours:   `{"a":2}`,
+		theirs: `{"a":2}`,
+		want:   `{"a":2}`,
+	}, {
+		name:   "different keys",
+		base:   `{"a":1,"b":1,"c":1}`,
+		ours:   `{"a":2,"b":1}`,
+		theirs: `{"a":1,"b":2,"c":1,"d":1}`,
+		want:   `{"a":2,"b":2,"d":1}`,
+	}, {
+		name:   "nested objects",
+		base:   `{"a":{"b":1,"c":1}}`,
+		ours:   `{"a":{"b":2,"c":1}}`,
+		theirs: `{"a":{"b":1,"c":2}}`,
+		want:   `{"a":{"b":2,"c":2}}`,
+	}, {
+		name:      "conflicting values",
+		base:      `{"a":1}`,
+		ours:      `{"a":2}`,
+		theirs:    `{"a":3}`,
+		want:      `{"a":2}`,
+		conflicts: ss(`@ ["a"]`, `- 2`, `+ 3`),
+	}, {
+		name:      "modified and deleted",
+		base:      `{"a":1}`,
+		ours:      `{}`,
+		theirs:    `{"a":3}`,
+		want:      `{}`,
+		conflicts: ss(`@ ["a"]`, `+ 3`),
+	}, {
+		name:      "conflicting types",
+		base:      `{"a":{}}`,
+		ours:      `{"a":{"b":1}}`,
+		theirs:    `{"a":[]}`,
+		want:      `{"a":{"b":1}}`,
+		conflicts: ss(`@ ["a"]`, `- {"b":1}`, `+ []`),
+	}, {
+		name:   "list insertions in different places",
+		base:   `[1,2,3]`,
+		ours:   `[0,1,2,3]`,
+		theirs: `[1,2,3,4]`,
+		want:   `[0,1,2,3,4]`,
+	}, {
+		name:   "list removal and insertion",
+		base:   `[1,2,3,4]`,
+		ours:   `[1,3,4]`,
+		theirs: `[1,2,3,4,5]`,
+		want:   `[1,3,4,5]`,
+	}, {
+		name:   "list elements merged in place",
+		base:   `[{"a":1,"b":1}]`,
+		ours:   `[{"a":2,"b":1}]`,
+		theirs: `[{"a":1,"b":2}]`,
+		want:   `[{"a":2,"b":2}]`,
+	}, {
+		name:      "list insertions in the same place",
+		base:      `[1,3]`,
+		ours:      `[1,2,3]`,
+		theirs:    `[1,4,5,3]`,
+		want:      `[1,2,3]`,
+		conflicts: ss(`@ [1]`, `- 2`, `+ 4`, `+ 5`),
+	}, {
+		name:    "sets",
+		base:    `[1,2,3]`,
+		ours:    `[3,2,4]`,
+		theirs:  `[1,2,3,5]`,
+		options: m(SET),
+		want:    `[2,3,4,5]`,
+	}, {
+		name:    "sets of objects by keys",
+		options: m(SetKeys("id")),
+		base:    `[{"id":1,"a":1,"b":1},{"id":2}]`,
+		ours:    `[{"id":2},{"id":1,"a":2,"b":1}]`,
+		theirs:  `[{"id":1,"a":1,"b":2},{"id":2},{"id":3}]`,
+		want:    `[{"id":1,"a":2,"b":2},{"id":2},{"id":3}]`,
+	}, {
+		name:      "sets of objects conflict",
+		options:   m(SetKeys("id")),
+		base:      `[{"id":1,"a":1}]`,
+		ours:      `[]`,
+		theirs:    `[{"id":1,"a":2}]`,
+		want:      `[]`,
+		conflicts: ss(`@ [{}]`, `+ {"a":2,"id":1}`),
+	}, {
+		name:    "multisets",
+		options: m(MULTISET),
+		base:    `[1,1,2]`,
+		ours:    `[1,1,1,2]`,
+		theirs:  `[1,2,3]`,
+		want:    `[1,1,2,3]`,
+	}, {
+		name:    "multisets with the same change",
+		options: m(MULTISET),
+		base:    `[1,1,2]`,
+		ours:    `[1,2]`,
+		theirs:  `[1,2,3]`,
+		want:    `[1,2,3]`,
+	}, {
+		name:    "path options",
+		options: m(PathOption(Path{PathKey("tags")}, SET)),
+		base:    `{"tags":["a","b"],"list":[1]}`,
+		ours:    `{"tags":["b","a","c"],"list":[1]}`,
+		theirs:  `{"tags":["a"],"list":[1,2]}`,
+		want:    `{"tags":["a","c"],"list":[1,2]}`,
+	}, {
+		name:    "diff off keeps ours",
+		options: m(PathOption(Path{PathKey("a")}, DIFF_OFF)),
+		base:    `{"a":1}`,
+		ours:    `{"a":2}`,
+		theirs:  `{"a":3}`,
+		want:    `{"a":2}`,
+	}, {
+		name:    "precision",
+		options: m(Precision(0.1)),
+		base:    `{"a":1.0}`,
+		ours:    `{"a":1.05}`,
+		theirs:  `{"a":2}`,
+		want:    `{"a":2}`,
+	}}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			base, err := ReadJsonString(c.base)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ours, err := ReadJsonString(c.ours)
+			if err != nil {
+				t.Fatal(err)
+			}
+			theirs, err := ReadJsonString(c.theirs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, err := ReadJsonString(c.want)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, conflicts := Merge3(base, ours, theirs, c.options...)
+			if !got.Equals(want, c.options...) {
+				t.Errorf("Merge3 = %v. Want %v.", got.Json(), want.Json())
+			}
+			wantConflicts := ""
+			if len(c.conflicts) > 0 {
+				wantConflicts = s(c.conflicts...)
+			}
+			if gotConflicts := conflicts.Diff().Render(); gotConflicts != wantConflicts {
+				t.Errorf("Conflicts = %q. Want %q.", gotConflicts, wantConflicts)
+			}
+			// Resolving every conflict as theirs is a valid patch.
+			if _, err := got.Patch(conflicts.Diff()); err != nil {
+				t.Errorf("Patch(conflicts) error: %v", err)
+			}
+		})
+	}
+}
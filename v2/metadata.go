@@ -8,6 +8,17 @@ import (
 
 type Metadata struct {
 	Merge bool
+
+	// Unchecked hunks remove whatever value is found at the Path,
+	// which is an error when there is none, and add their one Add
+	// value. They are read from a JSON Patch "replace" or "remove"
+	// without a "test", or an "add" at an object key. Unlike Merge it
+	// is not inherited by the following hunks.
+	Unchecked bool `json:",omitempty"`
+
+	// Upsert lets an Unchecked hunk also add its value when there is
+	// none at the Path, like the "add" of an object member.
+	Upsert bool `json:",omitempty"`
 }
 
 func readMetadata(n JsonNode) (Metadata, error) {
@@ -24,6 +35,18 @@ func readMetadata(n JsonNode) (Metadata, error) {
 				return Metadata{}, fmt.Errorf("merge must be a boolean. got %T", v)
 			}
 			m.Merge = bool(b)
+		case "Unchecked":
+			b, ok := v.(jsonBool)
+			if !ok {
+				return Metadata{}, fmt.Errorf("unchecked must be a boolean. got %T", v)
+			}
+			m.Unchecked = bool(b)
+		case "Upsert":
+			b, ok := v.(jsonBool)
+			if !ok {
+				return Metadata{}, fmt.Errorf("upsert must be a boolean. got %T", v)
+			}
+			m.Upsert = bool(b)
 		default:
 			return m, fmt.Errorf("unknown metadata %v", k)
 		}
@@ -53,12 +76,22 @@ type metadataMerge struct {
 
 func (m metadataMerge) isMetadataField() {}
 
+type metadataUnchecked struct {
+	Unchecked bool
+	Upsert    bool `json:",omitempty"`
+}
+
+func (m metadataUnchecked) isMetadataField() {}
+
 func (m Metadata) Render() string {
 	b := bytes.NewBuffer(nil)
 	if m.Merge != false {
 		s := renderMetadataField(metadataMerge{Merge: m.Merge})
 		b.WriteString(s)
 	}
+	if m.Unchecked {
+		b.WriteString(renderMetadataField(metadataUnchecked{Unchecked: true, Upsert: m.Upsert}))
+	}
 	return b.String()
 }
 
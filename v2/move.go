@@ -0,0 +1,240 @@
+package jd
+
+import (
+	"fmt"
+	"sort"
+)
+
+// withMoves applies move and copy detection to a Diff of a when
+// requested by the DETECT_MOVES option.
+func withMoves(a JsonNode, d Diff, opts *options) Diff {
+	if !checkOption[detectMovesOption](opts) || getPatchStrategy(opts) != strictPatchStrategy {
+		return d
+	}
+	return detectMoves(a, d)
+}
+
+// detectMoves pairs hunks which remove a subtree with hunks which add
+// an identical subtree elsewhere and replaces each pair with a single
+// move. Remaining additions of a subtree which is left untouched
+// elsewhere in a become copies. Only objects and arrays at object keys
+// are relocated, so indices into lists never need adjusting.
+func detectMoves(a JsonNode, d Diff) Diff {
+	opts := newOptions(nil)
+	removed := map[[8]byte][]int{}
+	for i, de := range d {
+		if isRelocatable(de, de.Remove, de.Add) {
+			hc := de.Remove[0].hashCode(opts)
+			removed[hc] = append(removed[hc], i)
+		}
+	}
+	out := make(Diff, len(d))
+	copy(out, d)
+	dropped := map[int]bool{}
+	for j, de := range d {
+		if !isRelocatable(de, de.Add, de.Remove) {
+			continue
+		}
+		for _, i := range removed[de.Add[0].hashCode(opts)] {
+			if dropped[i] || !d[i].Remove[0].Equals(de.Add[0]) || !noListHunksBetween(d, i, j) {
+				continue
+			}
+			dropped[i] = true
+			out[j] = DiffElement{
+				Metadata: de.Metadata,
+				Path:     de.Path,
+				From:     d[i].Path,
+			}
+			break
+		}
+	}
+	kept := Diff{}
+	for i, de := range out {
+		if !dropped[i] {
+			kept = append(kept, de)
+		}
+	}
+	sources := map[[8]byte][]Path{}
+	indexSources(a, Path{}, opts, sources)
+	for j, de := range kept {
+		if de.From != nil || !isRelocatable(de, de.Add, de.Remove) {
+			continue
+		}
+		for _, from := range sources[de.Add[0].hashCode(opts)] {
+			v, err := getPath(a, from)
+			if err != nil || !v.Equals(de.Add[0]) || touches(kept, from) {
+				continue
+			}
+			kept[j] = DiffElement{
+				Metadata: de.Metadata,
+				Path:     de.Path,
+				From:     from,
+				Copy:     true,
+			}
+			break
+		}
+	}
+	return kept
+}
+
+// isRelocatable reports whether a hunk only puts (or only takes) a
+// non-empty object or array at an object key outside of any embedded
+// document.
+func isRelocatable(de DiffElement, values, others []JsonNode) bool {
+	if len(values) != 1 || len(others) != 0 || len(de.Before) != 0 || len(de.After) != 0 || len(de.Path) == 0 {
+		return false
+	}
+	if _, ok := de.Path[len(de.Path)-1].(PathKey); !ok {
+		return false
+	}
+	for _, e := range de.Path {
+		if _, ok := e.(PathEmbedded); ok {
+			// Values cannot be read out of a string.
+			return false
+		}
+	}
+	switch v := values[0].(type) {
+	case jsonObject:
+		return len(v) > 0
+	case jsonArray:
+		return len(v) > 0
+	}
+	return false
+}
+
+// noListHunksBetween reports whether the hunks strictly between i and
+// j leave all list indices unchanged.
+func noListHunksBetween(d Diff, i, j int) bool {
+	if i > j {
+		i, j = j, i
+	}
+	for _, de := range d[i+1 : j] {
+		if len(de.Path) == 0 { //jd:nocover — a root hunk is the only hunk of its Diff
+			return false
+		}
+		if _, ok := de.Path[len(de.Path)-1].(PathKey); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// indexSources records the paths of non-empty objects and arrays
+// reachable from n through object keys only, by hash code.
+func indexSources(n JsonNode, path Path, opts *options, sources map[[8]byte][]Path) {
+	o, ok := n.(jsonObject)
+	if !ok {
+		return
+	}
+	keys := make([]string, 0, len(o))
+	for k := range o {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		p := append(path.clone(), PathKey(k))
+		switch v := o[k].(type) {
+		case jsonObject:
+			if len(v) > 0 {
+				hc := v.hashCode(opts)
+				sources[hc] = append(sources[hc], p)
+			}
+			indexSources(v, p, opts, sources)
+		case jsonArray:
+			if len(v) > 0 {
+				hc := v.hashCode(opts)
+				sources[hc] = append(sources[hc], p)
+			}
+		}
+	}
+}
+
+// touches reports whether any hunk of d reads or writes at, above or
+// below path.
+func touches(d Diff, path Path) bool {
+	for _, de := range d {
+		if pathsOverlap(de.Path, path) || (de.From != nil && pathsOverlap(de.From, path)) {
+			return true
+		}
+	}
+	return false
+}
+
+func pathsOverlap(a, b Path) bool {
+	return hasPathPrefix(a, b) || hasPathPrefix(b, a)
+}
+
+func hasPathPrefix(p, prefix Path) bool {
+	if len(prefix) > len(p) {
+		return false
+	}
+	a := p.JsonNode().(jsonArray)
+	b := prefix.JsonNode().(jsonArray)
+	for i := range b {
+		if !a[i].Equals(b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// getPath returns the value found at path in n. Only object keys and
+// list indices are supported.
+func getPath(n JsonNode, path Path) (JsonNode, error) {
+	for i, e := range path {
+		switch e := e.(type) {
+		case PathKey:
+			o, ok := n.(jsonObject)
+			if !ok {
+				return nil, fmt.Errorf("found %v at %v: expected JSON object", n.Json(), path[:i])
+			}
+			v, ok := o[string(e)]
+			if !ok {
+				return nil, &PathNotFoundError{Path: path[:i+1].clone()}
+			}
+			n = v
+		case PathIndex:
+			var l []JsonNode
+			switch n := n.(type) {
+			case jsonArray:
+				l = n
+			case jsonList:
+				// Patching a list hunk leaves a jsonList.
+				l = n
+			default:
+				return nil, fmt.Errorf("found %v at %v: expected JSON array", n.Json(), path[:i])
+			}
+			if int(e) < 0 || int(e) >= len(l) {
+				return nil, &PathNotFoundError{Path: path[:i+1].clone()}
+			}
+			n = l[e]
+		default:
+			return nil, fmt.Errorf("unsupported path element %T in %v", e, path)
+		}
+	}
+	return n, nil
+}
+
+// patchFrom applies a move or copy hunk to n.
+func patchFrom(n JsonNode, de DiffElement) (JsonNode, error) {
+	v, err := getPath(n, de.From)
+	if err != nil {
+		return nil, err
+	}
+	if de.Copy {
+		// The copy must not share structure with its source.
+		v, err = NewJsonNode(v.raw())
+		if err != nil { //jd:nocover — raw values are always valid
+			return nil, err
+		}
+	} else {
+		if hasPathPrefix(de.Path, de.From) {
+			return nil, fmt.Errorf("cannot move %v into itself at %v", de.From, de.Path)
+		}
+		n, err = n.patch(make(Path, 0), de.From, nil, []JsonNode{v}, nil, nil, strictPatchStrategy)
+		if err != nil { //jd:nocover — the value was just found at From
+			return nil, err
+		}
+	}
+	return n.patch(make(Path, 0), de.Path, nil, nil, []JsonNode{v}, nil, strictPatchStrategy)
+}
@@ -0,0 +1,358 @@
+package jd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDetectMoves(t *testing.T) {
+	cases := []struct {
+		name    string
+		options []Option
+		a       string
+		b       string
+		want    []string
+		patch   string
+	}{{
+		name: "rename key",
+		a:    `{"a":{"x":[1,2,3]}}`,
+		b:    `{"b":{"x":[1,2,3]}}`,
+		want: ss(
+			`@ ["b"]`,
+			`< ["a"]`,
+		),
+		patch: `[{"op":"move","from":"/a","path":"/b"}]`,
+	}, {
+		name: "move into nested object",
+		a:    `{"a":[1,2],"b":{}}`,
+		b:    `{"b":{"c":[1,2]}}`,
+		want: ss(
+			`@ ["b","c"]`,
+			`< ["a"]`,
+		),
+		patch: `[{"op":"move","from":"/a","path":"/b/c"}]`,
+	}, {
+		name: "move after a list edit",
+		a:    `{"l":[1,2,{"m":{"x":1}}],"z":1}`,
+		b:    `{"l":[0,1,2,{}],"n":{"x":1},"z":1}`,
+		want: ss(
+			`@ ["l",0]`,
+			`[`,
+			`+ 0`,
+			`  1`,
+			`@ ["n"]`,
+			`< ["l",3,"m"]`,
+		),
+	}, {
+		name: "move out of list element",
+		a:    `{"a":[{"b":{"c":1}}],"d":1}`,
+		b:    `{"a":[{}],"d":1,"e":{"c":1}}`,
+		want: ss(
+			`@ ["e"]`,
+			`< ["a",0,"b"]`,
+		),
+		patch: `[{"op":"move","from":"/a/0/b","path":"/e"}]`,
+	}, {
+		name: "copy unchanged subtree",
+		a:    `{"a":{"x":1}}`,
+		b:    `{"a":{"x":1},"b":{"x":1}}`,
+		want: ss(
+			`@ ["b"]`,
+			`= ["a"]`,
+		),
+		patch: `[{"op":"copy","from":"/a","path":"/b"}]`,
+	}, {
+		name: "changed source is not copied",
+		a:    `{"a":{"x":1,"y":1}}`,
+		b:    `{"a":{"x":1},"b":{"x":1,"y":1}}`,
+		want: ss(
+			`@ ["a","y"]`,
+			`- 1`,
+			`@ ["b"]`,
+			`+ {"x":1,"y":1}`,
+		),
+	}, {
+		name: "scalars are not moved",
+		a:    `{"a":1}`,
+		b:    `{"b":1}`,
+		want: ss(
+			`@ ["a"]`,
+			`- 1`,
+			`@ ["b"]`,
+			`+ 1`,
+		),
+	}, {
+		name: "replaced values are not moved",
+		a:    `{"a":{"x":1},"b":2}`,
+		b:    `{"a":3,"b":{"x":1}}`,
+		want: ss(
+			`@ ["a"]`,
+			`- {"x":1}`,
+			`+ 3`,
+			`@ ["b"]`,
+			`- 2`,
+			`+ {"x":1}`,
+		),
+	}, {
+		name: "list hunk in between",
+		a:    `{"a":[{"b":{"x":1}},1],"c":{}}`,
+		b:    `{"a":[{}],"c":{"b":{"x":1}}}`,
+		want: ss(
+			`@ ["a",0,"b"]`,
+			`- {"x":1}`,
+			`@ ["a",1]`,
+			`  {}`,
+			`- 1`,
+			`]`,
+			`@ ["c","b"]`,
+			`+ {"x":1}`,
+		),
+	}, {
+		name:    "merge diffs are unchanged",
+		options: []Option{MERGE},
+		a:       `{"a":{"x":1}}`,
+		b:       `{"b":{"x":1}}`,
+		want: ss(
+			`^ {"Merge":true}`,
+			`@ ["a"]`,
+			`+`,
+			`^ {"Merge":true}`,
+			`@ ["b"]`,
+			`+ {"x":1}`,
+		),
+	}, {
+		name: "move to an earlier key",
+		a:    `{"z":{"x":1}}`,
+		b:    `{"a":{"x":1}}`,
+		want: ss(
+			`@ ["a"]`,
+			`< ["z"]`,
+		),
+		patch: `[{"op":"move","from":"/z","path":"/a"}]`,
+	}, {
+		name:    "set members are not moved",
+		options: []Option{SET},
+		a:       `{"a":[],"b":{"x":1}}`,
+		b:       `{"a":[{"x":1}]}`,
+		want: ss(
+			`@ ["a",{}]`,
+			`+ {"x":1}`,
+			`@ ["b"]`,
+			`- {"x":1}`,
+		),
+	}, {
+		name:    "embedded documents are not moved",
+		options: []Option{EMBEDDED},
+		a:       `{"a":{"x":1},"s":"{}"}`,
+		b:       `{"s":"{\"b\":{\"x\":1}}"}`,
+		want: ss(
+			`@ ["a"]`,
+			`- {"x":1}`,
+			`@ ["s",["json"],"b"]`,
+			`+ {"x":1}`,
+		),
+	}, {
+		name: "array root",
+		a:    `[{"a":{"x":1}}]`,
+		b:    `[{"b":{"x":1}}]`,
+		want: ss(
+			`@ [0,"b"]`,
+			`< [0,"a"]`,
+		),
+		patch: `[{"op":"move","from":"/0/a","path":"/0/b"}]`,
+	}}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			a, err := ReadJsonString(c.a)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := ReadJsonString(c.b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			d := a.Diff(b, append(c.options, DETECT_MOVES)...)
+			want := strings.Join(c.want, "\n") + "\n"
+			if got := d.Render(); got != want {
+				t.Fatalf("got diff:\n%v\nwant:\n%v", got, want)
+			}
+			if len(c.options) > 0 {
+				return
+			}
+			read, err := ReadDiffString(d.Render())
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := a.Patch(read)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.Equals(b) {
+				t.Errorf("got %v after patch. want %v", got.Json(), b.Json())
+			}
+			if c.patch == "" {
+				return
+			}
+			patch, err := d.RenderPatch()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if patch != c.patch {
+				t.Errorf("got patch %v. want %v", patch, c.patch)
+			}
+			read, err = ReadPatchString(patch)
+			if err != nil {
+				t.Fatal(err)
+			}
+			a, _ = ReadJsonString(c.a)
+			got, err = a.Patch(read)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.Equals(b) {
+				t.Errorf("got %v after JSON Patch. want %v", got.Json(), b.Json())
+			}
+		})
+	}
+}
+
+func TestPatchMoveAndCopy(t *testing.T) {
+	cases := []struct {
+		name  string
+		a     string
+		patch string
+		want  string
+		err   bool
+	}{{
+		name:  "move list element",
+		a:     `{"a":[1,{"b":2},3]}`,
+		patch: `[{"op":"move","from":"/a/1","path":"/a/0"}]`,
+		want:  `{"a":[{"b":2},1,3]}`,
+	}, {
+		name:  "move after a list edit",
+		a:     `{"a":[1,2]}`,
+		patch: `[{"op":"add","path":"/a/0","value":0},{"op":"move","from":"/a/1","path":"/b"}]`,
+		want:  `{"a":[0,2],"b":1}`,
+	}, {
+		name:  "copy is independent of source",
+		a:     `{"a":{"b":1}}`,
+		patch: `[{"op":"copy","from":"/a","path":"/c"},{"op":"test","path":"/c/b","value":1},{"op":"remove","path":"/c/b","value":1}]`,
+		want:  `{"a":{"b":1},"c":{}}`,
+	}, {
+		name:  "missing source",
+		a:     `{"a":1}`,
+		patch: `[{"op":"move","from":"/b","path":"/c"}]`,
+		err:   true,
+	}, {
+		name:  "source is not an object",
+		a:     `{"a":1}`,
+		patch: `[{"op":"copy","from":"/a/b","path":"/c"}]`,
+		err:   true,
+	}, {
+		name:  "source index out of bounds",
+		a:     `{"a":[1]}`,
+		patch: `[{"op":"copy","from":"/a/1","path":"/c"}]`,
+		err:   true,
+	}, {
+		name:  "source is not an array",
+		a:     `{"a":{}}`,
+		patch: `[{"op":"copy","from":"/a/0","path":"/c"}]`,
+		err:   true,
+	}, {
+		name:  "target exists",
+		a:     `{"a":{"b":1},"c":2}`,
+		patch: `[{"op":"move","from":"/a","path":"/c"}]`,
+		err:   true,
+	}}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			a, err := ReadJsonString(c.a)
+			if err != nil {
+				t.Fatal(err)
+			}
+			d, err := ReadPatchString(c.patch)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := a.Patch(d)
+			if c.err {
+				if err == nil {
+					t.Errorf("wanted error. got %v", got.Json())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Json() != c.want {
+				t.Errorf("got %v. want %v", got.Json(), c.want)
+			}
+		})
+	}
+}
+
+func TestReadMoveAndCopy(t *testing.T) {
+	d, err := ReadDiffString(s(
+		`@ ["b"]`,
+		`< ["a"]`,
+		`@ ["c"]`,
+		`= ["b"]`,
+		`^ "DETECT_MOVES"`,
+		`@ ["d"]`,
+		`= ["b","x"]`,
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(d) != 3 {
+		t.Fatalf("got %v hunks. want 3", len(d))
+	}
+	if d[0].Copy || !d[1].Copy || !d[2].Copy {
+		t.Errorf("got copy %v %v %v. want false true true", d[0].Copy, d[1].Copy, d[2].Copy)
+	}
+	a, _ := ReadJsonString(`{"a":{"x":[1]}}`)
+	got, err := a.Patch(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"b":{"x":[1]},"c":{"x":[1]},"d":[1]}`; got.Json() != want {
+		t.Errorf("got %v. want %v", got.Json(), want)
+	}
+
+	for _, bad := range []string{
+		s(`@ ["b"]`, `< ["a"]`, `- 1`),
+		s(`@ ["b"]`, `- 1`, `< ["a"]`),
+		s(`@ ["b"]`, `< "a"`),
+		s(`@ ["b"]`, `< {`),
+		s(`@ ["b"]`, `< null`),
+		s(`@ ["b"]`, `< []`),
+	} {
+		if _, err := ReadDiffString(bad); err == nil {
+			t.Errorf("wanted error reading %q", bad)
+		}
+	}
+}
+
+func TestReadPatchMoveErrors(t *testing.T) {
+	for _, bad := range []string{
+		`[{"op":"move","from":"/a","path":"/a/b"}]`,
+		`[{"op":"move","from":"a","path":"/b"}]`,
+		`[{"op":"copy","from":"/a","path":"b"}]`,
+	} {
+		if _, err := ReadPatchString(bad); err == nil {
+			t.Errorf("wanted error reading %v", bad)
+		}
+	}
+	a, _ := ReadJsonString(`{"a":{"b":1}}`)
+	_, err := a.Patch(Diff{{Path: Path{PathKey("a"), PathKey("c")}, From: Path{PathKey("a")}}})
+	if err == nil {
+		t.Errorf("wanted error moving a value into itself")
+	}
+	setFrom := Diff{{Path: Path{PathKey("c")}, From: Path{PathKey("a"), PathSet{}}}}
+	if _, err := a.Patch(setFrom); err == nil {
+		t.Errorf("wanted error moving from a set")
+	}
+	if _, err := setFrom.RenderPatch(); err == nil {
+		t.Errorf("wanted error rendering a move from a set as JSON Patch")
+	}
+}
@@ -1,6 +1,7 @@
 package jd
 
 import (
+	"context"
 	"fmt"
 	"sort"
 )
@@ -22,6 +23,7 @@ func (a jsonMultiset) raw() interface{} {
 }
 
 func (a1 jsonMultiset) Equals(n JsonNode, opts ...Option) bool {
+	opts = InferKeys(a1, n, opts...)
 	o := refine(&options{retain: opts}, nil)
 	return a1.equals(n, o)
 }
@@ -37,9 +39,20 @@ func (a1 jsonMultiset) equals(n JsonNode, o *options) bool {
 	}
 	if a1.hashCode(o) == a2.hashCode(o) {
 		return true
-	} else {
+	}
+	if !hasTolerance(o) {
 		return false
 	}
+	equal := func(x, y JsonNode) bool {
+		o.budget.step()
+		return x.equals(y, o)
+	}
+	for _, j := range matchEqual(a1, a2, equal) {
+		if j < 0 {
+			return false
+		}
+	}
+	return true
 }
 
 func (a jsonMultiset) hashCode(opts *options) [8]byte {
@@ -56,6 +69,7 @@ func (a jsonMultiset) hashCode(opts *options) [8]byte {
 }
 
 func (a jsonMultiset) Diff(n JsonNode, opts ...Option) Diff {
+	opts = InferKeys(a, n, opts...)
 	o := refine(newOptions(opts), nil)
 	return a.diff(n, nil, o, getPatchStrategy(o))
 }
@@ -91,6 +105,14 @@ func (a jsonMultiset) Patch(d Diff) (JsonNode, error) {
 	return patchAll(a, d)
 }
 
+func (a jsonMultiset) DiffContext(ctx context.Context, n JsonNode, opts ...Option) (Diff, error) {
+	return diffContext(ctx, a, n, opts)
+}
+
+func (a jsonMultiset) PatchContext(ctx context.Context, d Diff) (JsonNode, error) {
+	return patchContext(ctx, a, d)
+}
+
 func (a jsonMultiset) patch(pathBehind, pathAhead Path, before, oldValues, newValues, after []JsonNode, strategy patchStrategy) (JsonNode, error) {
 
 	// Merge patch strategy
@@ -119,42 +141,40 @@ func (a jsonMultiset) patch(pathBehind, pathAhead Path, before, oldValues, newVa
 		return nil, fmt.Errorf(
 			"invalid path element %v: expected map[string]interface{}", n)
 	}
-	aCounts := make(map[[8]byte]int)
-	aMap := make(map[[8]byte]JsonNode)
+	// Each instance is kept as it is rather than one for each hash. A
+	// removed value takes its exact instance if there is one.
+	aInstances := make(map[[8]byte][]JsonNode)
 	for _, v := range a {
 		hc := v.hashCode(o)
-		aCounts[hc]++
-		aMap[hc] = v
+		aInstances[hc] = append(aInstances[hc], v)
 	}
 	for _, v := range oldValues {
 		hc := v.hashCode(o)
-		aCounts[hc]--
-		aMap[hc] = v
-	}
-	for hc, count := range aCounts {
-		if count < 0 {
-			return nil, fmt.Errorf(
-				"invalid diff: expected %v at %v but found nothing",
-				aMap[hc].Json(), pathBehind)
+		instances := aInstances[hc]
+		if len(instances) == 0 {
+			return patchErrExpectValue(v, voidNode{}, pathBehind)
+		}
+		i := 0
+		for j, x := range instances {
+			if x.Equals(v) {
+				i = j
+				break
+			}
 		}
+		aInstances[hc] = append(instances[:i:i], instances[i+1:]...)
 	}
 	for _, v := range newValues {
 		hc := v.hashCode(o)
-		aCounts[hc]++
-		aMap[hc] = v
+		aInstances[hc] = append(aInstances[hc], v)
 	}
 	aHashes := make(hashCodes, 0)
-	for hc := range aCounts {
-		if aCounts[hc] > 0 {
-			for i := 0; i < aCounts[hc]; i++ {
-				aHashes = append(aHashes, hc)
-			}
-		}
+	for hc := range aInstances {
+		aHashes = append(aHashes, hc)
 	}
 	sort.Sort(aHashes)
 	newValue := make(jsonMultiset, 0)
 	for _, hc := range aHashes {
-		newValue = append(newValue, aMap[hc])
+		newValue = append(newValue, aInstances[hc]...)
 	}
 	return newValue, nil
 }
@@ -196,6 +216,7 @@ func (p *multisetDiffProcessor) ProcessEvents(events []diffEvent) Diff {
 
 	for i, event := range events {
 		p.debugLog("Processing event %d: %s", i, event.String())
+		p.opts.budget.step()
 
 		switch e := event.(type) {
 		case multisetElementEvent:
@@ -265,71 +286,137 @@ func generateMultisetdiffEvents(a1, a2 jsonMultiset, opts *options) []diffEvent
 
 	var events []diffEvent
 
-	// Count elements in both multisets
-	a1Counts := make(map[[8]byte]int)
-	a1Map := make(map[[8]byte]JsonNode)
+	// Group the instances of both multisets by hash. Options such as
+	// IGNORE_CASE or COERCE give different instances one hash, so each
+	// instance removed or added is written as it is.
+	a1Instances := make(map[[8]byte][]JsonNode)
 	for _, v := range a1 {
 		hc := v.hashCode(opts)
-		a1Counts[hc]++
-		a1Map[hc] = v
+		a1Instances[hc] = append(a1Instances[hc], v)
 	}
-
-	a2Counts := make(map[[8]byte]int)
-	a2Map := make(map[[8]byte]JsonNode)
+	a2Instances := make(map[[8]byte][]JsonNode)
 	for _, v := range a2 {
 		hc := v.hashCode(opts)
-		a2Counts[hc]++
-		a2Map[hc] = v
+		a2Instances[hc] = append(a2Instances[hc], v)
 	}
 
 	// Get sorted hash codes for deterministic ordering (matches original implementation)
 	a1Hashes := make(hashCodes, 0)
-	for hc := range a1Counts {
+	for hc := range a1Instances {
 		a1Hashes = append(a1Hashes, hc)
 	}
 	sort.Sort(a1Hashes)
 
 	a2Hashes := make(hashCodes, 0)
-	for hc := range a2Counts {
+	for hc := range a2Instances {
 		a2Hashes = append(a2Hashes, hc)
 	}
 	sort.Sort(a2Hashes)
 
+	removed, removedHashes := surplusInstances(a1Hashes, a1Instances, a2Instances)
+	added, addedHashes := surplusInstances(a2Hashes, a2Instances, a1Instances)
+	removed, removedHashes, added, addedHashes = keepWithinTolerance(
+		removed, removedHashes, added, addedHashes, opts)
+
 	// Process removals first (sorted by hash)
-	for _, hc := range a1Hashes {
-		a1Count := a1Counts[hc]
-		a2Count, ok := a2Counts[hc]
-		if !ok {
-			a2Count = 0
-		}
-		removed := a1Count - a2Count
-		if removed > 0 {
-			events = append(events, multisetElementEvent{
-				Operation: "REMOVE",
-				Element:   a1Map[hc],
-				Count:     removed,
-				Hash:      hc,
-			})
-		}
+	for i, v := range removed {
+		events = append(events, multisetElementEvent{
+			Operation: "REMOVE",
+			Element:   v,
+			Count:     1,
+			Hash:      removedHashes[i],
+		})
 	}
 
 	// Process additions (sorted by hash)
-	for _, hc := range a2Hashes {
-		a2Count := a2Counts[hc]
-		a1Count, ok := a1Counts[hc]
-		if !ok {
-			a1Count = 0
+	for i, v := range added {
+		events = append(events, multisetElementEvent{
+			Operation: "ADD",
+			Element:   v,
+			Count:     1,
+			Hash:      addedHashes[i],
+		})
+	}
+
+	return events
+}
+
+// surplusInstances returns the instances of each hash which from has
+// more of than other, with their hashes. Instances with no exactly
+// equal instance in other go first, so those which are kept read the
+// same on both sides where they can.
+func surplusInstances(
+	hashes hashCodes,
+	from, other map[[8]byte][]JsonNode,
+) ([]JsonNode, hashCodes) {
+	var surplus []JsonNode
+	var surplusHashes hashCodes
+	for _, hc := range hashes {
+		instances := from[hc]
+		count := len(instances) - len(other[hc])
+		if count <= 0 {
+			continue
+		}
+		paired := make([]bool, len(instances))
+		for _, x := range other[hc] {
+			for i, y := range instances {
+				if !paired[i] && y.Equals(x) {
+					paired[i] = true
+					break
+				}
+			}
+		}
+		ordered := make([]JsonNode, 0, len(instances))
+		for i, v := range instances {
+			if !paired[i] {
+				ordered = append(ordered, v)
+			}
+		}
+		for i, v := range instances {
+			if paired[i] {
+				ordered = append(ordered, v)
+			}
 		}
-		added := a2Count - a1Count
-		if added > 0 {
-			events = append(events, multisetElementEvent{
-				Operation: "ADD",
-				Element:   a2Map[hc],
-				Count:     added,
-				Hash:      hc,
-			})
+		for _, v := range ordered[:count] {
+			surplus = append(surplus, v)
+			surplusHashes = append(surplusHashes, hc)
 		}
 	}
+	return surplus, surplusHashes
+}
 
-	return events
+// keepWithinTolerance drops the removed and added instances of a
+// multiset diff which are equal under a tolerance and so are kept.
+func keepWithinTolerance(
+	removed []JsonNode, removedHashes hashCodes,
+	added []JsonNode, addedHashes hashCodes,
+	opts *options,
+) ([]JsonNode, hashCodes, []JsonNode, hashCodes) {
+	if !hasTolerance(opts) {
+		return removed, removedHashes, added, addedHashes
+	}
+	equal := func(x, y JsonNode) bool {
+		opts.budget.step()
+		return x.equals(y, opts)
+	}
+	keptAdded := make([]bool, len(added))
+	var r []JsonNode
+	var rHashes hashCodes
+	for i, j := range matchEqual(removed, added, equal) {
+		if j >= 0 {
+			keptAdded[j] = true
+			continue
+		}
+		r = append(r, removed[i])
+		rHashes = append(rHashes, removedHashes[i])
+	}
+	var a []JsonNode
+	var aHashes hashCodes
+	for j, v := range added {
+		if !keptAdded[j] {
+			a = append(a, v)
+			aHashes = append(aHashes, addedHashes[j])
+		}
+	}
+	return r, rHashes, a, aHashes
 }
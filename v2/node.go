@@ -1,8 +1,10 @@
 package jd
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"strconv"
 )
 
 // JsonNode is a JSON value, collection of values, or a void representing
@@ -41,6 +43,19 @@ type JsonNode interface {
 	// embedded in the Diff itself.
 	Patch(d Diff) (JsonNode, error)
 
+	// DiffContext is Diff which stops when ctx is done, returning
+	// ctx.Err(), and which enforces the Limits set on ctx by
+	// WithLimits, returning a *LimitError. A diff out of budget is
+	// unwound by a panic private to jd which DiffContext recovers.
+	// Any other panic is passed on.
+	DiffContext(ctx context.Context, n JsonNode, options ...Option) (Diff, error)
+
+	// PatchContext is Patch which stops between hunks when ctx is done
+	// and which enforces the MaxDepth and MaxNodes Limits of ctx on
+	// the patched JsonNode. It returns these as errors without
+	// unwinding by panic.
+	PatchContext(ctx context.Context, d Diff) (JsonNode, error)
+
 	jsonNodeInternals
 }
 
@@ -110,17 +125,19 @@ func NewJsonNode(n interface{}) (JsonNode, error) {
 	case jsonArray:
 		return t, nil
 	case json.Number:
-		f, err := t.Float64()
-		if err != nil {
-			return nil, err
-		}
-		return jsonNumber(f), nil
+		return newNumber(string(t))
 	case float64:
 		return jsonNumber(t), nil
 	case int:
-		return jsonNumber(t), nil
+		return newNumber(strconv.Itoa(t))
+	case int64:
+		return newNumber(strconv.FormatInt(t, 10))
+	case uint64:
+		return newNumber(strconv.FormatUint(t, 10))
 	case jsonNumber:
 		return t, nil
+	case jsonDecimal:
+		return t, nil
 	case string:
 		return jsonString(t), nil
 	case jsonString:
@@ -1,7 +1,10 @@
 package jd
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
+	"io"
 	"os"
 	"strings"
 
@@ -14,7 +17,7 @@ func ReadJsonFile(filename string) (JsonNode, error) {
 	if err != nil {
 		return nil, err
 	}
-	return unmarshal(bytes, json.Unmarshal)
+	return unmarshal(bytes, unmarshalJson)
 }
 
 // ReadYamlFile reads a file as YAML and constructs a JsonNode.
@@ -28,7 +31,7 @@ func ReadYamlFile(filename string) (JsonNode, error) {
 
 // ReadJsonString reads a string as JSON and constructs a JsonNode.
 func ReadJsonString(s string) (JsonNode, error) {
-	return unmarshal([]byte(s), json.Unmarshal)
+	return unmarshal([]byte(s), unmarshalJson)
 }
 
 // ReadJsonString reads a string as YAML and constructs a JsonNode.
@@ -51,3 +54,17 @@ func unmarshal(bytes []byte, fn func([]byte, interface{}) error) (JsonNode, erro
 	}
 	return n, nil
 }
+
+// unmarshalJson is json.Unmarshal with numbers decoded as json.Number
+// so that none of their digits are lost.
+func unmarshalJson(data []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return fmt.Errorf("invalid data after top-level value")
+	}
+	return nil
+}
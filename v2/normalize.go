@@ -0,0 +1,108 @@
+package jd
+
+import (
+	"encoding/json"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+type ignoreCaseOption struct{}
+
+// IGNORE_CASE compares strings by their Unicode case folding, so
+// "Production" equals "production".
+var IGNORE_CASE = ignoreCaseOption{}
+
+func (o ignoreCaseOption) isOption() {}
+func (o ignoreCaseOption) MarshalJSON() ([]byte, error) {
+	return json.Marshal("IGNORE_CASE")
+}
+
+type trimSpaceOption struct{}
+
+// TRIM_SPACE compares strings without their leading and trailing white
+// space, so "prod " equals "prod".
+var TRIM_SPACE = trimSpaceOption{}
+
+func (o trimSpaceOption) isOption() {}
+func (o trimSpaceOption) MarshalJSON() ([]byte, error) {
+	return json.Marshal("TRIM_SPACE")
+}
+
+type collapseSpaceOption struct{}
+
+// COLLAPSE_SPACE compares strings with each run of white space replaced
+// by a single space, so "a \t b" equals "a b".
+var COLLAPSE_SPACE = collapseSpaceOption{}
+
+func (o collapseSpaceOption) isOption() {}
+func (o collapseSpaceOption) MarshalJSON() ([]byte, error) {
+	return json.Marshal("COLLAPSE_SPACE")
+}
+
+type nfcOption struct{}
+
+// NFC compares strings in Unicode Normalization Form C, so a letter
+// followed by a combining accent equals the precomposed letter.
+var NFC = nfcOption{}
+
+func (o nfcOption) isOption() {}
+func (o nfcOption) MarshalJSON() ([]byte, error) {
+	return json.Marshal("NFC")
+}
+
+type nfkcOption struct{}
+
+// NFKC compares strings in Unicode Normalization Form KC, which is NFC
+// also folding compatibility characters such as "ﬁ" into "fi".
+var NFKC = nfkcOption{}
+
+func (o nfkcOption) isOption() {}
+func (o nfkcOption) MarshalJSON() ([]byte, error) {
+	return json.Marshal("NFKC")
+}
+
+// normalized returns s as compared under the string options applied
+// in o. Unicode normalization comes first, then white space and then
+// case.
+func normalized(s jsonString, o *options) jsonString {
+	n := string(s)
+	if _, ok := getOption[nfkcOption](o); ok {
+		n = norm.NFKC.String(n)
+	} else if _, ok := getOption[nfcOption](o); ok {
+		n = norm.NFC.String(n)
+	}
+	if _, ok := getOption[trimSpaceOption](o); ok {
+		n = strings.TrimSpace(n)
+	}
+	if _, ok := getOption[collapseSpaceOption](o); ok {
+		n = collapseSpace(n)
+	}
+	if _, ok := getOption[ignoreCaseOption](o); ok {
+		n = cases.Fold().String(n)
+	}
+	return jsonString(n)
+}
+
+// collapseSpace replaces each run of white space in s with one space.
+func collapseSpace(s string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range s {
+		if unicode.IsSpace(r) {
+			space = true
+			continue
+		}
+		if space {
+			b.WriteByte(' ')
+			space = false
+		}
+		b.WriteRune(r)
+	}
+	if space {
+		b.WriteByte(' ')
+	}
+	return b.String()
+}
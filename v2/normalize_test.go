@@ -0,0 +1,193 @@
+package jd
+
+import (
+	"testing"
+)
+
+func TestNormalizedDiff(t *testing.T) {
+	cases := []struct {
+		name    string
+		options []Option
+		a       string
+		b       string
+		want    []string
+	}{{
+		name:    "ignore case and trim space",
+		options: []Option{IGNORE_CASE, TRIM_SPACE},
+		a:       `{"env":"Production ","tier":"Gold"}`,
+		b:       `{"env":"production","tier":"silver"}`,
+		want: ss(
+			`@ ["tier"]`,
+			`- "Gold"`,
+			`+ "silver"`,
+		),
+	}, {
+		name:    "ignore case only",
+		options: []Option{IGNORE_CASE},
+		a:       `{"env":"Production "}`,
+		b:       `{"env":"production"}`,
+		want: ss(
+			`@ ["env"]`,
+			`- "Production "`,
+			`+ "production"`,
+		),
+	}, {
+		name:    "fold case",
+		options: []Option{IGNORE_CASE},
+		a:       `["ΣΊΣΥΦΟΣ","Hello","STRASSE"]`,
+		b:       `["σίσυφος","hELLO","straße"]`,
+		want:    ss(),
+	}, {
+		name:    "collapse space",
+		options: []Option{COLLAPSE_SPACE},
+		a:       `["a \t b\n\nc"," a","a ","ab"]`,
+		b:       `["a b c","\n a","a  ","a b"]`,
+		want: ss(
+			`@ [3]`,
+			`  "a "`,
+			`- "ab"`,
+			`+ "a b"`,
+			`]`,
+		),
+	}, {
+		name:    "collapse and trim space",
+		options: []Option{COLLAPSE_SPACE, TRIM_SPACE},
+		a:       `" a  b "`,
+		b:       `"a b"`,
+		want:    ss(),
+	}, {
+		name:    "nfc",
+		options: []Option{NFC},
+		a:       `["cafe\u0301","ﬁ"]`,
+		b:       `["caf\u00e9","fi"]`,
+		want: ss(
+			`@ [1]`,
+			"  \"cafe\u0301\"",
+			"- \"ﬁ\"",
+			`+ "fi"`,
+			`]`,
+		),
+	}, {
+		name:    "nfkc",
+		options: []Option{NFKC},
+		a:       `["cafe\u0301","ﬁ"]`,
+		b:       `["caf\u00e9","fi"]`,
+		want:    ss(),
+	}, {
+		name:    "without options",
+		options: []Option{},
+		a:       `["cafe\u0301","A"]`,
+		b:       `["caf\u00e9","a"]`,
+		want: ss(
+			`@ [0]`,
+			`[`,
+			"- \"cafe\u0301\"",
+			`- "A"`,
+			"+ \"caf\u00e9\"",
+			`+ "a"`,
+			`]`,
+		),
+	}, {
+		name:    "path option",
+		options: []Option{PathOption(Path{PathKey("name")}, IGNORE_CASE)},
+		a:       `{"name":"Web","id":"Web"}`,
+		b:       `{"name":"web","id":"web"}`,
+		want: ss(
+			`@ ["id"]`,
+			`- "Web"`,
+			`+ "web"`,
+		),
+	}, {
+		name:    "set",
+		options: []Option{SET, IGNORE_CASE, TRIM_SPACE},
+		a:       `["Alpha","beta ",{"x":"Y"}]`,
+		b:       `[{"x":"y"},"BETA","alpha"]`,
+		want:    ss(),
+	}, {
+		name:    "multiset",
+		options: []Option{MULTISET, IGNORE_CASE},
+		a:       `["A","a","b"]`,
+		b:       `["a","B","a"]`,
+		want:    ss(),
+	}, {
+		name:    "multiset members removed",
+		options: []Option{MULTISET, IGNORE_CASE},
+		a:       `["A","a","b"]`,
+		b:       `["b"]`,
+		want: ss(
+			`@ [[]]`,
+			`- "A"`,
+			`- "a"`,
+		),
+	}, {
+		name:    "multiset member removed",
+		options: []Option{MULTISET, TRIM_SPACE, COLLAPSE_SPACE},
+		a:       `["a  b"," a b","a b"]`,
+		b:       `["a b"]`,
+		want: ss(
+			`@ [[]]`,
+			`- "a  b"`,
+			`- " a b"`,
+		),
+	}, {
+		name:    "multiset path option",
+		options: []Option{PathOption(Path{PathKey("a")}, MULTISET, IGNORE_CASE)},
+		a:       `{"a":["B","b","a"]}`,
+		b:       `{"a":["b","A"]}`,
+		want: ss(
+			`@ ["a",[]]`,
+			`- "B"`,
+		),
+	}, {
+		name:    "multiset normalized forms",
+		options: []Option{MULTISET, NFKC},
+		a:       `["ﬁ","fi"]`,
+		b:       `[]`,
+		want: ss(
+			`@ [[]]`,
+			`- "ﬁ"`,
+			`- "fi"`,
+		),
+	}, {
+		name:    "set keys",
+		options: []Option{SetKeys("name"), IGNORE_CASE},
+		a:       `[{"name":"Web","port":80},{"name":"DB","port":5432}]`,
+		b:       `[{"name":"db","port":5432},{"name":"web","port":8080}]`,
+		want: ss(
+			`@ [{"name":"Web"},"port"]`,
+			`- 80`,
+			`+ 8080`,
+		),
+	}, {
+		name:    "with coerce",
+		options: []Option{COERCE, TRIM_SPACE, IGNORE_CASE},
+		a:       `["True"," 1 "]`,
+		b:       `[true,1]`,
+		want: ss(
+			`@ [0]`,
+			`[`,
+			`- "True"`,
+			`- " 1 "`,
+			`+ true`,
+			`+ 1`,
+			`]`,
+		),
+	}}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ctx := newTestContext(t).withOptions(c.options...)
+			checkDiff(ctx, c.a, c.b, c.want...)
+			checkRoundTrip(t, c.a, c.b, c.options)
+		})
+	}
+}
+
+func TestNormalizedEquals(t *testing.T) {
+	a, b := jsonString("Hello  World"), jsonString("hello world")
+	if a.Equals(b) {
+		t.Errorf("%v equals %v without options", a.Json(), b.Json())
+	}
+	if !a.Equals(b, IGNORE_CASE, COLLAPSE_SPACE) {
+		t.Errorf("%v does not equal %v with IGNORE_CASE and COLLAPSE_SPACE", a.Json(), b.Json())
+	}
+}
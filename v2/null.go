@@ -1,5 +1,7 @@
 package jd
 
+import "context"
+
 type jsonNull []byte
 
 var _ JsonNode = jsonNull{}
@@ -16,12 +18,13 @@ func (n jsonNull) raw() interface{} {
 	return nil
 }
 
-func (n jsonNull) Equals(node JsonNode, _ ...Option) bool {
-	return n.equals(node, nil)
+func (n jsonNull) Equals(node JsonNode, opts ...Option) bool {
+	o := refine(&options{retain: opts}, nil)
+	return n.equals(node, o)
 }
 
-func (n jsonNull) equals(node JsonNode, _ *options) bool {
-	switch node.(type) {
+func (n jsonNull) equals(node JsonNode, o *options) bool {
+	switch coerced(node, o).(type) {
 	case jsonNull:
 		return true
 	default:
@@ -54,6 +57,14 @@ func (n jsonNull) Patch(d Diff) (JsonNode, error) {
 	return patchAll(n, d)
 }
 
+func (n jsonNull) DiffContext(ctx context.Context, node JsonNode, opts ...Option) (Diff, error) {
+	return diffContext(ctx, n, node, opts)
+}
+
+func (n jsonNull) PatchContext(ctx context.Context, d Diff) (JsonNode, error) {
+	return patchContext(ctx, n, d)
+}
+
 func (n jsonNull) patch(
 	pathBehind, pathAhead Path,
 	before, oldValues, newValues, after []JsonNode,
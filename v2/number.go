@@ -2,10 +2,19 @@ package jd
 
 import (
 	"bytes"
+	"context"
 	"encoding/binary"
+	"fmt"
 	"math"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
 )
 
+// jsonNumber is a number which float64 holds without losing digits.
+// Numbers which float64 cannot hold are jsonDecimals. See newNumber.
 type jsonNumber float64
 
 var _ JsonNode = jsonNumber(0)
@@ -28,17 +37,14 @@ func (n1 jsonNumber) Equals(node JsonNode, opts ...Option) bool {
 }
 
 func (n1 jsonNumber) equals(node JsonNode, o *options) bool {
-	precision := 0.0
-	if p, ok := getOption[precisionOption](o); ok {
-		precision = p.precision
+	node = coerced(node, o)
+	switch n2 := node.(type) {
+	case jsonNumber:
+		return newTolerance(o).allows(float64(n1), float64(n2))
+	case jsonDecimal:
+		return n2.equals(n1, o)
 	}
-
-	n2, ok := node.(jsonNumber)
-	if !ok {
-		return false
-	}
-
-	return math.Abs(float64(n1)-float64(n2)) <= precision
+	return false
 }
 
 func (n jsonNumber) hashCode(opts *options) [8]byte {
@@ -69,6 +75,14 @@ func (n jsonNumber) Patch(d Diff) (JsonNode, error) {
 	return patchAll(n, d)
 }
 
+func (n jsonNumber) DiffContext(ctx context.Context, node JsonNode, opts ...Option) (Diff, error) {
+	return diffContext(ctx, n, node, opts)
+}
+
+func (n jsonNumber) PatchContext(ctx context.Context, d Diff) (JsonNode, error) {
+	return patchContext(ctx, n, d)
+}
+
 func (n jsonNumber) patch(
 	pathBehind, pathAhead Path,
 	before, oldValues, newValues, after []JsonNode,
@@ -76,3 +90,261 @@ func (n jsonNumber) patch(
 ) (JsonNode, error) {
 	return patch(n, pathBehind, pathAhead, before, oldValues, newValues, after, strategy)
 }
+
+// newNumber reads a JSON number literal. It is a jsonNumber when
+// float64 holds the same value, so it renders back to an equal literal,
+// and a jsonDecimal otherwise.
+func newNumber(lit string) (JsonNode, error) {
+	d, err := parseDecimal(lit)
+	if err != nil {
+		return nil, err
+	}
+	f, err := strconv.ParseFloat(lit, 64)
+	if err == nil {
+		short, _ := parseDecimal(strconv.FormatFloat(f, 'g', -1, 64))
+		if short == d {
+			return jsonNumber(f), nil
+		}
+	}
+	return jsonDecimal(lit), nil
+}
+
+// jsonDecimal is a number which float64 cannot hold without losing
+// digits, such as a 64-bit ID or a long decimal fraction. It keeps the
+// literal text, which is written back as is, and compares by exact
+// value. A jsonDecimal never equals a jsonNumber without a tolerance.
+type jsonDecimal string
+
+var _ JsonNode = jsonDecimal("")
+
+func (n jsonDecimal) Json(_ ...Option) string {
+	return renderJson(n.raw())
+}
+
+func (n jsonDecimal) Yaml(_ ...Option) string {
+	return renderYaml(n.raw())
+}
+
+func (n jsonDecimal) raw() interface{} {
+	return n
+}
+
+// MarshalJSON writes the literal as is because it may be used as a raw
+// value.
+func (n jsonDecimal) MarshalJSON() ([]byte, error) {
+	return []byte(n), nil
+}
+
+// MarshalYAML writes the literal as a plain number.
+func (n jsonDecimal) MarshalYAML() (interface{}, error) {
+	return &yaml.Node{Kind: yaml.ScalarNode, Value: string(n)}, nil
+}
+
+func (n1 jsonDecimal) Equals(node JsonNode, opts ...Option) bool {
+	o := refine(&options{retain: opts}, nil)
+	return n1.equals(node, o)
+}
+
+func (n1 jsonDecimal) equals(node JsonNode, o *options) bool {
+	node = coerced(node, o)
+	var lit string
+	switch n2 := node.(type) {
+	case jsonDecimal:
+		lit = string(n2)
+	case jsonNumber:
+		lit = strconv.FormatFloat(float64(n2), 'g', -1, 64)
+	default:
+		return false
+	}
+	d1, _ := parseDecimal(string(n1))
+	d2, _ := parseDecimal(lit)
+	if d1 == d2 {
+		return true
+	}
+	return newTolerance(o).allowsBig(bigFloat(string(n1)), bigFloat(lit))
+}
+
+// tolerance is how far apart numbers may be and still be equal. They are
+// equal when any of the Precision, Relative and ULP options allows it.
+type tolerance struct {
+	precision float64
+	relative  float64
+	ulps      int
+}
+
+func newTolerance(o *options) tolerance {
+	var t tolerance
+	if p, ok := getOption[precisionOption](o); ok {
+		t.precision = p.precision
+	}
+	if r, ok := getOption[relativeOption](o); ok {
+		t.relative = r.tolerance
+	}
+	if u, ok := getOption[ulpOption](o); ok {
+		t.ulps = u.ulps
+	}
+	return t
+}
+
+// hasTolerance reports whether a Precision, Relative or ULP option
+// applies at the path of o or may apply below it. Hashes of numbers
+// are exact, so sets and multisets then pair the elements their hashes
+// leave unpaired by equality.
+func hasTolerance(o *options) bool {
+	return anyTolerance(o.apply) || anyTolerance(o.retain)
+}
+
+func anyTolerance(opts []Option) bool {
+	for _, o := range opts {
+		switch o := o.(type) {
+		case precisionOption, relativeOption, ulpOption:
+			return true
+		case pathOption:
+			if anyTolerance(o.Then) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (t tolerance) allows(a, b float64) bool {
+	diff := math.Abs(a - b)
+	if diff <= t.precision || diff <= t.relative*max(math.Abs(a), math.Abs(b)) {
+		return true
+	}
+	return t.ulps > 0 && ulpDistance(a, b) <= uint64(t.ulps)
+}
+
+// allowsBig is allows for numbers float64 cannot hold. ULP distance is
+// that of the nearest float64 values.
+func (t tolerance) allowsBig(a, b *big.Float) bool {
+	prec := max(a.Prec(), b.Prec())
+	diff := new(big.Float).SetPrec(prec).Sub(a, b)
+	diff.Abs(diff)
+	if diff.Cmp(big.NewFloat(t.precision)) <= 0 {
+		return true
+	}
+	if t.relative > 0 {
+		larger := new(big.Float).SetPrec(prec).Abs(a)
+		if absB := new(big.Float).SetPrec(prec).Abs(b); absB.Cmp(larger) > 0 {
+			larger = absB
+		}
+		if diff.Cmp(larger.Mul(larger, big.NewFloat(t.relative))) <= 0 {
+			return true
+		}
+	}
+	if t.ulps > 0 {
+		fa, _ := a.Float64()
+		fb, _ := b.Float64()
+		if !math.IsInf(fa, 0) && !math.IsInf(fb, 0) {
+			return ulpDistance(fa, fb) <= uint64(t.ulps)
+		}
+	}
+	return false
+}
+
+// ulpDistance is the number of float64 values from a to b.
+func ulpDistance(a, b float64) uint64 {
+	oa, ob := ordered(a), ordered(b)
+	if oa < ob {
+		oa, ob = ob, oa
+	}
+	return uint64(oa) - uint64(ob)
+}
+
+// ordered maps the bits of f to an integer which orders like f, with
+// both zeros at 0.
+func ordered(f float64) int64 {
+	i := int64(math.Float64bits(f))
+	if i < 0 {
+		return math.MinInt64 - i
+	}
+	return i
+}
+
+// bigFloat reads a valid number literal with enough precision for all
+// of its digits.
+func bigFloat(lit string) *big.Float {
+	f, _, err := big.ParseFloat(lit, 10, uint(len(lit))*4+64, big.ToNearestEven)
+	if err != nil { //jd:nocover — jsonDecimal literals are valid numbers
+		panic(err)
+	}
+	return f
+}
+
+func (n jsonDecimal) hashCode(opts *options) [8]byte {
+	d, _ := parseDecimal(string(n))
+	return hash([]byte(fmt.Sprintf("decimal %v %v %v", d.neg, d.digits, d.exp)))
+}
+
+func (n jsonDecimal) Diff(node JsonNode, opts ...Option) Diff {
+	o := refine(newOptions(opts), nil)
+	return n.diff(node, make(Path, 0), o, getPatchStrategy(o))
+}
+
+func (n jsonDecimal) diff(
+	node JsonNode,
+	path Path,
+	opts *options,
+	strategy patchStrategy,
+) Diff {
+	events := generateSimpleEvents(n, node, opts)
+	processor := newSimpleDiffProcessor(path, opts, strategy)
+	return processor.ProcessEvents(events)
+}
+
+func (n jsonDecimal) Patch(d Diff) (JsonNode, error) {
+	return patchAll(n, d)
+}
+
+func (n jsonDecimal) DiffContext(ctx context.Context, node JsonNode, opts ...Option) (Diff, error) {
+	return diffContext(ctx, n, node, opts)
+}
+
+func (n jsonDecimal) PatchContext(ctx context.Context, d Diff) (JsonNode, error) {
+	return patchContext(ctx, n, d)
+}
+
+func (n jsonDecimal) patch(
+	pathBehind, pathAhead Path,
+	before, oldValues, newValues, after []JsonNode,
+	strategy patchStrategy,
+) (JsonNode, error) {
+	return patch(n, pathBehind, pathAhead, before, oldValues, newValues, after, strategy)
+}
+
+// decimal is the value of a number literal as 0.digits × 10^exp with
+// no leading or trailing zero digits, so equal numbers are ==.
+type decimal struct {
+	neg    bool
+	digits string
+	exp    int
+}
+
+func parseDecimal(lit string) (decimal, error) {
+	var d decimal
+	s, neg := strings.CutPrefix(lit, "-")
+	mantissa, exponent, hasExp := strings.Cut(strings.ToLower(s), "e")
+	whole, frac, _ := strings.Cut(mantissa, ".")
+	digits := whole + frac
+	if digits == "" || strings.Trim(digits, "0123456789") != "" {
+		return d, fmt.Errorf("invalid number %q", lit)
+	}
+	if hasExp {
+		e, err := strconv.Atoi(exponent)
+		if err != nil {
+			return d, fmt.Errorf("invalid number %q", lit)
+		}
+		d.exp = e
+	}
+	trimmed := strings.TrimLeft(digits, "0")
+	d.exp += len(whole) - (len(digits) - len(trimmed))
+	d.digits = strings.TrimRight(trimmed, "0")
+	if d.digits == "" {
+		// All zeros are the same zero.
+		return decimal{}, nil
+	}
+	d.neg = neg
+	return d, nil
+}
@@ -60,6 +60,26 @@ func TestNumberJson(t *testing.T) {
 			input:    ` 42 `,
 			expected: `42`,
 		},
+		{
+			name:     "64-bit integer keeps every digit",
+			input:    `12345678901234567891`,
+			expected: `12345678901234567891`,
+		},
+		{
+			name:     "long decimal keeps every digit",
+			input:    `0.10000000000000000001`,
+			expected: `0.10000000000000000001`,
+		},
+		{
+			name:     "exponent beyond float64",
+			input:    `1e400`,
+			expected: `1e400`,
+		},
+		{
+			name:     "nested big number",
+			input:    `{"id":9007199254740993}`,
+			expected: `{"id":9007199254740993}`,
+		},
 	}
 
 	for _, tt := range tests {
@@ -134,6 +154,81 @@ func TestNumberEqual(t *testing.T) {
 			b:       `42.420001`,
 			options: []Option{Precision(0.01)},
 		},
+		{
+			name: "same big integer",
+			a:    `12345678901234567891`,
+			b:    `12345678901234567891`,
+		},
+		{
+			name: "big integer equals equivalent decimal",
+			a:    `12345678901234567891`,
+			b:    `1.2345678901234567891000e19`,
+		},
+		{
+			name: "negative big numbers",
+			a:    `-0.10000000000000000001`,
+			b:    `-0.100000000000000000010`,
+		},
+		{
+			name:    "precision tolerance - big integers",
+			a:       `10000000000000000001`,
+			b:       `10000000000000000000`,
+			options: []Option{Precision(1)},
+		},
+		{
+			name:    "precision tolerance - big integer and number",
+			a:       `9007199254740993`,
+			b:       `9007199254740992`,
+			options: []Option{Precision(1)},
+		},
+		{
+			name:    "relative tolerance - large numbers",
+			a:       `1000000000`,
+			b:       `1009000000`,
+			options: []Option{Relative(0.01)},
+		},
+		{
+			name:    "relative tolerance - small numbers",
+			a:       `-0.00100999`,
+			b:       `-0.001`,
+			options: []Option{Relative(0.01)},
+		},
+		{
+			name:    "relative tolerance - precision satisfies",
+			a:       `0`,
+			b:       `0.05`,
+			options: []Option{Precision(0.1), Relative(0.01)},
+		},
+		{
+			name:    "relative tolerance - big integers",
+			a:       `10000000000000000001`,
+			b:       `10000000000000000100`,
+			options: []Option{Relative(1e-10)},
+		},
+		{
+			name:    "relative tolerance - big number and larger number",
+			a:       `0.10000000000000000001`,
+			b:       `0.11`,
+			options: []Option{Relative(0.1)},
+		},
+		{
+			name:    "ulp tolerance - rounding error",
+			a:       `0.30000000000000004`,
+			b:       `0.3`,
+			options: []Option{ULP(1)},
+		},
+		{
+			name:    "ulp tolerance - across zero",
+			a:       `-5e-324`,
+			b:       `5e-324`,
+			options: []Option{ULP(2)},
+		},
+		{
+			name:    "ulp tolerance - big number and its float64 value",
+			a:       `0.10000000000000000001`,
+			b:       `0.1`,
+			options: []Option{ULP(1)},
+		},
 	}
 
 	for _, tt := range tests {
@@ -246,6 +341,67 @@ func TestNumberNotEqual(t *testing.T) {
 			b:       `2`,
 			options: []Option{Precision(0.1)},
 		},
+		{
+			name:    "relative tolerance - outside range",
+			a:       `100`,
+			b:       `102`,
+			options: []Option{Relative(0.01)},
+		},
+		{
+			name:    "relative tolerance - big numbers outside range",
+			a:       `0.10000000000000000001`,
+			b:       `0.2`,
+			options: []Option{Relative(0.1)},
+		},
+		{
+			name:    "ulp tolerance - outside range",
+			a:       `1`,
+			b:       `1.0000000000000004`,
+			options: []Option{ULP(1)},
+		},
+		{
+			name:    "ulp tolerance - across zero outside range",
+			a:       `-5e-324`,
+			b:       `5e-324`,
+			options: []Option{ULP(1)},
+		},
+		{
+			name:    "ulp tolerance - beyond float64",
+			a:       `1e400`,
+			b:       `2e400`,
+			options: []Option{ULP(1)},
+		},
+		{
+			name: "consecutive 64-bit integers",
+			a:    `9007199254740993`,
+			b:    `9007199254740992`,
+		},
+		{
+			name: "big integers differ in last digit",
+			a:    `12345678901234567891`,
+			b:    `12345678901234567890`,
+		},
+		{
+			name: "big number and its float64 value",
+			a:    `0.10000000000000000001`,
+			b:    `0.1`,
+		},
+		{
+			name: "big number and negation",
+			a:    `12345678901234567891`,
+			b:    `-12345678901234567891`,
+		},
+		{
+			name: "big number not equal to string",
+			a:    `12345678901234567891`,
+			b:    `"12345678901234567891"`,
+		},
+		{
+			name:    "precision tolerance - big integers outside range",
+			a:       `10000000000000000002`,
+			b:       `10000000000000000000`,
+			options: []Option{Precision(1)},
+		},
 	}
 
 	for _, tt := range tests {
@@ -344,6 +500,18 @@ func TestNumberHash(t *testing.T) {
 			b:        `{}`,
 			wantSame: false,
 		},
+		{
+			name:     "equal big numbers hash same",
+			a:        `12345678901234567891`,
+			b:        `1.2345678901234567891e19`,
+			wantSame: true,
+		},
+		{
+			name:     "different big numbers hash different",
+			a:        `12345678901234567891`,
+			b:        `12345678901234567890`,
+			wantSame: false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -472,6 +640,26 @@ func TestNumberDiff(t *testing.T) {
 				`+ 123.456`,
 			},
 		},
+		{
+			name: "diff consecutive 64-bit integers",
+			a:    `{"id":9007199254740993}`,
+			b:    `{"id":9007199254740992}`,
+			expected: []string{
+				`@ ["id"]`,
+				`- 9007199254740993`,
+				`+ 9007199254740992`,
+			},
+		},
+		{
+			name: "diff big number roots",
+			a:    `12345678901234567891`,
+			b:    `12345678901234567892`,
+			expected: []string{
+				`@ []`,
+				`- 12345678901234567891`,
+				`+ 12345678901234567892`,
+			},
+		},
 		{
 			name:    "merge diff numbers",
 			a:       `1`,
@@ -512,6 +700,33 @@ func TestNumberDiff(t *testing.T) {
 				`+ 42.43`,
 			},
 		},
+		{
+			name: "relative tolerance - path option",
+			a:    `{"bytes":1000000000,"errors":100}`,
+			b:    `{"bytes":1000900000,"errors":101}`,
+			options: []Option{
+				PathOption(Path{PathKey("bytes")}, Relative(0.001)),
+				Precision(0.5),
+			},
+			expected: []string{
+				`@ ["errors"]`,
+				`- 100`,
+				`+ 101`,
+			},
+		},
+		{
+			name: "ulp tolerance - path option",
+			a:    `{"a":0.30000000000000004,"b":0.30000000000000004}`,
+			b:    `{"a":0.3,"b":0.3}`,
+			options: []Option{
+				PathOption(Path{PathKey("a")}, ULP(4)),
+			},
+			expected: []string{
+				`@ ["b"]`,
+				`- 0.30000000000000004`,
+				`+ 0.3`,
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -538,6 +753,16 @@ func TestNumberPatch(t *testing.T) {
 			expected: `0`,
 			patch:    []string{},
 		},
+		{
+			name:     "patch big number root",
+			initial:  `12345678901234567891`,
+			expected: `12345678901234567892`,
+			patch: []string{
+				`@ []`,
+				`- 12345678901234567891`,
+				`+ 12345678901234567892`,
+			},
+		},
 		{
 			name:     "patch number to different number",
 			initial:  `0`,
@@ -781,3 +1006,61 @@ func TestNumberPatchError(t *testing.T) {
 		})
 	}
 }
+
+func TestNumberLiteralPatch(t *testing.T) {
+	doc := `{"id":12345678901234567891,"x":[0.10000000000000000001]}`
+	a, err := ReadJsonString(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := ReadPatchString(`[{"op":"test","path":"/id","value":12345678901234567891},{"op":"replace","path":"/id","value":12345678901234567892}]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := a.Patch(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"id":12345678901234567892,"x":[0.10000000000000000001]}`; b.Json() != want {
+		t.Errorf("got %v. want %v", b.Json(), want)
+	}
+	if want := "id: 12345678901234567892\nx:\n    - 0.10000000000000000001\n"; b.Yaml() != want {
+		t.Errorf("got %q. want %q", b.Yaml(), want)
+	}
+	a, _ = ReadJsonString(doc)
+	patch, err := a.Diff(b).RenderPatch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `[{"op":"test","path":"/id","value":12345678901234567891},{"op":"remove","path":"/id","value":12345678901234567891},{"op":"add","path":"/id","value":12345678901234567892}]`; patch != want {
+		t.Errorf("got patch %v. want %v", patch, want)
+	}
+}
+
+func TestNewNumber(t *testing.T) {
+	for _, c := range []struct {
+		in   interface{}
+		want JsonNode
+	}{
+		{int64(9007199254740993), jsonDecimal("9007199254740993")},
+		{uint64(18446744073709551615), jsonDecimal("18446744073709551615")},
+		{int(42), jsonNumber(42)},
+		{jsonDecimal("1e400"), jsonDecimal("1e400")},
+	} {
+		got, err := NewJsonNode(c.in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != c.want {
+			t.Errorf("NewJsonNode(%#v) = %#v. want %#v", c.in, got, c.want)
+		}
+	}
+	for _, bad := range []string{``, `-`, `.`, `1x`, `1e`, `1e1.5`} {
+		if got, err := newNumber(bad); err == nil {
+			t.Errorf("wanted error reading %q. got %v", bad, got.Json())
+		}
+	}
+	if _, err := ReadJsonString(`1 2`); err == nil {
+		t.Errorf("wanted error reading trailing data")
+	}
+}
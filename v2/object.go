@@ -1,6 +1,7 @@
 package jd
 
 import (
+	"context"
 	"fmt"
 	"sort"
 )
@@ -34,6 +35,7 @@ func (o jsonObject) raw() interface{} {
 }
 
 func (o1 jsonObject) Equals(n JsonNode, opts ...Option) bool {
+	opts = InferKeys(o1, n, opts...)
 	o := refine(newOptions(opts), nil)
 	return o1.equals(n, o)
 }
@@ -52,7 +54,7 @@ func (o1 jsonObject) equals(n JsonNode, o *options) bool {
 		if !ok {
 			return false
 		}
-		ret := val1.equals(val2, o)
+		ret := val1.equals(val2, refine(o, PathKey(key1)))
 		if !ret {
 			return false
 		}
@@ -100,6 +102,38 @@ func (o jsonObject) ident(opts *options) [8]byte {
 	return hashes.combine()
 }
 
+// sameIdent reports whether n is an object with the identity of o
+// under opts, comparing the values of the set keys with equals rather
+// than by hash so that tolerances apply.
+func (o jsonObject) sameIdent(n JsonNode, opts *options) bool {
+	o2, ok := n.(jsonObject)
+	if !ok {
+		return false
+	}
+	keys, ok := getOption[setKeysOption](opts)
+	if !ok {
+		return o.equals(o2, opts)
+	}
+	found := false
+	for _, key := range []string(*keys) {
+		v1, ok1 := o[key]
+		v2, ok2 := o2[key]
+		if ok1 != ok2 {
+			return false
+		}
+		if ok1 {
+			if !v1.equals(v2, opts) {
+				return false
+			}
+			found = true
+		}
+	}
+	if !found {
+		return o.equals(o2, opts)
+	}
+	return true
+}
+
 func (o jsonObject) pathIdent(pathObject jsonObject, opts *options) [8]byte {
 	keys := []string{}
 	for k := range pathObject {
@@ -116,8 +150,10 @@ func (o jsonObject) pathIdent(pathObject jsonObject, opts *options) [8]byte {
 }
 
 func (o jsonObject) Diff(n JsonNode, opts ...Option) Diff {
+	opts = InferKeys(o, n, opts...)
 	op := newOptions(opts)
-	return o.diff(n, make(Path, 0), op, getPatchStrategy(op))
+	d := o.diff(n, make(Path, 0), op, getPatchStrategy(op))
+	return withMoves(o, d, op)
 }
 
 func (o1 jsonObject) diff(
@@ -144,6 +180,14 @@ func (o jsonObject) Patch(d Diff) (JsonNode, error) {
 	return patchAll(o, d)
 }
 
+func (o jsonObject) DiffContext(ctx context.Context, n JsonNode, opts ...Option) (Diff, error) {
+	return diffContext(ctx, o, n, opts)
+}
+
+func (o jsonObject) PatchContext(ctx context.Context, d Diff) (JsonNode, error) {
+	return patchContext(ctx, o, d)
+}
+
 func (o jsonObject) patch(
 	pathBehind, pathAhead Path,
 	before, oldValues, newValues, after []JsonNode,
@@ -272,6 +316,7 @@ func (p *objectDiffProcessor) ProcessEvents(events []diffEvent) Diff {
 
 	for i, event := range events {
 		p.debugLog("Processing event %d: %s", i, event.String())
+		p.opts.budget.step()
 		p.processEvent(event)
 	}
 
@@ -448,7 +493,7 @@ func generateObjectdiffEvents(o1, o2 jsonObject, opts *options) []diffEvent {
 			o := refine(opts, PathKey(k))
 			if !v1.equals(v2, o) {
 				// Check if compatible containers for recursive diff
-				isRecursive := sameContainerType(v1, v2, opts)
+				isRecursive := sameContainerType(v1, v2, o)
 				events = append(events, objectKeyDiffEvent{
 					Key:         k,
 					OldValue:    v1,
@@ -3,6 +3,8 @@ package jd
 import (
 	"encoding/json"
 	"fmt"
+	"math"
+	"regexp"
 )
 
 type Option interface {
@@ -48,6 +50,24 @@ func NewOption(a any) (Option, error) {
 			return DIFF_ON, nil
 		case "DIFF_OFF":
 			return DIFF_OFF, nil
+		case "DETECT_MOVES":
+			return DETECT_MOVES, nil
+		case "AUTO_KEYS":
+			return AUTO_KEYS, nil
+		case "COERCE":
+			return COERCE, nil
+		case "IGNORE_CASE":
+			return IGNORE_CASE, nil
+		case "TRIM_SPACE":
+			return TRIM_SPACE, nil
+		case "COLLAPSE_SPACE":
+			return COLLAPSE_SPACE, nil
+		case "NFC":
+			return NFC, nil
+		case "NFKC":
+			return NFKC, nil
+		case "EMBEDDED":
+			return EMBEDDED, nil
 		default:
 			return nil, fmt.Errorf("unrecognized string: %v", a)
 		}
@@ -64,6 +84,34 @@ func NewOption(a any) (Option, error) {
 					}
 					prec = f
 					return Precision(prec), nil
+				case "pattern":
+					s, ok := v.(string)
+					if !ok {
+						return nil, fmt.Errorf("wanted string. got %T", v)
+					}
+					re, err := regexp.Compile(s)
+					if err != nil {
+						return nil, err
+					}
+					return patternOption{re}, nil
+				case "relative":
+					f, ok := v.(float64)
+					if !ok {
+						return nil, fmt.Errorf("wanted float64. got %T", v)
+					}
+					return Relative(f), nil
+				case "ulp":
+					f, ok := v.(float64)
+					if !ok || f != math.Trunc(f) {
+						return nil, fmt.Errorf("wanted integer. got %v", v)
+					}
+					return ULP(int(f)), nil
+				case "similarity":
+					f, ok := v.(float64)
+					if !ok {
+						return nil, fmt.Errorf("wanted float64. got %T", v)
+					}
+					return Similarity(f), nil
 				case "keys", "setkeys":
 					untypedKeys, ok := v.([]any)
 					if !ok {
@@ -206,6 +254,18 @@ func (o diffOffOption) MarshalJSON() ([]byte, error) {
 	return json.Marshal("DIFF_OFF")
 }
 
+type detectMovesOption struct{}
+
+// DETECT_MOVES replaces the removal and addition of an identical
+// object or array with a single hunk moving it, and the addition of a
+// copy of an unchanged object or array with a hunk copying it.
+var DETECT_MOVES = detectMovesOption{}
+
+func (o detectMovesOption) isOption() {}
+func (o detectMovesOption) MarshalJSON() ([]byte, error) {
+	return json.Marshal("DETECT_MOVES")
+}
+
 type precisionOption struct {
 	precision float64
 }
@@ -220,6 +280,57 @@ func (o precisionOption) MarshalJSON() ([]byte, error) {
 	})
 }
 
+type relativeOption struct {
+	tolerance float64
+}
+
+// Relative makes numbers equal when they differ by at most tolerance
+// times the larger magnitude, so Relative(0.01) allows 1%. Numbers are
+// equal when any of Precision, Relative and ULP allows it.
+func Relative(tolerance float64) Option {
+	return relativeOption{tolerance}
+}
+func (o relativeOption) isOption() {}
+func (o relativeOption) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]float64{
+		"relative": o.tolerance,
+	})
+}
+
+type ulpOption struct {
+	ulps int
+}
+
+// ULP makes numbers equal when at most ulps float64 values lie between
+// them, which absorbs rounding error at any magnitude. Numbers are equal
+// when any of Precision, Relative and ULP allows it.
+func ULP(ulps int) Option {
+	return ulpOption{ulps}
+}
+func (o ulpOption) isOption() {}
+func (o ulpOption) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]int{
+		"ulp": o.ulps,
+	})
+}
+
+type similarityOption struct {
+	threshold float64
+}
+
+// Similarity diffs objects and arrays of a List in place when they are
+// not equal but have at least threshold (between 0 and 1) of their
+// leaves in common, instead of removing one and adding the other.
+func Similarity(threshold float64) Option {
+	return similarityOption{threshold}
+}
+func (o similarityOption) isOption() {}
+func (o similarityOption) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]float64{
+		"similarity": o.threshold,
+	})
+}
+
 type pathOption struct {
 	At   Path     `json:"@"`
 	Then []Option `json:"^"`
@@ -229,6 +340,12 @@ func PathOption(at Path, then ...Option) Option {
 	return pathOption{at, then}
 }
 func (o pathOption) isOption() {}
+func (o pathOption) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		At   json.RawMessage `json:"@"`
+		Then []Option        `json:"^"`
+	}{json.RawMessage(o.At.JsonNode().Json()), o.Then})
+}
 
 type fileOption struct {
 	file string
@@ -282,23 +399,41 @@ func getOption[T Option](opts *options) (*T, bool) {
 	return nil, false
 }
 
+func withoutOption[T Option](opts []Option) []Option {
+	out := []Option{}
+	for _, o := range opts {
+		if _, ok := o.(T); !ok {
+			out = append(out, o)
+		}
+	}
+	return out
+}
+
 func ValidateOptions(opts []Option) error {
-	hasEquivalenceModifier := false
-	hasSetSemantics := false
 	for _, o := range opts {
 		switch o := o.(type) {
 		case precisionOption:
 			if o.precision < 0 {
 				return fmt.Errorf("precision must not be negative")
 			}
-			hasEquivalenceModifier = true
-		case setOption, multisetOption:
-			hasSetSemantics = true
+		case relativeOption:
+			if o.tolerance < 0 || math.IsInf(o.tolerance, 0) || math.IsNaN(o.tolerance) {
+				return fmt.Errorf("relative must be a finite number which is not negative")
+			}
+		case ulpOption:
+			if o.ulps < 0 {
+				return fmt.Errorf("ulp must not be negative")
+			}
+		case similarityOption:
+			if o.threshold <= 0 || o.threshold > 1 {
+				return fmt.Errorf("similarity must be greater than 0 and at most 1")
+			}
+		case pathOption:
+			if err := ValidateOptions(o.Then); err != nil {
+				return err
+			}
 		}
 	}
-	if hasEquivalenceModifier && hasSetSemantics {
-		return fmt.Errorf("precision option is incompatible with set/multiset options because they use hash-based comparison")
-	}
 	return nil
 }
 
@@ -329,24 +464,33 @@ type options struct {
 	apply     []Option
 	retain    []Option
 	diffingOn bool
+	budget    *budget
 }
 
 func newOptions(retain []Option) *options {
-	return &options{
+	o := &options{
 		retain:    retain,
 		diffingOn: true, // Default to diffing ON
 	}
+	for _, opt := range retain {
+		if b, ok := opt.(budgetOption); ok {
+			o.budget = b.budget
+		}
+	}
+	return o
 }
 
 func refine(o *options, p PathElement) *options {
 	var apply, retain []Option
+	var b *budget
 	diffingOn := o.diffingOn // Inherit parent diffing state
 
 	// Only recurse on retained options. Applied options are consumed.
 	for _, o := range o.retain {
 		switch o := o.(type) {
 		// Global options always to every path.
-		case mergeOption, setOption, multisetOption, colorOption, colorWordsOption, precisionOption, setKeysOption, diffOnOption, diffOffOption:
+		case mergeOption, setOption, multisetOption, colorOption, colorWordsOption, precisionOption, relativeOption, ulpOption, setKeysOption, diffOnOption, diffOffOption, detectMovesOption, similarityOption, coerceOption,
+			ignoreCaseOption, trimSpaceOption, collapseSpaceOption, nfcOption, nfkcOption, patternOption, embeddedOption:
 			apply = append(apply, o)
 			retain = append(retain, o)
 			// Update diffing state based on DIFF_ON/DIFF_OFF options
@@ -355,8 +499,17 @@ func refine(o *options, p PathElement) *options {
 			} else if _, ok := o.(diffOffOption); ok {
 				diffingOn = false
 			}
+		case budgetOption:
+			// The budget of a DiffContext follows it everywhere.
+			retain = append(retain, o)
+			b = o.budget
 		case pathOption:
+			if len(o.At) > 0 && p != nil && !matches(o.At[0], p) {
+				// Ignore options targetting other paths.
+				continue
+			}
 			leaf := false
+			var inferred []Option
 			if len(o.At) < 2 {
 				leaf = true
 			}
@@ -364,20 +517,21 @@ func refine(o *options, p PathElement) *options {
 				// Apply options inferred from the path.
 				switch o.At[1].(type) {
 				case PathSet:
-					apply = append(apply, SET)
+					inferred = []Option{SET}
 					leaf = true
 				case PathMultiset:
-					apply = append(apply, MULTISET)
+					inferred = []Option{MULTISET}
 					leaf = true
 				}
 			}
 
+			if leaf && len(o.At) > 0 && p == nil {
+				// Retain options targetting children.
+				leaf = false
+			}
 			if leaf {
-				if len(o.At) > 0 && o.At[0] != p {
-					// Ignore options targetting other paths.
-					continue
-				}
 				// Apply payload of options.
+				apply = append(apply, inferred...)
 				apply = append(apply, o.Then...)
 				// Also update diffing state from PathOption payload
 				for _, thenOpt := range o.Then {
@@ -407,9 +561,11 @@ func refine(o *options, p PathElement) *options {
 			}
 		}
 	}
+	b.step()
 	return &options{
 		apply:     apply,
 		retain:    retain,
 		diffingOn: diffingOn,
+		budget:    b,
 	}
 }
@@ -2,6 +2,7 @@ package jd
 
 import (
 	"encoding/json"
+	"math"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -36,6 +37,36 @@ func TestNewOptionEdgeCases(t *testing.T) {
 	if err == nil {
 		t.Fatal("expected error")
 	}
+	// similarity with wrong type
+	_, err = NewOption(map[string]any{"similarity": "not a number"})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	// relative with wrong type
+	_, err = NewOption(map[string]any{"relative": "1%"})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	// ulp which is not an integer
+	_, err = NewOption(map[string]any{"ulp": 1.5})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	// ulp with wrong type
+	_, err = NewOption(map[string]any{"ulp": "1"})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	// pattern with wrong type
+	_, err = NewOption(map[string]any{"pattern": 42})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	// pattern which is not a regular expression
+	_, err = NewOption(map[string]any{"pattern": "("})
+	if err == nil {
+		t.Fatal("expected error for invalid pattern")
+	}
 	// keys with wrong type
 	_, err = NewOption(map[string]any{"keys": "not an array"})
 	if err == nil {
@@ -186,6 +217,51 @@ func TestOptionJSON(t *testing.T) {
 	}, {
 		json:   `["DIFF_OFF"]`,
 		option: DIFF_OFF,
+	}, {
+		json:   `["DETECT_MOVES"]`,
+		option: DETECT_MOVES,
+	}, {
+		json:   `["AUTO_KEYS"]`,
+		option: AUTO_KEYS,
+	}, {
+		json:   `[{"relative":0.01}]`,
+		option: Relative(0.01),
+	}, {
+		json:   `[{"ulp":4}]`,
+		option: ULP(4),
+	}, {
+		json:   `[{"similarity":0.5}]`,
+		option: Similarity(0.5),
+	}, {
+		json:   `["COERCE"]`,
+		option: COERCE,
+	}, {
+		json:   `["IGNORE_CASE"]`,
+		option: IGNORE_CASE,
+	}, {
+		json:   `["TRIM_SPACE"]`,
+		option: TRIM_SPACE,
+	}, {
+		json:   `["COLLAPSE_SPACE"]`,
+		option: COLLAPSE_SPACE,
+	}, {
+		json:   `["NFC"]`,
+		option: NFC,
+	}, {
+		json:   `["NFKC"]`,
+		option: NFKC,
+	}, {
+		json:   `["EMBEDDED"]`,
+		option: EMBEDDED,
+	}, {
+		json:   `[{"@":["policy",["json"]],"^":["DIFF_OFF"]}]`,
+		option: PathOption(Path{PathKey("policy"), PathEmbedded("json")}, DIFF_OFF),
+	}, {
+		json:   `[{"@":["at"],"^":[{"pattern":"^\\d+$"}]}]`,
+		option: PathOption(Path{PathKey("at")}, Pattern(`^\d+$`)),
+	}, {
+		json:   `[{"@":["foo",null,[null],[]],"^":["DIFF_OFF"]}]`,
+		option: PathOption(Path{PathKey("foo"), PathAllValues{}, PathAllKeys{}, PathMultiset{}}, DIFF_OFF),
 	}, {
 		json:   `[{"file":"example.json"}]`,
 		option: File("example.json"),
@@ -236,6 +312,54 @@ func TestRefine(t *testing.T) {
 		element:   PathKey("foo"),
 		wantApply: []Option{SET},
 		wantRest:  nil,
+	}, {
+		name:      "path option for another key",
+		opts:      []Option{PathOption(Path{PathKey("foo"), PathKey("bar")}, SET)},
+		element:   PathKey("baz"),
+		wantApply: nil,
+		wantRest:  nil,
+	}, {
+		name:      "path option ending in set for another key",
+		opts:      []Option{PathOption(Path{PathKey("foo"), PathSet{}})},
+		element:   PathKey("baz"),
+		wantApply: nil,
+		wantRest:  nil,
+	}, {
+		name:      "all values matches index",
+		opts:      []Option{PathOption(Path{PathAllValues{}, PathKey("bar")}, SET)},
+		element:   PathIndex(3),
+		wantApply: nil,
+		wantRest:  []Option{PathOption(Path{PathKey("bar")}, SET)},
+	}, {
+		name:      "all values matches key",
+		opts:      []Option{PathOption(Path{PathAllValues{}}, SET)},
+		element:   PathKey("foo"),
+		wantApply: []Option{SET},
+		wantRest:  nil,
+	}, {
+		name:      "all keys matches key",
+		opts:      []Option{PathOption(Path{PathAllKeys{}, PathSet{}})},
+		element:   PathKey("foo"),
+		wantApply: []Option{SET},
+		wantRest:  nil,
+	}, {
+		name:      "all keys does not match index",
+		opts:      []Option{PathOption(Path{PathAllKeys{}}, SET)},
+		element:   PathIndex(0),
+		wantApply: nil,
+		wantRest:  nil,
+	}, {
+		name:      "root keeps options targeting children",
+		opts:      []Option{PathOption(Path{PathKey("foo")}, SET), PathOption(Path{PathKey("bar"), PathSet{}})},
+		element:   nil,
+		wantApply: nil,
+		wantRest:  []Option{PathOption(Path{PathKey("foo")}, SET), PathOption(Path{PathKey("bar"), PathSet{}})},
+	}, {
+		name:      "set keys never match",
+		opts:      []Option{PathOption(Path{PathSetKeys{"id": jsonNumber(1)}}, SET)},
+		element:   PathKey("id"),
+		wantApply: nil,
+		wantRest:  nil,
 	}}
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
@@ -386,6 +510,22 @@ func TestPathOption(t *testing.T) {
 		a:            `[{"nested":[1,2,3]}, {"nested":[4,5,6]}]`,
 		b:            `[{"nested":[4,5,6]}, {"nested":[1,2,3]}]`, // root as set (reordered), but nested lists should remain as lists
 		expectedDiff: strPtr(""),                                 // Should be empty since root array as set has same elements
+	}, {
+		name: "Wildcard index ignores a field in every list element",
+		opts: `[{"@":["items",null,"metadata","generation"],"^":["DIFF_OFF"]}]`,
+		a:    `{"items":[{"metadata":{"generation":1}},{"metadata":{"generation":1}}]}`,
+		b:    `{"items":[{"metadata":{"generation":2}},{"metadata":{"generation":5}}]}`,
+	}, {
+		name: "Wildcard key applies SET under every key",
+		opts: `[{"@":[[null],{}],"^":[]}]`,
+		a:    `{"a":[1,2],"b":[1,2]}`,
+		b:    `{"a":[2,1],"b":[2,1]}`,
+	}, {
+		name:         "Set path option does not apply to other keys",
+		opts:         `[{"@":["a",{}],"^":[]}]`,
+		a:            `{"a":[1,2],"b":[1,2]}`,
+		b:            `{"a":[2,1],"b":[2,1]}`,
+		expectedDiff: strPtr("@ [\"b\",0]\n[\n+ 2\n  1\n@ [\"b\",2]\n  1\n- 2\n]\n"),
 	}}
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
@@ -424,6 +564,30 @@ func TestPathOption(t *testing.T) {
 	}
 }
 
+func TestPathOptionEquals(t *testing.T) {
+	cases := []struct {
+		opts string
+		a, b string
+		want bool
+	}{
+		{`[{"@":["foo"],"^":[{"precision":0.1}]}]`, `{"foo":1.0}`, `{"foo":1.05}`, true},
+		{`[{"@":["foo"],"^":[{"precision":0.1}]}]`, `{"bar":1.0}`, `{"bar":1.05}`, false},
+		{`[{"@":[1],"^":[{"precision":0.1}]}]`, `[1,2.0]`, `[1,2.05]`, true},
+		{`[{"@":[0],"^":[{"precision":0.1}]}]`, `[1,2.0]`, `[1,2.05]`, false},
+		{`[{"@":["foo",{}],"^":[]}]`, `{"foo":[1,2]}`, `{"foo":[2,1]}`, true},
+		{`[{"@":["foo"],"^":["MULTISET"]}]`, `{"foo":[1,2,2]}`, `{"foo":[2,1,2]}`, true},
+	}
+	for _, c := range cases {
+		a, err := ReadJsonString(c.a)
+		require.NoError(t, err)
+		b, err := ReadJsonString(c.b)
+		require.NoError(t, err)
+		o, err := ReadOptionsString(c.opts)
+		require.NoError(t, err)
+		require.Equal(t, c.want, a.Equals(b, o...), "%v.Equals(%v, %v)", c.a, c.b, c.opts)
+	}
+}
+
 func TestDiffOnOffOption(t *testing.T) {
 	cases := []struct {
 		name         string
@@ -617,17 +781,58 @@ func TestValidateOptions(t *testing.T) {
 		name: "precision with SetKeys is valid",
 		opts: []Option{Precision(0.01), SetKeys("id")},
 	}, {
-		name:    "SET with precision is invalid",
-		opts:    []Option{SET, Precision(0.01)},
-		wantErr: true,
+		name: "SET with precision is valid",
+		opts: []Option{SET, Precision(0.01)},
 	}, {
-		name:    "MULTISET with precision is invalid",
-		opts:    []Option{MULTISET, Precision(0.01)},
-		wantErr: true,
+		name: "MULTISET with precision is valid",
+		opts: []Option{MULTISET, Precision(0.01)},
 	}, {
 		name:    "negative precision is invalid",
 		opts:    []Option{Precision(-0.1)},
 		wantErr: true,
+	}, {
+		name: "relative and ulp are valid",
+		opts: []Option{Relative(0.01), ULP(4), Precision(0.1)},
+	}, {
+		name: "SET with relative is valid",
+		opts: []Option{SET, Relative(0.01)},
+	}, {
+		name: "MULTISET with ulp is valid",
+		opts: []Option{MULTISET, ULP(1)},
+	}, {
+		name:    "negative relative is invalid",
+		opts:    []Option{Relative(-0.01)},
+		wantErr: true,
+	}, {
+		name:    "infinite relative is invalid",
+		opts:    []Option{Relative(math.Inf(1))},
+		wantErr: true,
+	}, {
+		name:    "NaN relative is invalid",
+		opts:    []Option{Relative(math.NaN())},
+		wantErr: true,
+	}, {
+		name:    "negative ulp is invalid",
+		opts:    []Option{ULP(-1)},
+		wantErr: true,
+	}, {
+		name:    "negative relative in a PathOption is invalid",
+		opts:    []Option{PathOption(Path{PathKey("a")}, Relative(-1))},
+		wantErr: true,
+	}, {
+		name: "relative in a PathOption is valid",
+		opts: []Option{PathOption(Path{PathKey("a")}, Relative(0.1))},
+	}, {
+		name: "similarity is valid",
+		opts: []Option{Similarity(1)},
+	}, {
+		name:    "zero similarity is invalid",
+		opts:    []Option{Similarity(0)},
+		wantErr: true,
+	}, {
+		name:    "similarity above one is invalid",
+		opts:    []Option{Similarity(1.5)},
+		wantErr: true,
 	}}
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
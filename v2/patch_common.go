@@ -1,12 +1,37 @@
 package jd
 
 import (
+	"context"
+	"errors"
 	"fmt"
 )
 
 func patchAll(n JsonNode, d Diff) (JsonNode, error) {
+	return patchAllContext(context.Background(), n, d)
+}
+
+// patchAllContext applies d to n hunk by hunk, stopping when ctx is
+// done.
+func patchAllContext(ctx context.Context, n JsonNode, d Diff) (JsonNode, error) {
 	var err error
 	for _, de := range d {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if de.From != nil {
+			n, err = patchFrom(n, de)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if de.Metadata.Unchecked {
+			n, err = patchUnchecked(n, de)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
 		strategy := strictPatchStrategy
 		if de.Metadata.Merge {
 			strategy = mergePatchStrategy
@@ -19,6 +44,27 @@ func patchAll(n JsonNode, d Diff) (JsonNode, error) {
 	return n, nil
 }
 
+// patchUnchecked applies a hunk which removes whatever value is found
+// at its path. An upsert hunk may also find none.
+func patchUnchecked(n JsonNode, de DiffElement) (JsonNode, error) {
+	var oldValues []JsonNode
+	oldValue, err := getPath(n, de.Path)
+	var notFoundErr *PathNotFoundError
+	switch {
+	case err == nil:
+		oldValues = []JsonNode{oldValue}
+	case de.Metadata.Upsert && errors.As(err, &notFoundErr) && len(notFoundErr.Path) == len(de.Path):
+		// The member is added.
+	default:
+		return nil, err
+	}
+	var newValues []JsonNode
+	if !isVoid(singleValue(de.Add)) {
+		newValues = de.Add
+	}
+	return n.patch(make(Path, 0), de.Path, nil, oldValues, newValues, nil, strictPatchStrategy)
+}
+
 func patch(
 	node JsonNode,
 	pathBehind, pathAhead Path,
@@ -102,9 +148,7 @@ func patchErrNonSetDiff(oldValues, newValues []JsonNode, path Path) (JsonNode, e
 }
 
 func patchErrExpectValue(want, found JsonNode, path Path) (JsonNode, error) {
-	return nil, fmt.Errorf(
-		"found %v at %v: expected %v",
-		found.Json(), path, want.Json())
+	return nil, &PatchConflictError{Path: path.clone(), Expected: want, Actual: found}
 }
 
 func patchErrMergeWithOldValue(path Path, oldValue JsonNode) (JsonNode, error) {
@@ -0,0 +1,940 @@
+package jd
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// PatchJsonText applies d to the JSON document src and returns the
+// patched text. Unlike rendering the result of Patch, each hunk only
+// rewrites the values at its path. Object key order, whitespace and the
+// formatting of untouched values are kept. New object keys are added
+// after the existing ones, following the layout of their siblings.
+func PatchJsonText(src string, d Diff) (string, error) {
+	return patchText(src, d, false)
+}
+
+// PatchYamlText applies d to the YAML document src and returns the
+// patched text. As with PatchJsonText each hunk only rewrites the lines
+// of the values at its path, so key order, comments, blank lines,
+// scalar styles and indentation are kept everywhere else. Values inside
+// flow collections like [1, 2] are written as JSON.
+func PatchYamlText(src string, d Diff) (string, error) {
+	return patchText(src, d, true)
+}
+
+// patchText applies the hunks of d to src one at a time, reading the
+// positions of the values again after each one.
+func patchText(src string, d Diff, isYaml bool) (string, error) {
+	a, err := readText(src, isYaml)
+	if err != nil {
+		return "", err
+	}
+	text := src
+	for i, de := range d {
+		void := isVoid(a)
+		b, err := a.Patch(Diff{de})
+		if err != nil {
+			return "", err
+		}
+		if void || isVoid(b) {
+			// No source text is left to keep.
+			b, err = b.Patch(d[i+1:])
+			if err != nil {
+				return "", err
+			}
+			return renderText(b, isYaml), nil
+		}
+		if de.From != nil && !de.Copy {
+			text, err = editText(text, isYaml, func(e *textEditor, root *textNode) {
+				e.removePath(root, de.From)
+			})
+			if err != nil { //jd:nocover — each edit leaves valid text
+				return "", err
+			}
+		}
+		text, err = editText(text, isYaml, func(e *textEditor, root *textNode) {
+			e.set(root, de, b)
+		})
+		if err != nil { //jd:nocover — each edit leaves valid text
+			return "", err
+		}
+		a = b
+	}
+	return text, nil
+}
+
+func readText(src string, isYaml bool) (JsonNode, error) {
+	if isYaml {
+		return ReadYamlString(src)
+	}
+	return ReadJsonString(src)
+}
+
+func renderText(n JsonNode, isYaml bool) string {
+	switch {
+	case isVoid(n):
+		return ""
+	case isYaml:
+		return n.Yaml()
+	}
+	return n.Json()
+}
+
+// editText reads the positions of the values of text, which is a
+// document and not void, and applies the edits made by edit.
+func editText(text string, isYaml bool, edit func(*textEditor, *textNode)) (string, error) {
+	e := &textEditor{src: text}
+	var root *textNode
+	var err error
+	if isYaml {
+		e.yaml = true
+		e.unit = yamlIndent(text)
+		root, err = readYamlText(text)
+	} else {
+		dec := json.NewDecoder(strings.NewReader(text))
+		dec.UseNumber()
+		root, err = readTextNode(dec, text, nil)
+	}
+	if err != nil { //jd:nocover — text was already read
+		return "", err
+	}
+	edit(e, root)
+	return e.apply(), nil
+}
+
+// textNode is a value with its position in the source text.
+type textNode struct {
+	start, end int
+	value      JsonNode
+	parent     *textNode
+	// Members of an object or array in source order. Keys are empty
+	// for arrays. A member starts at its key, or at the "-" of a YAML
+	// block sequence item.
+	keys     []string
+	keyEnds  []int
+	starts   []int
+	children []*textNode
+	// block is set on YAML block collections, whose members are lines
+	// starting at column indent.
+	block  bool
+	indent int
+}
+
+func (n *textNode) isObject() bool {
+	_, ok := n.value.(jsonObject)
+	return ok
+}
+
+func (n *textNode) isArray() bool {
+	_, ok := n.value.(jsonArray)
+	return ok
+}
+
+func (n *textNode) index(key string) int {
+	for i, k := range n.keys {
+		if k == key {
+			return i
+		}
+	}
+	return -1
+}
+
+func readTextNode(dec *json.Decoder, src string, parent *textNode) (*textNode, error) {
+	start := skipSeparators(src, int(dec.InputOffset()))
+	t, err := dec.Token()
+	if err != nil { //jd:nocover — src was already read as JSON
+		return nil, err
+	}
+	n := &textNode{start: start, parent: parent}
+	switch {
+	case isDelim(t, '{'):
+		o := newJsonObject()
+		for dec.More() {
+			keyStart := skipSeparators(src, int(dec.InputOffset()))
+			k, err := readKey(dec)
+			if err != nil { //jd:nocover — src was already read as JSON
+				return nil, err
+			}
+			keyEnd := int(dec.InputOffset())
+			child, err := readTextNode(dec, src, n)
+			if err != nil { //jd:nocover — src was already read as JSON
+				return nil, err
+			}
+			n.keys = append(n.keys, k)
+			n.keyEnds = append(n.keyEnds, keyEnd)
+			n.starts = append(n.starts, keyStart)
+			n.children = append(n.children, child)
+			o[k] = child.value
+		}
+		n.value = o
+	case isDelim(t, '['):
+		a := jsonArray{}
+		for dec.More() {
+			child, err := readTextNode(dec, src, n)
+			if err != nil { //jd:nocover — src was already read as JSON
+				return nil, err
+			}
+			n.keys = append(n.keys, "")
+			n.keyEnds = append(n.keyEnds, child.start)
+			n.starts = append(n.starts, child.start)
+			n.children = append(n.children, child)
+			a = append(a, child.value)
+		}
+		n.value = a
+	default:
+		n.value, err = NewJsonNode(t)
+		if err != nil { //jd:nocover — src was already read as JSON
+			return nil, err
+		}
+		n.end = int(dec.InputOffset())
+		return n, nil
+	}
+	if _, err := dec.Token(); err != nil { //jd:nocover — src was already read as JSON
+		return nil, err
+	}
+	n.end = int(dec.InputOffset())
+	return n, nil
+}
+
+// skipSeparators returns the position of the next token at or after i.
+func skipSeparators(src string, i int) int {
+	for i < len(src) && strings.IndexByte(" \t\r\n,:", src[i]) >= 0 {
+		i++
+	}
+	return i
+}
+
+// yamlTextReader reads the positions of the nodes of a YAML document.
+// The parser only records where a node starts, so its end is found by
+// scanning the text.
+type yamlTextReader struct {
+	src   string
+	lines []int
+}
+
+func readYamlText(src string) (*textNode, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(src), &doc); err != nil { //jd:nocover — src was already read as YAML
+		return nil, err
+	}
+	r := &yamlTextReader{src: src, lines: []int{0}}
+	for i := 0; i < len(src); i++ {
+		if src[i] == '\n' {
+			r.lines = append(r.lines, i+1)
+		}
+	}
+	return r.read(doc.Content[0], nil, -1), nil
+}
+
+// offset is the position in the text of the line and column of y.
+func (r *yamlTextReader) offset(y *yaml.Node) int {
+	i := r.lines[y.Line-1]
+	for col := 1; col < y.Column && i < len(r.src); col++ {
+		_, size := utf8.DecodeRuneInString(r.src[i:])
+		i += size
+	}
+	return i
+}
+
+// read reads y, the value of a member whose key or "-" is at column
+// owner.
+func (r *yamlTextReader) read(y *yaml.Node, parent *textNode, owner int) *textNode {
+	n := &textNode{start: r.skipProperties(r.offset(y)), parent: parent}
+	flow := parent != nil && !parent.block
+	switch y.Kind {
+	case yaml.MappingNode:
+		n.block = y.Style&yaml.FlowStyle == 0
+		n.indent = y.Column - 1
+		o := newJsonObject()
+		for i := 0; i+1 < len(y.Content); i += 2 {
+			k := y.Content[i]
+			keyStart := r.skipProperties(r.offset(k))
+			child := r.read(y.Content[i+1], n, k.Column-1)
+			n.keys = append(n.keys, k.Value)
+			n.keyEnds = append(n.keyEnds, r.scalarEnd(k, keyStart, !n.block, true, owner))
+			n.starts = append(n.starts, keyStart)
+			n.children = append(n.children, child)
+			o[k.Value] = child.value
+		}
+		n.value = o
+	case yaml.SequenceNode:
+		n.block = y.Style&yaml.FlowStyle == 0
+		n.indent = y.Column - 1
+		a := jsonArray{}
+		for _, item := range y.Content {
+			child := r.read(item, n, n.indent)
+			start := child.start
+			if n.block {
+				start = strings.LastIndexByte(r.src[:r.offset(item)], '-')
+			}
+			n.keys = append(n.keys, "")
+			n.keyEnds = append(n.keyEnds, start)
+			n.starts = append(n.starts, start)
+			n.children = append(n.children, child)
+			a = append(a, child.value)
+		}
+		n.value = a
+	default:
+		n.value = yamlValue(y)
+		n.end = r.scalarEnd(y, n.start, flow, false, owner)
+		return n
+	}
+	if n.block {
+		n.end = n.children[len(n.children)-1].end
+	} else {
+		n.end = r.flowEnd(n.start)
+	}
+	return n
+}
+
+// skipProperties skips the anchor and tag of the node at i.
+func (r *yamlTextReader) skipProperties(i int) int {
+	for i < len(r.src) && (r.src[i] == '&' || r.src[i] == '!') {
+		for i < len(r.src) && strings.IndexByte(" \t\n", r.src[i]) < 0 {
+			i++
+		}
+		for i < len(r.src) && strings.IndexByte(" \t\n", r.src[i]) >= 0 {
+			i++
+		}
+	}
+	return i
+}
+
+// scalarEnd returns the end of the scalar or alias y starting at i.
+func (r *yamlTextReader) scalarEnd(y *yaml.Node, i int, flow, key bool, owner int) int {
+	s := r.src
+	switch {
+	case y.Style&yaml.DoubleQuotedStyle != 0:
+		for i++; s[i] != '"'; i++ {
+			if s[i] == '\\' {
+				i++
+			}
+		}
+		return i + 1
+	case y.Style&yaml.SingleQuotedStyle != 0:
+		for i++; s[i] != '\'' || i+1 < len(s) && s[i+1] == '\''; i++ {
+			if s[i] == '\'' {
+				i++
+			}
+		}
+		return i + 1
+	case y.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0:
+		for i < len(s) && strings.IndexByte(" \t\n", s[i]) < 0 {
+			i++
+		}
+		return r.linesEnd(i, owner, true)
+	case y.Kind == yaml.ScalarNode && y.Value == "":
+		// An empty value like "a:".
+		return i
+	}
+	end := i
+	for ; i < len(s) && s[i] != '\n'; i++ {
+		c := s[i]
+		if c == '#' && i > 0 && (s[i-1] == ' ' || s[i-1] == '\t') ||
+			flow && strings.IndexByte(",[]{}", c) >= 0 ||
+			key && c == ':' && (i+1 == len(s) || strings.IndexByte(" \t\n,[]{}", s[i+1]) >= 0) {
+			break
+		}
+		if c != ' ' && c != '\t' {
+			end = i + 1
+		}
+	}
+	if flow || key {
+		return end
+	}
+	// A plain scalar goes on over more indented lines.
+	return r.linesEnd(end, owner, false)
+}
+
+// linesEnd returns the end of the lines after i which are indented
+// more than owner, and comments too when they belong to a block scalar.
+func (r *yamlTextReader) linesEnd(end, owner int, comments bool) int {
+	s := r.src
+	for i := lineEnd(s, end); i < len(s); i = lineEnd(s, i+1) {
+		line := s[i+1 : lineEnd(s, i+1)]
+		trimmed := strings.TrimLeft(line, " \t")
+		switch {
+		case trimmed == "":
+			continue
+		case len(line)-len(trimmed) <= owner,
+			!comments && strings.HasPrefix(trimmed, "#"):
+			return end
+		}
+		end = i + 1 + len(strings.TrimRight(line, " \t\r"))
+	}
+	return end
+}
+
+// flowEnd returns the end of the flow collection starting at i.
+func (r *yamlTextReader) flowEnd(i int) int {
+	s := r.src
+	depth := 0
+	for ; ; i++ {
+		switch c := s[i]; {
+		case c == '[' || c == '{':
+			depth++
+		case c == ']' || c == '}':
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		case c == '"':
+			for i++; s[i] != '"'; i++ {
+				if s[i] == '\\' {
+					i++
+				}
+			}
+		case c == '\'':
+			for i++; s[i] != '\'' || i+1 < len(s) && s[i+1] == '\''; i++ {
+				if s[i] == '\'' {
+					i++
+				}
+			}
+		case c == '#' && (s[i-1] == ' ' || s[i-1] == '\t' || s[i-1] == '\n'):
+			i = lineEnd(s, i)
+		}
+	}
+}
+
+// lineStart returns the start of the line of i.
+func lineStart(s string, i int) int {
+	return strings.LastIndexByte(s[:i], '\n') + 1
+}
+
+// lineEnd returns the position of the newline ending the line of i.
+func lineEnd(s string, i int) int {
+	if j := strings.IndexByte(s[i:], '\n'); j >= 0 {
+		return i + j
+	}
+	return len(s)
+}
+
+type textEdit struct {
+	start, end int
+	text       string
+}
+
+// textEditor collects non-overlapping edits of src.
+type textEditor struct {
+	src   string
+	edits []textEdit
+	// yaml is set when src is YAML, whose block collections are
+	// edited by lines indented by unit.
+	yaml bool
+	unit int
+}
+
+func (e *textEditor) replaceText(start, end int, text string) {
+	e.edits = append(e.edits, textEdit{start, end, text})
+}
+
+func (e *textEditor) apply() string {
+	// Later edits first so the positions of earlier ones hold. An
+	// insertion after a removal starts where the removal ends.
+	sort.SliceStable(e.edits, func(i, j int) bool {
+		return e.edits[i].start > e.edits[j].start
+	})
+	out := e.src
+	for _, edit := range e.edits {
+		out = out[:edit.start] + edit.text + out[edit.end:]
+	}
+	return out
+}
+
+// walkText follows p in the source from n and in b from nb, as far as
+// both have its elements. It returns the nodes reached and the rest of
+// p.
+func walkText(n *textNode, nb JsonNode, p Path) (*textNode, JsonNode, Path) {
+	for i, el := range p {
+		j, next := -1, JsonNode(nil)
+		switch el := el.(type) {
+		case PathKey:
+			o, ok := nb.(jsonObject)
+			if v, found := o[string(el)]; ok && found && n.isObject() {
+				j, next = n.index(string(el)), v
+			}
+		case PathIndex:
+			l, ok := elements(nb)
+			if ok && n.isArray() && int(el) >= 0 && int(el) < len(n.children) && int(el) < len(l) {
+				j, next = int(el), l[el]
+			}
+		case PathSetKeys:
+			j, next = findKeyed(n, nb, el)
+		}
+		if j < 0 {
+			return n, nb, p[i:]
+		}
+		n, nb = n.children[j], next
+	}
+	return n, nb, nil
+}
+
+// elements returns the elements of an array in any of its forms.
+func elements(n JsonNode) ([]JsonNode, bool) {
+	switch n := n.(type) {
+	case jsonArray:
+		return n, true
+	case jsonList:
+		return n, true
+	case jsonSet:
+		return n, true
+	case jsonMultiset:
+		return n, true
+	}
+	return nil, false
+}
+
+// findKeyed finds the object with keys among the members of n and
+// among the elements of nb.
+func findKeyed(n *textNode, nb JsonNode, keys PathSetKeys) (int, JsonNode) {
+	match := func(v JsonNode) bool {
+		o, ok := v.(jsonObject)
+		if !ok {
+			return false
+		}
+		for k, want := range keys {
+			if got, ok := o[k]; !ok || !got.Equals(want) {
+				return false
+			}
+		}
+		return true
+	}
+	l, _ := elements(nb)
+	for _, v := range l {
+		if !match(v) {
+			continue
+		}
+		for j, c := range n.children {
+			if match(c.value) {
+				return j, v
+			}
+		}
+	}
+	return -1, nil //jd:nocover — Patch found the object in the source
+}
+
+// set edits the text at the path of de so that it reads as b there.
+func (e *textEditor) set(root *textNode, de DiffElement, b JsonNode) {
+	if len(de.Path) == 0 {
+		e.replace(root, b)
+		return
+	}
+	last := de.Path[len(de.Path)-1]
+	n, nb, rest := walkText(root, b, de.Path[:len(de.Path)-1])
+	if len(rest) > 0 {
+		// A parent is missing from the source, as when a merge patch
+		// adds nested objects.
+		last = rest[0]
+	}
+	l, isArray := elements(nb)
+	switch el := last.(type) {
+	case PathKey:
+		o, ok := nb.(jsonObject)
+		if !ok || !n.isObject() {
+			e.replace(n, nb)
+			return
+		}
+		j := n.index(string(el))
+		v, found := o[string(el)]
+		switch {
+		case j >= 0 && found:
+			e.replace(n.children[j], v)
+		case j >= 0:
+			e.edit(n, removedAt(n, j, 1), 0, nil, nil)
+		case found:
+			e.edit(n, nil, len(n.children), []string{string(el)}, []JsonNode{v})
+		}
+	case PathIndex:
+		if !isArray || !n.isArray() || len(rest) > 0 { //jd:nocover — Patch found the list at the path
+			e.replace(n, nb)
+			return
+		}
+		i := int(el)
+		switch {
+		case de.From != nil:
+			// A move or copy adds one value.
+			if i < 0 {
+				i = len(l) - 1
+			}
+			e.splice(n, i, 0, l[i:i+1])
+		case i < 0:
+			e.splice(n, len(n.children), 0, l[len(l)-len(de.Add):])
+		case de.Metadata.Unchecked && len(l) < len(n.children):
+			// A JSON Patch remove adds void.
+			e.splice(n, i, 1, nil)
+		case de.Metadata.Unchecked:
+			e.splice(n, i, 1, l[i:i+1])
+		default:
+			e.splice(n, i, len(de.Remove), l[i:i+len(de.Add)])
+		}
+	case PathSet, PathMultiset:
+		if !n.isArray() || len(rest) > 0 {
+			e.replace(n, nb)
+			return
+		}
+		// A set drops every member equal to a removed value, a
+		// multiset only one for each.
+		_, all := last.(PathSet)
+		removed := make([]bool, len(n.children))
+		for _, v := range de.Remove {
+			found := false
+			for j, c := range n.children {
+				if !removed[j] && c.value.Equals(v) && (all || !found) {
+					removed[j] = true
+					found = true
+				}
+			}
+			if !found { //jd:nocover — Patch found each removed value
+				e.replace(n, nb)
+				return
+			}
+		}
+		e.edit(n, removed, len(n.children), nil, de.Add)
+	default:
+		e.replace(n, nb)
+	}
+}
+
+// removePath removes the value at p, the source of a move.
+func (e *textEditor) removePath(root *textNode, p Path) {
+	parent, _, rest := walkText(root, root.value, p[:len(p)-1])
+	if len(rest) > 0 { //jd:nocover — the move was patched, so p is found
+		return
+	}
+	j := -1
+	switch el := p[len(p)-1].(type) {
+	case PathKey:
+		j = parent.index(string(el))
+	case PathIndex:
+		j = int(el)
+	}
+	if j < 0 || j >= len(parent.children) { //jd:nocover — the move was patched, so p is found
+		return
+	}
+	e.edit(parent, removedAt(parent, j, 1), 0, nil, nil)
+}
+
+func removedAt(n *textNode, i, count int) []bool {
+	removed := make([]bool, len(n.children))
+	for j := i; j < i+count; j++ {
+		removed[j] = true
+	}
+	return removed
+}
+
+// splice replaces count members of the array n from index i with
+// values, pairing them with the members they replace.
+func (e *textEditor) splice(n *textNode, i, count int, values []JsonNode) {
+	paired := min(count, len(values))
+	for j := 0; j < paired; j++ {
+		e.replace(n.children[i+j], values[j])
+	}
+	e.edit(n, removedAt(n, i+paired, count-paired), i+paired, nil, values[paired:])
+}
+
+// edit removes the members of n which are removed and inserts values
+// before the member at. Keys are given when n is an object. When none
+// of its members are kept n is replaced.
+func (e *textEditor) edit(n *textNode, removed []bool, at int, keys []string, values []JsonNode) {
+	if removed == nil {
+		removed = make([]bool, len(n.children))
+	}
+	kept := false
+	for j, r := range removed {
+		if !r {
+			kept = true
+		} else if n.block && !e.ownLine(n.starts[j]) {
+			// A member after the "-" of another, as in "- - a", has no
+			// lines of its own.
+			kept = false
+			break
+		}
+	}
+	if !kept {
+		e.replace(n, n.without(removed, at, keys, values))
+		return
+	}
+	if n.block {
+		for j, r := range removed {
+			if r {
+				e.replaceText(e.blockStart(n, j), e.blockEnd(n, j), "")
+			}
+		}
+	} else {
+		e.remove(n, removed)
+	}
+	if len(values) == 0 {
+		return
+	}
+	text := ""
+	switch {
+	case n.block:
+		for j, v := range values {
+			text += e.blockMember(n, keys, j, v) + "\n"
+		}
+		if at == 0 {
+			// Insert on the line of the first member, which is
+			// already indented.
+			e.replaceText(n.starts[0], n.starts[0], text[n.indent:]+strings.Repeat(" ", n.indent))
+			return
+		}
+		pos := e.blockEnd(n, at-1)
+		if pos == len(e.src) && !strings.HasSuffix(e.src, "\n") {
+			text = "\n" + strings.TrimSuffix(text, "\n")
+		}
+		e.replaceText(pos, pos, text)
+	case at == 0:
+		for j, v := range values {
+			text += e.flowMember(n, keys, j, v) + e.separator(n)
+		}
+		e.replaceText(n.starts[0], n.starts[0], text)
+	default:
+		for j, v := range values {
+			text += e.separator(n) + e.flowMember(n, keys, j, v)
+		}
+		e.replaceText(n.children[at-1].end, n.children[at-1].end, text)
+	}
+}
+
+// without returns the value of n without the members which are removed
+// and with values inserted before the member at.
+func (n *textNode) without(removed []bool, at int, keys []string, values []JsonNode) JsonNode {
+	if n.isObject() {
+		o := newJsonObject()
+		for j, k := range n.keys {
+			if !removed[j] {
+				o[k] = n.children[j].value
+			}
+		}
+		for j, k := range keys {
+			o[k] = values[j]
+		}
+		return o
+	}
+	a := jsonArray{}
+	for j, c := range n.children {
+		if j == at {
+			a = append(a, values...)
+		}
+		if !removed[j] {
+			a = append(a, c.value)
+		}
+	}
+	if at == len(n.children) {
+		a = append(a, values...)
+	}
+	return a
+}
+
+// remove deletes the members of n which are removed along with one
+// adjacent separator. At least one member must be kept.
+func (e *textEditor) remove(n *textNode, removed []bool) {
+	keptBefore := false
+	for i, r := range removed {
+		switch {
+		case !r:
+			keptBefore = true
+		case keptBefore:
+			e.replaceText(n.children[i-1].end, n.children[i].end, "")
+		default:
+			e.replaceText(n.starts[i], n.starts[i+1], "")
+		}
+	}
+}
+
+// separator is the text between two members of n, such as ",\n  ".
+func (e *textEditor) separator(n *textNode) string {
+	if len(n.children) > 1 {
+		return e.src[n.children[0].end:n.starts[1]]
+	}
+	// The whitespace before the only member.
+	space := e.src[n.start+1 : n.starts[0]]
+	if e.yaml && !strings.Contains(space, "\n") {
+		// As in [1, 2].
+		space = " "
+	}
+	return "," + space
+}
+
+// flowMember renders member j of values inserted into the JSON value
+// or YAML flow collection n.
+func (e *textEditor) flowMember(n *textNode, keys []string, j int, v JsonNode) string {
+	if keys == nil {
+		return v.Json()
+	}
+	colon := e.src[n.keyEnds[0]:n.children[0].start]
+	return jsonString(keys[j]).Json() + colon + v.Json()
+}
+
+// blockMember renders member j of values inserted into the YAML block
+// collection n as lines indented like its members.
+func (e *textEditor) blockMember(n *textNode, keys []string, j int, v JsonNode) string {
+	var key *string
+	if keys != nil {
+		key = &keys[j]
+	}
+	return indentLines(e.yamlMember(key, v), n.indent)
+}
+
+// replace replaces the text of n with v.
+func (e *textEditor) replace(n *textNode, v JsonNode) {
+	if !e.yaml || n.parent != nil && !n.parent.block {
+		e.replaceText(n.start, n.end, v.Json())
+		return
+	}
+	l, isArray := elements(v)
+	o, isObject := v.(jsonObject)
+	if (n.isObject() || n.isArray()) && !n.block && (isArray || isObject) {
+		// Flow collections stay flow collections.
+		e.replaceText(n.start, n.end, v.Json())
+		return
+	}
+	text := yamlText(v, e.unit)
+	if !n.block && len(l) == 0 && len(o) == 0 && !strings.Contains(text, "\n") {
+		if n.start == n.end {
+			// An empty value like "a:".
+			text = " " + text
+		}
+		e.replaceText(n.start, n.end, text)
+		return
+	}
+	end := lineEnd(e.src, n.end)
+	p := n.parent
+	if p == nil {
+		e.replaceText(n.start, end, yamlText(v, e.unit))
+		return
+	}
+	j := 0
+	for p.children[j] != n {
+		j++
+	}
+	var key *string
+	if p.isObject() {
+		key = &p.keys[j]
+	}
+	member := indentLines(e.yamlMember(key, v), p.indent)
+	e.replaceText(p.starts[j], end, member[p.indent:])
+}
+
+// ownLine reports whether the member starting at i begins its line.
+func (e *textEditor) ownLine(i int) bool {
+	return strings.TrimLeft(e.src[lineStart(e.src, i):i], " ") == ""
+}
+
+// blockStart returns the start of the lines of member j of n, with
+// the comments right above it.
+func (e *textEditor) blockStart(n *textNode, j int) int {
+	s := lineStart(e.src, n.starts[j])
+	for s > 0 {
+		prev := lineStart(e.src, s-1)
+		line := e.src[prev : s-1]
+		trimmed := strings.TrimLeft(line, " ")
+		if !strings.HasPrefix(trimmed, "#") || len(line)-len(trimmed) != n.indent {
+			break
+		}
+		s = prev
+	}
+	return s
+}
+
+// blockEnd returns the end of the lines of member j of n, after its
+// newline and the more indented comments below it.
+func (e *textEditor) blockEnd(n *textNode, j int) int {
+	i := lineEnd(e.src, n.children[j].end)
+	for i < len(e.src) {
+		line := e.src[i+1 : lineEnd(e.src, i+1)]
+		trimmed := strings.TrimLeft(line, " ")
+		if !strings.HasPrefix(trimmed, "#") || len(line)-len(trimmed) <= n.indent {
+			break
+		}
+		i = lineEnd(e.src, i+1)
+	}
+	if i < len(e.src) {
+		i++
+	}
+	return i
+}
+
+// yamlMember renders v as a mapping member with key, or as a sequence
+// item when key is nil, without a final newline.
+func (e *textEditor) yamlMember(key *string, v JsonNode) string {
+	n := &yaml.Node{Kind: yaml.SequenceNode, Content: []*yaml.Node{newYamlNode(v)}}
+	if key != nil {
+		n = &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{
+			newYamlNode(jsonString(*key)), newYamlNode(v),
+		}}
+	}
+	return encodeYaml(n, e.unit)
+}
+
+// yamlText renders v as a YAML document without a final newline.
+func yamlText(v JsonNode, indent int) string {
+	return encodeYaml(newYamlNode(v), indent)
+}
+
+func encodeYaml(n *yaml.Node, indent int) string {
+	out := &bytes.Buffer{}
+	enc := yaml.NewEncoder(out)
+	enc.SetIndent(indent)
+	if err := enc.Encode(n); err != nil { //jd:nocover — the node was built from valid values
+		panic(err)
+	}
+	return strings.TrimSuffix(out.String(), "\n")
+}
+
+// indentLines indents each line of s which is not empty.
+func indentLines(s string, indent int) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = strings.Repeat(" ", indent) + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// yamlValue reads the value of a node parsed from valid YAML.
+func yamlValue(n *yaml.Node) JsonNode {
+	var v interface{}
+	if err := n.Decode(&v); err != nil { //jd:nocover — n was parsed from valid YAML
+		panic(err)
+	}
+	node, err := NewJsonNode(v)
+	if err != nil { //jd:nocover — decoded YAML values are supported
+		panic(err)
+	}
+	return node
+}
+
+func newYamlNode(v JsonNode) *yaml.Node {
+	n := &yaml.Node{}
+	if err := n.Encode(v.raw()); err != nil { //jd:nocover — values always encode
+		panic(err)
+	}
+	return n
+}
+
+// yamlIndent guesses the indentation of src from its least indented
+// nested line.
+func yamlIndent(src string) int {
+	indent := 0
+	for _, line := range strings.Split(src, "\n") {
+		trimmed := strings.TrimLeft(line, " ")
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if n := len(line) - len(trimmed); n > 0 && (indent == 0 || n < indent) {
+			indent = n
+		}
+	}
+	if indent < 2 {
+		return 2
+	}
+	return indent
+}
@@ -0,0 +1,607 @@
+package jd
+
+import (
+	"testing"
+)
+
+func TestPatchJsonText(t *testing.T) {
+	cases := []struct {
+		name string
+		src  string
+		b    string
+		want string
+	}{{
+		name: "no change",
+		src:  `{ "b": 1.0, "a": [1, 2] }`,
+		b:    `{"a":[1,2],"b":1}`,
+		want: `{ "b": 1.0, "a": [1, 2] }`,
+	}, {
+		name: "scalar in nested object",
+		src: s(
+			`{`,
+			`  "name": "web",`,
+			`  "spec": {`,
+			`    "replicas": 1,`,
+			`    "ratio": 1.50`,
+			`  }`,
+			`}`,
+		),
+		b: `{"name":"web","spec":{"replicas":3,"ratio":1.5}}`,
+		want: s(
+			`{`,
+			`  "name": "web",`,
+			`  "spec": {`,
+			`    "replicas": 3,`,
+			`    "ratio": 1.50`,
+			`  }`,
+			`}`,
+		),
+	}, {
+		name: "keys added after existing keys",
+		src: s(
+			`{`,
+			`  "z": 1,`,
+			`  "a": 2`,
+			`}`,
+		),
+		b: `{"z":1,"a":2,"m":{"x":true},"b":null}`,
+		want: s(
+			`{`,
+			`  "z": 1,`,
+			`  "a": 2,`,
+			`  "b": null,`,
+			`  "m": {"x":true}`,
+			`}`,
+		),
+	}, {
+		name: "key added to single key object",
+		src: s(
+			`{`,
+			`    "a" : 1`,
+			`}`,
+		),
+		b: `{"a":1,"b":2}`,
+		want: s(
+			`{`,
+			`    "a" : 1,`,
+			`    "b" : 2`,
+			`}`,
+		),
+	}, {
+		name: "keys removed",
+		src: s(
+			`{`,
+			`  "a": 1,`,
+			`  "b": 2,`,
+			`  "c": 3,`,
+			`  "d": 4`,
+			`}`,
+		),
+		b: `{"c":3}`,
+		want: s(
+			`{`,
+			`  "c": 3`,
+			`}`,
+		),
+	}, {
+		name: "last key replaced",
+		src:  `{"a":1,"b":2}`,
+		b:    `{"a":1,"c":3}`,
+		want: `{"a":1,"c":3}`,
+	}, {
+		name: "all keys replaced",
+		src:  `{ "a": 1 }`,
+		b:    `{"b":2}`,
+		want: `{"b":2}`,
+	}, {
+		name: "keys added to empty object",
+		src:  `{ }`,
+		b:    `{"a":1}`,
+		want: `{"a":1}`,
+	}, {
+		name: "element changed",
+		src:  `[1, {"a": "x", "b": "y"}, 3]`,
+		b:    `[1,{"a":"x","b":"z"},3]`,
+		want: `[1, {"a": "x", "b": "z"}, 3]`,
+	}, {
+		name: "elements removed",
+		src:  `[1, 2, 3, 4]`,
+		b:    `[1,4]`,
+		want: `[1, 4]`,
+	}, {
+		name: "leading elements removed",
+		src:  `[1, 2, 3]`,
+		b:    `[3]`,
+		want: `[3]`,
+	}, {
+		name: "all elements removed",
+		src:  `[1, 2]`,
+		b:    `[]`,
+		want: `[]`,
+	}, {
+		name: "elements inserted",
+		src: s(
+			`[`,
+			`  1,`,
+			`  4`,
+			`]`,
+		),
+		b: `[1,2,3,4]`,
+		want: s(
+			`[`,
+			`  1,`,
+			`  2,`,
+			`  3,`,
+			`  4`,
+			`]`,
+		),
+	}, {
+		name: "elements inserted first",
+		src:  `[3]`,
+		b:    `[1,2,3]`,
+		want: `[1,2,3]`,
+	}, {
+		name: "elements changed and appended",
+		src:  `[0, 1]`,
+		b:    `[0,2,3]`,
+		want: `[0, 2, 3]`,
+	}, {
+		name: "type changed",
+		src:  `{"a": [1], "b": {"c": 1}}`,
+		b:    `{"a":{"x":1},"b":[1]}`,
+		want: `{"a": {"x":1}, "b": [1]}`,
+	}, {
+		name: "void to value",
+		src:  ``,
+		b:    `{"a":1}`,
+		want: `{"a":1}`,
+	}, {
+		name: "value to void",
+		src:  `{"a":1}`,
+		b:    ``,
+		want: ``,
+	}}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			a, err := ReadJsonString(c.src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := ReadJsonString(c.b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := PatchJsonText(c.src, a.Diff(b))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != c.want {
+				t.Errorf("got:\n%v\nwant:\n%v", got, c.want)
+			}
+			n, err := ReadJsonString(got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !n.Equals(b) {
+				t.Errorf("got %v. want %v", n.Json(), b.Json())
+			}
+		})
+	}
+}
+
+func TestPatchYamlText(t *testing.T) {
+	cases := []struct {
+		name string
+		src  string
+		b    string
+		want string
+	}{{
+		name: "comments and key order",
+		src: s(
+			`# deployment`,
+			`name: web`,
+			`spec:`,
+			`  replicas: 1 # scaled by hand`,
+			`  image: "web:1.0"`,
+			`  ports:`,
+			`    - 80`,
+			`    - 443`,
+			`  old: true`,
+		),
+		b: `{"name":"web","spec":{"replicas":3,"image":"web:1.0","ports":[80,8080,443],"env":{"a":"x"}}}`,
+		want: s(
+			`# deployment`,
+			`name: web`,
+			`spec:`,
+			`  replicas: 3 # scaled by hand`,
+			`  image: "web:1.0"`,
+			`  ports:`,
+			`    - 80`,
+			`    - 8080`,
+			`    - 443`,
+			`  env:`,
+			`    a: x`,
+		),
+	}, {
+		name: "elements changed and removed",
+		src: s(
+			`- a`,
+			`- b`,
+			`- c`,
+			`- d`,
+		),
+		b: `["a","x"]`,
+		want: s(
+			`- a`,
+			`- x`,
+		),
+	}, {
+		name: "type changed",
+		src: s(
+			`a: 1 # one`,
+		),
+		b: `{"a":[1]}`,
+		want: s(
+			`a:`,
+			`  - 1`,
+		),
+	}, {
+		name: "comment removed with its element",
+		src: s(
+			`- 1`,
+			`- 2 # two`,
+			`- 3`,
+		),
+		b: `[1,3,4]`,
+		want: s(
+			`- 1`,
+			`- 3`,
+			`- 4`,
+		),
+	}, {
+		name: "blank lines kept",
+		src: s(
+			`a: 1`,
+			``,
+			`# b`,
+			`b:`,
+			`  c: 1`,
+			``,
+			`  d: 2`,
+			``,
+			`e: 3`,
+		),
+		b: `{"a":1,"b":{"c":2,"d":2},"e":4}`,
+		want: s(
+			`a: 1`,
+			``,
+			`# b`,
+			`b:`,
+			`  c: 2`,
+			``,
+			`  d: 2`,
+			``,
+			`e: 4`,
+		),
+	}, {
+		name: "member removed with its comments",
+		src: s(
+			`a: 1`,
+			`# about b`,
+			`b:`,
+			`  - x`,
+			`  # more about b`,
+			`c: 3`,
+		),
+		b: `{"a":1,"c":3}`,
+		want: s(
+			`a: 1`,
+			`c: 3`,
+		),
+	}, {
+		name: "flow collections",
+		src: s(
+			`a: [1, 2, 3] # list`,
+			`b: {x: 1, y: 2}`,
+		),
+		b: `{"a":[1,3,{"z":1}],"b":{"x":1,"z":"q"}}`,
+		want: s(
+			`a: [1, 3, {"z":1}] # list`,
+			`b: {x: 1, "z": "q"}`,
+		),
+	}, {
+		name: "quotes and comments in flow collections",
+		src: s(
+			`a: ["x]", 'y''}', # z`,
+			`  "w\"", 1]`,
+			`b: [1, 2]`,
+		),
+		b: `{"a":["x]","y'}","w\"",2],"b":{"c":1}}`,
+		want: s(
+			`a: ["x]", 'y''}', # z`,
+			`  "w\"", 2]`,
+			`b: {"c":1}`,
+		),
+	}, {
+		name: "scalar styles",
+		src: s(
+			`a: 'one''s'`,
+			`b: "t\"wo"`,
+			`c: |`,
+			`  three`,
+			`  lines`,
+			`d: plain`,
+			`  continued`,
+			`e: &x !!str five`,
+			`f: 6`,
+		),
+		b: `{"a":"one's","b":"2","c":"3","d":"4","e":"five","f":7}`,
+		want: s(
+			`a: 'one''s'`,
+			`b: "2"`,
+			`c: "3"`,
+			`d: "4"`,
+			`e: &x !!str five`,
+			`f: 7`,
+		),
+	}, {
+		name: "empty values",
+		src: s(
+			`a:`,
+			`b: ~`,
+			`c:`,
+		),
+		b: `{"a":1,"b":{"x":1},"c":null}`,
+		want: s(
+			`a: 1`,
+			`b:`,
+			`  x: 1`,
+			`c:`,
+		),
+	}, {
+		name: "elements inserted first",
+		src: s(
+			`a:`,
+			`- 3`,
+			`b:`,
+			`  - - x`,
+		),
+		b: `{"a":[1,2,3],"b":[["w","x"]]}`,
+		want: s(
+			`a:`,
+			`- 1`,
+			`- 2`,
+			`- 3`,
+			`b:`,
+			`  - - w`,
+			`    - x`,
+		),
+	}, {
+		name: "compact element removed",
+		src: s(
+			`- - a: 1`,
+			`    b: 2`,
+			`  - c`,
+		),
+		b: `[[{"b":2},"c"]]`,
+		want: s(
+			`- - b: 2`,
+			`  - c`,
+		),
+	}, {
+		name: "compact first element removed",
+		src: s(
+			`- - a`,
+			`  - b`,
+		),
+		b: `[["b"]]`,
+		want: s(
+			`- - b`,
+		),
+	}, {
+		name: "key added without trailing newline",
+		src:  `a: 1`,
+		b:    `{"a":1,"b":[1]}`,
+		want: "a: 1\nb:\n  - 1",
+	}, {
+		name: "root replaced",
+		src:  s(`# scalar`, `1`),
+		b:    `{"a":1}`,
+		want: s(`# scalar`, `a: 1`),
+	}, {
+		name: "void to value",
+		src:  ``,
+		b:    `{"a":1}`,
+		want: s(`a: 1`),
+	}}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			a, err := ReadYamlString(c.src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := ReadJsonString(c.b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := PatchYamlText(c.src, a.Diff(b))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != c.want {
+				t.Errorf("got:\n%v\nwant:\n%v", got, c.want)
+			}
+			n, err := ReadYamlString(got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !n.Equals(b) {
+				t.Errorf("got %v. want %v", n.Json(), b.Json())
+			}
+		})
+	}
+}
+
+func TestPatchTextErrors(t *testing.T) {
+	d, err := ReadDiffString(s(
+		`@ ["a"]`,
+		`- 2`,
+		`+ 3`,
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, src := range []string{`{"a":`, `{"a":1}`} {
+		if _, err := PatchJsonText(src, d); err == nil {
+			t.Errorf("wanted error patching %q", src)
+		}
+	}
+	for _, src := range []string{`a: [`, `a: 1`} {
+		if _, err := PatchYamlText(src, d); err == nil {
+			t.Errorf("wanted error patching %q", src)
+		}
+	}
+	// Hunks after the document is removed still apply.
+	d, err = ReadDiffString(s(
+		`@ []`,
+		`- {"a":1}`,
+		`@ ["a"]`,
+		`- 1`,
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := PatchJsonText(`{"a":1}`, d); err == nil {
+		t.Errorf("wanted error patching removed document")
+	}
+}
+
+func TestPatchTextDiffs(t *testing.T) {
+	cases := []struct {
+		name     string
+		read     func(string) (Diff, error)
+		diff     string
+		json     string
+		wantJson string
+		yaml     string
+		wantYaml string
+	}{{
+		name:     "merge nested objects",
+		read:     ReadMergeString,
+		diff:     `{"a":{"b":{"c":1}},"d":null}`,
+		json:     `{"d": 1, "e": 2}`,
+		wantJson: `{"e": 2, "a": {"b":{"c":1}}}`,
+		yaml:     s(`d: 1`, `e: 2`),
+		wantYaml: s(`e: 2`, `a:`, `  b:`, `    c: 1`),
+	}, {
+		name:     "patch replace and remove",
+		read:     ReadPatchString,
+		diff:     `[{"op":"replace","path":"/0","value":"x"},{"op":"remove","path":"/1"}]`,
+		json:     `["a", "b", "c"]`,
+		wantJson: `["x", "c"]`,
+		yaml:     s(`- a`, `- b`, `- c`),
+		wantYaml: s(`- x`, `- c`),
+	}, {
+		name:     "patch add and append",
+		read:     ReadPatchString,
+		diff:     `[{"op":"add","path":"/1","value":"x"},{"op":"add","path":"/-","value":"y"}]`,
+		json:     `["a", "b"]`,
+		wantJson: `["a", "x", "b", "y"]`,
+		yaml:     s(`- a`, `- b`),
+		wantYaml: s(`- a`, `- x`, `- b`, `- "y"`),
+	}, {
+		name:     "move and copy",
+		read:     ReadPatchString,
+		diff:     `[{"op":"move","from":"/a","path":"/b"},{"op":"copy","from":"/b","path":"/c/-"}]`,
+		json:     `{"a": 1, "c": [0]}`,
+		wantJson: `{"c": [0,1],"b": 1}`,
+		yaml:     s(`a: 1 # one`, `c:`, `  - 0`),
+		wantYaml: s(`c:`, `  - 0`, `  - 1`, `b: 1`),
+	}, {
+		name:     "move within a list",
+		read:     ReadPatchString,
+		diff:     `[{"op":"move","from":"/0","path":"/1"}]`,
+		json:     `["a", "b"]`,
+		wantJson: `["b","a"]`,
+		yaml:     s(`- a`, `- b`),
+		wantYaml: s(`- b`, `- a`),
+	}, {
+		name:     "merge into scalar",
+		read:     ReadMergeString,
+		diff:     `{"a":{"b":1}}`,
+		json:     `{"a": 1}`,
+		wantJson: `{"a": {"b":1}}`,
+		yaml:     s(`a: 1`),
+		wantYaml: s(`a:`, `  b: 1`),
+	}, {
+		name:     "set hunk on a scalar",
+		read:     ReadDiffString,
+		diff:     s(`@ ["a",{}]`, `- 1`, `+ 2`),
+		json:     `{"a": 1}`,
+		wantJson: `{"a": 2}`,
+		yaml:     s(`a: 1 # one`),
+		wantYaml: s(`a: 2 # one`),
+	}, {
+		name:     "embedded",
+		read:     ReadDiffString,
+		diff:     s(`@ ["a",["json"],0]`, `- 1`, `+ 2`),
+		json:     `{"a": "[1]"}`,
+		wantJson: `{"a": "[2]"}`,
+		yaml:     s(`a: '[1]'`),
+		wantYaml: s(`a: '[2]'`),
+	}, {
+		name:     "set",
+		read:     ReadDiffString,
+		diff:     s(`@ ["a",{}]`, `- 1`, `+ 3`),
+		json:     `{"a": [1, 2, 1]}`,
+		wantJson: `{"a": [2, 3]}`,
+		yaml:     s(`a:`, `  - 1`, `  - 2`, `  - 1`),
+		wantYaml: s(`a:`, `  - 2`, `  - 3`),
+	}, {
+		name:     "multiset",
+		read:     ReadDiffString,
+		diff:     s(`@ ["a",[]]`, `- 1`, `+ 3`),
+		json:     `{"a": [1, 2, 1]}`,
+		wantJson: `{"a": [2, 1, 3]}`,
+		yaml:     s(`a:`, `  - 1`, `  - 2`, `  - 1`),
+		wantYaml: s(`a:`, `  - 2`, `  - 1`, `  - 3`),
+	}, {
+		name:     "set value not found",
+		read:     ReadDiffString,
+		diff:     s(`@ [{}]`, `- 1`),
+		json:     `[1, 1.0]`,
+		wantJson: `[]`,
+		yaml:     s(`- 1`, `- 1.0`),
+		wantYaml: s(`[]`),
+	}, {
+		name:     "set keys",
+		read:     ReadDiffString,
+		diff:     s(`@ [{"id":2},"a"]`, `- 1`, `+ 2`),
+		json:     `[0, {"id": 1, "a": 1}, {"id": 2, "a": 1}]`,
+		wantJson: `[0, {"id": 1, "a": 1}, {"id": 2, "a": 2}]`,
+		yaml:     s(`- 0`, `- id: 1`, `  a: 1`, `- id: 2`, `  a: 1`),
+		wantYaml: s(`- 0`, `- id: 1`, `  a: 1`, `- id: 2`, `  a: 2`),
+	}}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			d, err := c.read(c.diff)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := PatchJsonText(c.json, d)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != c.wantJson {
+				t.Errorf("got:\n%v\nwant:\n%v", got, c.wantJson)
+			}
+			got, err = PatchYamlText(c.yaml, d)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != c.wantYaml {
+				t.Errorf("got:\n%v\nwant:\n%v", got, c.wantYaml)
+			}
+		})
+	}
+}
@@ -8,13 +8,23 @@ type PathElement interface {
 
 type PathIndex int
 type PathKey string
-type PathAllKeys struct{}
 type PathSet struct{}
 type PathMultiset struct{}
 type PathSetKeys map[string]JsonNode
 type PathMultisetKeys map[string]JsonNode
+
+// PathAllKeys matches any object key in the Path of a PathOption. It
+// is written as [null].
+type PathAllKeys struct{}
+
+// PathAllValues matches any object key or list index in the Path of a
+// PathOption. It is written as null.
 type PathAllValues struct{}
 
+// PathEmbedded steps into the JSON or YAML document held by a string,
+// as diffed under EMBEDDED. It is written ["json"] or ["yaml"].
+type PathEmbedded string
+
 func (_ PathIndex) isPathElement()        {}
 func (_ PathKey) isPathElement()          {}
 func (_ PathAllKeys) isPathElement()      {}
@@ -23,6 +33,7 @@ func (_ PathMultiset) isPathElement()     {}
 func (_ PathSetKeys) isPathElement()      {}
 func (_ PathMultisetKeys) isPathElement() {}
 func (_ PathAllValues) isPathElement()    {}
+func (_ PathEmbedded) isPathElement()     {}
 
 func newPathSetKeys(o jsonObject, opts *options) PathSetKeys {
 	setKeys, ok := getOption[setKeysOption](opts)
@@ -64,11 +75,24 @@ func NewPath(n JsonNode) (Path, error) {
 			} else {
 				p[i] = PathSetKeys(e)
 			}
+		case jsonNull:
+			p[i] = PathAllValues{}
 		case jsonArray:
 			switch len(e) {
 			case 0:
 				p[i] = PathMultiset{}
 			case 1:
+				if isNull(e[0]) {
+					p[i] = PathAllKeys{}
+					continue
+				}
+				if f, ok := e[0].(jsonString); ok {
+					if f != "json" && f != "yaml" {
+						return nil, fmt.Errorf("embedded document format must be json or yaml. got %v", f)
+					}
+					p[i] = PathEmbedded(f)
+					continue
+				}
 				o, ok := e[0].(jsonObject)
 				if !ok {
 					return nil, fmt.Errorf("multiset keys must be an object. got %T", e[0])
@@ -78,7 +102,7 @@ func NewPath(n JsonNode) (Path, error) {
 				return nil, fmt.Errorf("multiset path element must have length 0 or 1. got %v", len(e))
 			}
 		default:
-			return nil, fmt.Errorf("path element must be a number, object or array. got %T", e)
+			return nil, fmt.Errorf("path element must be a number, object, array or null. got %T", e)
 		}
 	}
 	return p, nil
@@ -100,6 +124,12 @@ func (p Path) JsonNode() JsonNode {
 			a[i] = jsonObject(e)
 		case PathMultisetKeys:
 			a[i] = jsonArray{jsonObject(e)}
+		case PathAllKeys:
+			a[i] = jsonArray{jsonNull{}}
+		case PathAllValues:
+			a[i] = jsonNull{}
+		case PathEmbedded:
+			a[i] = jsonArray{jsonString(e)}
 		default:
 			panic(fmt.Sprintf("path element should be a closed set. got %T", e))
 		}
@@ -115,7 +145,7 @@ func (p Path) next() (PathElement, []Option, Path) {
 	switch e := p[0].(type) {
 	case PathKey:
 		return p[0], []Option{}, rest
-	case PathIndex:
+	case PathIndex, PathEmbedded:
 		return p[0], nil, rest
 	case PathSet:
 		return p[0], []Option{setOption{}}, rest
@@ -145,6 +175,34 @@ func (p Path) isLeaf() bool {
 	}
 }
 
+// matches reports whether the element e of a PathOption Path selects
+// the path element p. Wildcards never select a PathEmbedded.
+func matches(e, p PathElement) bool {
+	switch e := e.(type) {
+	case PathAllValues:
+		_, ok := p.(PathEmbedded)
+		return !ok
+	case PathAllKeys:
+		_, ok := p.(PathKey)
+		return ok
+	case PathKey, PathIndex, PathEmbedded:
+		return e == p
+	}
+	return false
+}
+
+// hasWildcard reports whether p contains a PathAllKeys or
+// PathAllValues, which only select values in a PathOption.
+func (p Path) hasWildcard() bool {
+	for _, e := range p {
+		switch e.(type) {
+		case PathAllKeys, PathAllValues:
+			return true
+		}
+	}
+	return false
+}
+
 func (p Path) clone() Path {
 	p2 := make(Path, len(p))
 	for i, e := range p {
@@ -40,7 +40,7 @@ func TestNewPathEdgeCases(t *testing.T) {
 		t.Errorf("expected PathMultisetKeys, got %T", p[0])
 	}
 	// Nested array with non-object -> error
-	_, err = NewPath(jsonArray{jsonArray{jsonString("not obj")}})
+	_, err = NewPath(jsonArray{jsonArray{jsonNumber(1)}})
 	if err == nil {
 		t.Fatal("expected error for non-object in multiset")
 	}
@@ -49,6 +49,34 @@ func TestNewPathEdgeCases(t *testing.T) {
 	if err == nil {
 		t.Fatal("expected error for multiset array length > 1")
 	}
+	// Null -> PathAllValues
+	p, err = NewPath(jsonArray{jsonNull{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := p[0].(PathAllValues); !ok {
+		t.Errorf("expected PathAllValues, got %T", p[0])
+	}
+	// Nested array with null -> PathAllKeys
+	p, err = NewPath(jsonArray{jsonArray{jsonNull{}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := p[0].(PathAllKeys); !ok {
+		t.Errorf("expected PathAllKeys, got %T", p[0])
+	}
+	// Nested array with string -> PathEmbedded
+	p, err = NewPath(jsonArray{jsonArray{jsonString("yaml")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p[0] != PathEmbedded("yaml") {
+		t.Errorf("expected PathEmbedded, got %v", p[0])
+	}
+	_, err = NewPath(jsonArray{jsonArray{jsonString("xml")}})
+	if err == nil {
+		t.Fatal("expected error for unknown embedded format")
+	}
 	// Unsupported element type
 	_, err = NewPath(jsonArray{jsonBool(true)})
 	if err == nil {
@@ -0,0 +1,79 @@
+package jd
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+type patternOption struct {
+	re *regexp.Regexp
+}
+
+// Pattern compares strings by the shape given by the regular expression
+// pattern rather than by their exact value. Strings which both match
+// pattern are equal when they are equal with the text matched by each
+// capture group masked, or with each whole match masked when pattern
+// has no capture groups. A string which matches never equals one which
+// does not, so a value losing its expected shape is still diffed.
+//
+// Pattern is meant for a PathOption targeting values like timestamps,
+// UUIDs and build hashes. It panics if pattern is not a valid regular
+// expression. See NewOption to read the option without panicking.
+func Pattern(pattern string) Option {
+	return patternOption{regexp.MustCompile(pattern)}
+}
+
+func (o patternOption) isOption() {}
+func (o patternOption) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]string{
+		"pattern": o.re.String(),
+	})
+}
+
+// mask returns s with the text matched by each capture group of the
+// pattern, or by each whole match when there are none, replaced by a
+// NUL byte. It reports whether the pattern matched at all.
+func (o patternOption) mask(s string) (string, bool) {
+	matches := o.re.FindAllStringSubmatchIndex(s, -1)
+	if matches == nil {
+		return s, false
+	}
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		spans := m[:2]
+		if len(m) > 2 {
+			spans = m[2:]
+		}
+		for i := 0; i < len(spans); i += 2 {
+			start, end := spans[i], spans[i+1]
+			if start < last {
+				// Unmatched and nested groups are masked with
+				// their enclosing group.
+				continue
+			}
+			b.WriteString(s[last:start])
+			b.WriteByte(0)
+			last = end
+		}
+	}
+	b.WriteString(s[last:])
+	return b.String(), true
+}
+
+// patterned returns s masked by the Pattern options applied in o and
+// whether any of them matched.
+func patterned(s jsonString, o *options) (jsonString, bool) {
+	matched := false
+	for _, opt := range o.apply {
+		if p, ok := opt.(patternOption); ok {
+			masked, ok := p.mask(string(s))
+			if ok {
+				s = jsonString(masked)
+				matched = true
+			}
+		}
+	}
+	return s, matched
+}
@@ -0,0 +1,137 @@
+package jd
+
+import (
+	"testing"
+)
+
+func TestPatternDiff(t *testing.T) {
+	uuid := `^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`
+	cases := []struct {
+		name    string
+		options []Option
+		a       string
+		b       string
+		want    []string
+	}{{
+		name:    "both match",
+		options: []Option{PathOption(Path{PathKey("id")}, Pattern(uuid))},
+		a:       `{"id":"0f8fad5b-d9cb-469f-a165-70867728950e","v":1}`,
+		b:       `{"id":"7c9e6679-7425-40de-944b-e07fc1f90ae7","v":1}`,
+		want:    ss(),
+	}, {
+		name:    "one stops matching",
+		options: []Option{PathOption(Path{PathKey("id")}, Pattern(uuid))},
+		a:       `{"id":"0f8fad5b-d9cb-469f-a165-70867728950e"}`,
+		b:       `{"id":"7C9E6679-7425-40DE-944B-E07FC1F90AE7"}`,
+		want: ss(
+			`@ ["id"]`,
+			`- "0f8fad5b-d9cb-469f-a165-70867728950e"`,
+			`+ "7C9E6679-7425-40DE-944B-E07FC1F90AE7"`,
+		),
+	}, {
+		name:    "neither matches",
+		options: []Option{Pattern(`^\d+$`)},
+		a:       `["a","b"]`,
+		b:       `["a","c"]`,
+		want: ss(
+			`@ [1]`,
+			`  "a"`,
+			`- "b"`,
+			`+ "c"`,
+			`]`,
+		),
+	}, {
+		name:    "not a string",
+		options: []Option{Pattern(`.*`)},
+		a:       `{"a":1}`,
+		b:       `{"a":"1"}`,
+		want: ss(
+			`@ ["a"]`,
+			`- 1`,
+			`+ "1"`,
+		),
+	}, {
+		name:    "capture groups",
+		options: []Option{PathOption(Path{PathAllValues{}, PathKey("image")}, Pattern(`:(v[0-9.]+)-([0-9a-f]+)$`))},
+		a:       `[{"image":"web:v1.2-abc123"},{"image":"db:v1-ff"}]`,
+		b:       `[{"image":"web:v1.3-def456"},{"image":"cache:v1-ff"}]`,
+		want: ss(
+			`@ [1,"image"]`,
+			`- "db:v1-ff"`,
+			`+ "cache:v1-ff"`,
+		),
+	}, {
+		name:    "unanchored matches",
+		options: []Option{Pattern(`\d{4}-\d{2}-\d{2}`)},
+		a:       `["built 2024-01-01 from 2023-12-31","built 2024-01-01"]`,
+		b:       `["built 2025-06-30 from 2025-06-01","deployed 2025-06-30"]`,
+		want: ss(
+			`@ [1]`,
+			`  "built 2024-01-01 from 2023-12-31"`,
+			`- "built 2024-01-01"`,
+			`+ "deployed 2025-06-30"`,
+			`]`,
+		),
+	}, {
+		name:    "optional group",
+		options: []Option{Pattern(`^id-(\d+)(-(\w+))?$`)},
+		a:       `["id-1","id-2-x"]`,
+		b:       `["id-3","id-4-y"]`,
+		want:    ss(),
+	}, {
+		name:    "with ignore case",
+		options: []Option{Pattern(`^\d+ `), IGNORE_CASE},
+		a:       `"12 Items"`,
+		b:       `"3 items"`,
+		want:    ss(),
+	}, {
+		name:    "set",
+		options: []Option{PathOption(Path{PathKey("builds")}, SET, Pattern(`^build-\d+$`))},
+		a:       `{"builds":["build-100","latest"]}`,
+		b:       `{"builds":["latest","build-200","nightly"]}`,
+		want: ss(
+			`@ ["builds",{}]`,
+			`+ "nightly"`,
+		),
+	}, {
+		name:    "multiset",
+		options: []Option{PathOption(Path{PathKey("builds")}, MULTISET, Pattern(`^build-\d+$`))},
+		a:       `{"builds":["build-1","build-2","latest"]}`,
+		b:       `{"builds":["latest"]}`,
+		want: ss(
+			`@ ["builds",[]]`,
+			`- "build-1"`,
+			`- "build-2"`,
+		),
+	}}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ctx := newTestContext(t).withOptions(c.options...)
+			checkDiff(ctx, c.a, c.b, c.want...)
+			checkRoundTrip(t, c.a, c.b, c.options)
+		})
+	}
+}
+
+func TestPatternRender(t *testing.T) {
+	a, _ := ReadJsonString(`{"at":"2024-01-01","v":1}`)
+	b, _ := ReadJsonString(`{"at":"2025-01-01","v":2}`)
+	opts := []Option{PathOption(Path{PathKey("at")}, Pattern(`^\d{4}-\d{2}-\d{2}$`))}
+	got := a.Diff(b, opts...).Render(opts...)
+	want := s(
+		`^ {"@":["at"],"^":[{"pattern":"^\\d{4}-\\d{2}-\\d{2}$"}]}`,
+		`@ ["v"]`,
+		`- 1`,
+		`+ 2`,
+	)
+	if got != want {
+		t.Errorf("got %v. Want %v", got, want)
+	}
+	d, err := ReadDiffString(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := d.Render(); got != want {
+		t.Errorf("got %v after reading. Want %v", got, want)
+	}
+}
@@ -55,6 +55,11 @@ func writePointer(path []JsonNode) (string, error) {
 		case jsonObject:
 			return "", fmt.Errorf("JSON Pointer does not support set-based paths. Use jd format instead of patch")
 		case jsonArray:
+			if len(e) == 1 {
+				if _, ok := e[0].(jsonString); ok {
+					return "", fmt.Errorf("JSON Pointer does not support embedded documents. Use jd format instead of patch")
+				}
+			}
 			return "", fmt.Errorf("JSON Pointer does not support jd metadata")
 		default:
 			return "", fmt.Errorf("unsupported type: %T", e)
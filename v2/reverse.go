@@ -0,0 +1,42 @@
+package jd
+
+import (
+	"fmt"
+)
+
+// Reverse returns the Diff which undoes d, so patching a with d and
+// then with d.Reverse() gives back a. Each hunk removes the values the
+// original added and adds the values it removed, and moves go back to
+// where they came from.
+//
+// Hunks apply one after another, so the hunks of the reversed Diff are
+// in reverse order. A list hunk then finds its list as the original
+// left it: the values before its index and after its new values are
+// the same, so its Path index and its Before and After context are
+// kept as they are.
+//
+// Merge hunks, hunks read from JSON Patch which do not test the values
+// they replace, copies and appends to the end of a list (index -1) do
+// not record what they overwrite or where they put it and cannot be
+// reversed.
+func (d Diff) Reverse() (Diff, error) {
+	r := make(Diff, 0, len(d))
+	for i := len(d) - 1; i >= 0; i-- {
+		de := d[i]
+		switch {
+		case de.Metadata.Merge || de.Metadata.Unchecked:
+			return nil, fmt.Errorf("cannot reverse hunk at %v: it does not record the value it replaces", de.Path)
+		case de.Copy:
+			return nil, fmt.Errorf("cannot reverse copy to %v: it does not record the value it replaces", de.Path)
+		case len(de.Path) > 0 && de.Path[len(de.Path)-1] == PathIndex(-1):
+			return nil, fmt.Errorf("cannot reverse append to %v: it does not record the index of the values", de.Path)
+		}
+		if de.From != nil {
+			de.Path, de.From = de.From, de.Path
+		} else {
+			de.Remove, de.Add = de.Add, de.Remove
+		}
+		r = append(r, de)
+	}
+	return r, nil
+}
@@ -0,0 +1,149 @@
+package jd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReverse(t *testing.T) {
+	cases := []struct {
+		name    string
+		options []Option
+		a       string
+		b       string
+	}{{
+		name: "object",
+		a:    `{"a":1,"b":{"c":[1,2]},"d":true}`,
+		b:    `{"a":2,"b":{"c":"x"},"e":null}`,
+	}, {
+		name: "root",
+		a:    `1`,
+		b:    `{"a":1}`,
+	}, {
+		name: "list",
+		a:    `[1,2,3,4,5,6]`,
+		b:    `[0,1,3,4,7,6,8]`,
+	}, {
+		name: "list of containers",
+		a:    `{"a":[{"b":1},[1,2],3,{"c":1}]}`,
+		b:    `{"a":[{"b":2},[2],{"c":1},4]}`,
+	}, {
+		name: "emptied list",
+		a:    `[1,2]`,
+		b:    `[]`,
+	}, {
+		name:    "set",
+		options: []Option{SET},
+		a:       `{"a":[1,2,3]}`,
+		b:       `{"a":[3,4,1]}`,
+	}, {
+		name:    "multiset",
+		options: []Option{MULTISET},
+		a:       `[1,1,2]`,
+		b:       `[1,2,2,3]`,
+	}, {
+		name:    "set keys",
+		options: []Option{SetKeys("id")},
+		a:       `[{"id":1,"a":1},{"id":2}]`,
+		b:       `[{"id":1,"a":2},{"id":3}]`,
+	}, {
+		name:    "moves",
+		options: []Option{DETECT_MOVES},
+		a:       `{"a":{"b":[1,2]},"c":1}`,
+		b:       `{"d":{"b":[1,2]},"c":2}`,
+	}, {
+		name:    "embedded",
+		options: []Option{EMBEDDED},
+		a:       `{"a":"{\"b\":[1,2]}"}`,
+		b:       `{"a":"{\"b\":[1,3]}"}`,
+	}}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			d := mustParse(t, c.a).Diff(mustParse(t, c.b), c.options...)
+			r, err := d.Reverse()
+			if err != nil {
+				t.Fatalf("wanted no error. got %v", err)
+			}
+			b, err := mustParse(t, c.a).Patch(d)
+			if err != nil {
+				t.Fatalf("wanted no error. got %v", err)
+			}
+			got, err := b.Patch(r)
+			if err != nil {
+				t.Fatalf("wanted no error patching with:\n%v\ngot %v", r.Render(), err)
+			}
+			if want := mustParse(t, c.a); !got.Equals(want, c.options...) {
+				t.Errorf("got %v. want %v", got.Json(), want.Json())
+			}
+			rr, err := r.Reverse()
+			if err != nil {
+				t.Fatalf("wanted no error. got %v", err)
+			}
+			if got, want := rr.Render(), d.Render(); got != want {
+				t.Errorf("reversed twice got:\n%v\nwant:\n%v", got, want)
+			}
+		})
+	}
+}
+
+func TestReverseRender(t *testing.T) {
+	d, err := ReadDiffString(joinLines(ss(
+		`@ [1]`, `  1`, `- 2`, `+ 3`, `  4`,
+		`@ [3]`, `  4`, `+ 5`, `]`,
+	)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := d.Reverse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := joinLines(ss(
+		`@ [3]`, `  4`, `- 5`, `]`,
+		`@ [1]`, `  1`, `- 3`, `+ 2`, `  4`,
+	))
+	if got := r.Render(); got != want {
+		t.Errorf("got:\n%v\nwant:\n%v", got, want)
+	}
+}
+
+func TestReverseErrors(t *testing.T) {
+	cases := []struct {
+		name    string
+		read    func(string) (Diff, error)
+		diff    string
+		wantErr string
+	}{{
+		name:    "merge",
+		read:    ReadMergeString,
+		diff:    `{"a":1}`,
+		wantErr: "cannot reverse hunk at [a]",
+	}, {
+		name:    "unchecked",
+		read:    ReadPatchString,
+		diff:    `[{"op":"replace","path":"/0","value":1}]`,
+		wantErr: "cannot reverse hunk at [0]",
+	}, {
+		name:    "copy",
+		read:    ReadPatchString,
+		diff:    `[{"op":"copy","from":"/a","path":"/b"}]`,
+		wantErr: "cannot reverse copy to [b]",
+	}, {
+		name:    "append",
+		read:    ReadPatchString,
+		diff:    `[{"op":"add","path":"/-","value":1}]`,
+		wantErr: "cannot reverse append to [-1]",
+	}}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			d, err := c.read(c.diff)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = d.Reverse()
+			if err == nil || !strings.HasPrefix(err.Error(), c.wantErr) {
+				t.Errorf("wanted error %q. got %v", c.wantErr, err)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package jd
 
 import (
+	"context"
 	"fmt"
 	"sort"
 )
@@ -36,6 +37,7 @@ func (s jsonSet) raw() interface{} {
 }
 
 func (s1 jsonSet) Equals(n JsonNode, opts ...Option) bool {
+	opts = InferKeys(s1, n, opts...)
 	o := refine(&options{retain: opts}, nil)
 	return s1.equals(n, o)
 }
@@ -48,9 +50,28 @@ func (s1 jsonSet) equals(n JsonNode, o *options) bool {
 	}
 	if s1.hashCode(o) == s2.hashCode(o) {
 		return true
-	} else {
+	}
+	if !hasTolerance(o) {
 		return false
 	}
+	return s1.within(s2, o) && s2.within(s1, o)
+}
+
+// within reports whether every element of s1 equals an element of s2.
+func (s1 jsonSet) within(s2 jsonSet, o *options) bool {
+	for _, v1 := range s1 {
+		found := false
+		for _, v2 := range s2 {
+			if v1.equals(v2, o) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
 }
 
 func (s jsonSet) hashCode(opts *options) [8]byte {
@@ -68,6 +89,7 @@ func (s jsonSet) hashCode(opts *options) [8]byte {
 }
 
 func (s jsonSet) Diff(j JsonNode, opts ...Option) Diff {
+	opts = InferKeys(s, j, opts...)
 	o := refine(newOptions(opts), nil)
 	return s.diff(j, make(Path, 0), o, getPatchStrategy(o))
 }
@@ -103,6 +125,14 @@ func (s jsonSet) Patch(d Diff) (JsonNode, error) {
 	return patchAll(s, d)
 }
 
+func (s jsonSet) DiffContext(ctx context.Context, n JsonNode, opts ...Option) (Diff, error) {
+	return diffContext(ctx, s, n, opts)
+}
+
+func (s jsonSet) PatchContext(ctx context.Context, d Diff) (JsonNode, error) {
+	return patchContext(ctx, s, d)
+}
+
 func (s jsonSet) patch(
 	pathBehind, pathAhead Path,
 	before, oldValues, newValues, after []JsonNode,
@@ -144,7 +174,7 @@ func (s jsonSet) patch(
 				}
 			}
 		}
-		return nil, fmt.Errorf("invalid diff: expected object with id %v but found none", jsonObject(pathSetKeys).Json())
+		return nil, &PathNotFoundError{Path: append(pathBehind.clone(), n)}
 	}
 	_, ok = n.(PathSet)
 	if !ok {
@@ -175,15 +205,10 @@ func (s jsonSet) patch(
 		}
 		toDelete, ok := aMap[hc]
 		if !ok {
-			return nil, fmt.Errorf(
-				"invalid diff: expected %v at %v but found nothing",
-				v.Json(), pathBehind)
+			return patchErrExpectValue(v, voidNode{}, pathBehind)
 		}
 		if !toDelete.equals(v, opts) { //jd:nocover — requires ident hash collision
-			return nil, fmt.Errorf(
-				"invalid diff: expected %v at %v but found %v",
-				v.Json(), pathBehind, toDelete.Json())
-
+			return patchErrExpectValue(v, toDelete, pathBehind)
 		}
 		delete(aMap, hc)
 	}
@@ -259,6 +284,7 @@ func (p *setDiffProcessor) ProcessEvents(events []diffEvent) Diff {
 
 	for i, event := range events {
 		p.debugLog("Processing event %d: %s", i, event.String())
+		p.opts.budget.step()
 
 		switch e := event.(type) {
 		case setElementEvent:
@@ -304,7 +330,7 @@ func (p *setDiffProcessor) processsetObjectDiffEvent(event setObjectDiffEvent) {
 	setKeysPath := newPathSetKeys(o1, p.opts)
 	subPath := append(p.path.clone(), setKeysPath)
 
-	subDiff := event.OldObject.diff(event.NewObject, subPath, p.opts, p.strategy)
+	subDiff := event.OldObject.diff(event.NewObject, subPath, refine(p.opts, setKeysPath), p.strategy)
 	p.finalDiff = append(p.finalDiff, subDiff...)
 }
 
@@ -340,8 +366,11 @@ func generateSetdiffEvents(s1, s2 jsonSet, opts *options) []diffEvent {
 
 	var events []diffEvent
 
-	// Create hash maps for identity-based comparison
+	// Create hash maps for identity-based comparison. Options such as
+	// IGNORE_CASE or EMBEDDED give different elements one hash, so each
+	// of them is removed when the hash is.
 	s1Map := make(map[[8]byte]JsonNode)
+	s1Instances := make(map[[8]byte][]JsonNode)
 	for _, v := range s1 {
 		var hc [8]byte
 		if o, ok := v.(jsonObject); ok {
@@ -352,6 +381,9 @@ func generateSetdiffEvents(s1, s2 jsonSet, opts *options) []diffEvent {
 			hc = v.hashCode(opts)
 		}
 		s1Map[hc] = v
+		if !containsEqual(s1Instances[hc], v) {
+			s1Instances[hc] = append(s1Instances[hc], v)
+		}
 	}
 
 	s2Map := make(map[[8]byte]JsonNode)
@@ -380,16 +412,24 @@ func generateSetdiffEvents(s1, s2 jsonSet, opts *options) []diffEvent {
 	}
 	sort.Sort(s2Hashes)
 
+	paired, pairedB := pairWithinTolerance(s1Map, s2Map, s1Hashes, s2Hashes, opts)
+
 	// Process removes first (sorted by hash)
 	for _, hc := range s1Hashes {
 		v1 := s1Map[hc]
-		if v2, ok := s2Map[hc]; !ok {
-			// Deleted value
-			events = append(events, setElementEvent{
-				Operation: "REMOVE",
-				Element:   v1,
-				Hash:      hc,
-			})
+		v2, ok := s2Map[hc]
+		if !ok {
+			v2, ok = paired[hc]
+		}
+		if !ok {
+			// Deleted values
+			for _, v := range s1Instances[hc] {
+				events = append(events, setElementEvent{
+					Operation: "REMOVE",
+					Element:   v,
+					Hash:      hc,
+				})
+			}
 		} else {
 			// Check for object diffs with same identity
 			o1, isObject1 := v1.(jsonObject)
@@ -407,7 +447,7 @@ func generateSetdiffEvents(s1, s2 jsonSet, opts *options) []diffEvent {
 
 	// Process adds (sorted by hash)
 	for _, hc := range s2Hashes {
-		if _, ok := s1Map[hc]; !ok {
+		if _, ok := s1Map[hc]; !ok && !pairedB[hc] {
 			// Added value
 			events = append(events, setElementEvent{
 				Operation: "ADD",
@@ -419,3 +459,57 @@ func generateSetdiffEvents(s1, s2 jsonSet, opts *options) []diffEvent {
 
 	return events
 }
+
+// containsEqual reports whether l holds a value exactly equal to v.
+func containsEqual(l []JsonNode, v JsonNode) bool {
+	for _, x := range l {
+		if x.Equals(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// pairWithinTolerance pairs the elements of two sets which their hashes
+// leave unpaired but which are equal under a tolerance, or are objects
+// with the same identity. It returns the element of s2 paired with each
+// hash of s1 and the paired hashes of s2.
+func pairWithinTolerance(
+	s1Map, s2Map map[[8]byte]JsonNode,
+	s1Hashes, s2Hashes hashCodes,
+	opts *options,
+) (map[[8]byte]JsonNode, map[[8]byte]bool) {
+	paired := map[[8]byte]JsonNode{}
+	pairedB := map[[8]byte]bool{}
+	if !hasTolerance(opts) {
+		return paired, pairedB
+	}
+	var removed, added []JsonNode
+	var removedHashes, addedHashes hashCodes
+	for _, hc := range s1Hashes {
+		if _, ok := s2Map[hc]; !ok {
+			removed = append(removed, s1Map[hc])
+			removedHashes = append(removedHashes, hc)
+		}
+	}
+	for _, hc := range s2Hashes {
+		if _, ok := s1Map[hc]; !ok {
+			added = append(added, s2Map[hc])
+			addedHashes = append(addedHashes, hc)
+		}
+	}
+	pairs := matchEqual(removed, added, func(x, y JsonNode) bool {
+		opts.budget.step()
+		if o, ok := x.(jsonObject); ok {
+			return o.sameIdent(y, opts)
+		}
+		return x.equals(y, opts)
+	})
+	for i, j := range pairs {
+		if j >= 0 {
+			paired[removedHashes[i]] = added[j]
+			pairedB[addedHashes[j]] = true
+		}
+	}
+	return paired, pairedB
+}
@@ -0,0 +1,156 @@
+package jd
+
+import (
+	"strconv"
+)
+
+// alignSimilar re-pairs the elements which are removed and added
+// between matches of a List diff. Objects and arrays of the same type
+// which share at least threshold of their leaves (see similarity) are
+// paired to be diffed in place. Others are removed and added. Pairs
+// keep their order and are chosen to maximize the total similarity.
+func alignSimilar(a, b jsonList, events []diffEvent, threshold float64, opts *options) []diffEvent {
+	aligned := make([]diffEvent, 0, len(events))
+	var gapA, gapB []int
+	flush := func() {
+		aligned = append(aligned, alignGap(a, b, gapA, gapB, threshold, opts)...)
+		gapA, gapB = nil, nil
+	}
+	for _, e := range events {
+		switch e := e.(type) {
+		case matchEvent:
+			flush()
+			aligned = append(aligned, e)
+		case removeEvent:
+			gapA = append(gapA, e.AIndex)
+		case addEvent:
+			gapB = append(gapB, e.BIndex)
+		case replaceEvent:
+			gapA = append(gapA, e.AIndex)
+			gapB = append(gapB, e.BIndex)
+		case containerDiffEvent:
+			gapA = append(gapA, e.AIndex)
+			gapB = append(gapB, e.BIndex)
+		}
+	}
+	flush()
+	return aligned
+}
+
+// alignGap aligns the elements of a at indices ia with the elements of
+// b at indices ib.
+func alignGap(a, b jsonList, ia, ib []int, threshold float64, opts *options) []diffEvent {
+	n, m := len(ia), len(ib)
+	if n == 0 && m == 0 {
+		return nil
+	}
+	opts.budget.cells((n + 1) * (m + 1))
+	leavesA := make([]map[string]JsonNode, n)
+	for i, x := range ia {
+		leavesA[i] = leaves(a[x])
+	}
+	leavesB := make([]map[string]JsonNode, m)
+	for j, y := range ib {
+		leavesB[j] = leaves(b[y])
+	}
+	// pair[i][j] is the similarity of a pairable a[ia[i]] and b[ib[j]]
+	// or -1. best[i][j] is the greatest total similarity of pairs
+	// aligning ia[i:] with ib[j:].
+	pair := make([][]float64, n)
+	for i := range pair {
+		pair[i] = make([]float64, m)
+		for j := range pair[i] {
+			opts.budget.step()
+			pair[i][j] = -1
+			if sameContainerType(a[ia[i]], b[ib[j]], opts) {
+				if s := similarity(leavesA[i], leavesB[j], opts); s >= threshold {
+					pair[i][j] = s
+				}
+			}
+		}
+	}
+	best := make([][]float64, n+1)
+	for i := range best {
+		best[i] = make([]float64, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			best[i][j] = max(best[i+1][j], best[i][j+1])
+			if pair[i][j] >= 0 {
+				best[i][j] = max(best[i][j], pair[i][j]+best[i+1][j+1])
+			}
+		}
+	}
+	events := make([]diffEvent, 0, n+m)
+	i, j := 0, 0
+	for i < n || j < m {
+		switch {
+		case i < n && j < m && pair[i][j] >= 0 && best[i][j] == pair[i][j]+best[i+1][j+1]:
+			events = append(events, containerDiffEvent{
+				AIndex:   ia[i],
+				BIndex:   ib[j],
+				AElement: a[ia[i]],
+				BElement: b[ib[j]],
+			})
+			i++
+			j++
+		case i < n && (j == m || best[i][j] == best[i+1][j]):
+			events = append(events, removeEvent{AIndex: ia[i], Element: a[ia[i]]})
+			i++
+		default:
+			events = append(events, addEvent{BIndex: ib[j], Element: b[ib[j]]})
+			j++
+		}
+	}
+	return events
+}
+
+// similarity is the fraction of leaves two values have in common: twice
+// the number of leaves with equal values at the same path divided by
+// the number of leaves of both.
+func similarity(a, b map[string]JsonNode, opts *options) float64 {
+	equal := 0
+	for p, v := range a {
+		if w, ok := b[p]; ok && v.equals(w, opts) {
+			equal++
+		}
+	}
+	return float64(2*equal) / float64(len(a)+len(b))
+}
+
+// leaves are the scalars and empty objects and arrays of n by their
+// path below n.
+func leaves(n JsonNode) map[string]JsonNode {
+	l := map[string]JsonNode{}
+	collectLeaves(n, "", l)
+	return l
+}
+
+func collectLeaves(n JsonNode, p string, l map[string]JsonNode) {
+	var elements []JsonNode
+	switch n := n.(type) {
+	case jsonObject:
+		if len(n) == 0 {
+			break
+		}
+		for k, v := range n {
+			collectLeaves(v, p+"/"+strconv.Quote(k), l)
+		}
+		return
+	case jsonArray:
+		elements = n
+	case jsonList:
+		elements = n
+	case jsonSet:
+		elements = n
+	case jsonMultiset:
+		elements = n
+	}
+	if len(elements) == 0 {
+		l[p] = n
+		return
+	}
+	for i, e := range elements {
+		collectLeaves(e, p+"/"+strconv.Itoa(i), l)
+	}
+}
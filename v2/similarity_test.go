@@ -0,0 +1,122 @@
+package jd
+
+import (
+	"testing"
+)
+
+func TestSimilarityDiff(t *testing.T) {
+	cases := []struct {
+		name      string
+		threshold float64
+		a         string
+		b         string
+		want      []string
+	}{{
+		name:      "changed object is diffed in place",
+		threshold: 0.5,
+		a:         `[{"id":1,"a":1,"b":2,"c":3},{"id":2,"a":1,"b":2,"c":3},{"id":3}]`,
+		b:         `[{"id":2,"a":1,"b":2,"c":4},{"id":3}]`,
+		want: ss(
+			`@ [0]`,
+			`[`,
+			`- {"a":1,"b":2,"c":3,"id":1}`,
+			`  {"a":1,"b":2,"c":3,"id":2}`,
+			`@ [0,"c"]`,
+			`- 3`,
+			`+ 4`,
+		),
+	}, {
+		name:      "dissimilar objects are not paired",
+		threshold: 0.5,
+		a:         `[1,{"a":1,"b":2},{"z":1}]`,
+		b:         `[1,{"q":9},{"a":1,"b":3}]`,
+		want: ss(
+			`@ [1]`,
+			`  1`,
+			`+ {"q":9}`,
+			`  {"a":1,"b":2}`,
+			`@ [2,"b"]`,
+			`- 2`,
+			`+ 3`,
+			`@ [3]`,
+			`  {"a":1,"b":3}`,
+			`- {"z":1}`,
+			`]`,
+		),
+	}, {
+		name:      "added before a similar object",
+		threshold: 0.5,
+		a:         `[{"a":1,"b":1}]`,
+		b:         `[{"a":0},{"a":1,"b":2}]`,
+		want: ss(
+			`@ [0]`,
+			`[`,
+			`+ {"a":0}`,
+			`  {"a":1,"b":1}`,
+			`@ [1,"b"]`,
+			`- 1`,
+			`+ 2`,
+		),
+	}, {
+		name:      "below the threshold",
+		threshold: 0.9,
+		a:         `[{"a":1,"b":2}]`,
+		b:         `[{"a":1,"b":3}]`,
+		want: ss(
+			`@ [0]`,
+			`[`,
+			`- {"a":1,"b":2}`,
+			`+ {"a":1,"b":3}`,
+			`]`,
+		),
+	}, {
+		name:      "nested arrays and empty values",
+		threshold: 0.5,
+		a:         `[[{},[],[1,2],{"a":[3],"b":1}],"x"]`,
+		b:         `[[{},[],[1,2],{"a":[4],"b":1}],"y"]`,
+		want: ss(
+			`@ [0,3,"a",0]`,
+			`[`,
+			`- 3`,
+			`+ 4`,
+			`]`,
+			`@ [1]`,
+			`  [{},[],[1,2],{"a":[4],"b":1}]`,
+			`- "x"`,
+			`+ "y"`,
+			`]`,
+		),
+	}, {
+		name:      "different container types are not paired",
+		threshold: 0.1,
+		a:         `[{"a":1}]`,
+		b:         `[["a",1]]`,
+		want: ss(
+			`@ [0]`,
+			`[`,
+			`- {"a":1}`,
+			`+ ["a",1]`,
+			`]`,
+		),
+	}}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ctx := newTestContext(t).withOptions(Similarity(c.threshold))
+			checkDiff(ctx, c.a, c.b, c.want...)
+			checkPatch(ctx, c.a, c.b, c.want...)
+		})
+	}
+}
+
+func TestSimilarityOfDispatchedArrays(t *testing.T) {
+	// Similarity compares the leaves of lists and sets by position.
+	a := leaves(jsonList{jsonNumber(1), jsonNumber(2)})
+	for _, n := range []JsonNode{
+		jsonSet{jsonNumber(1), jsonNumber(3)},
+		jsonMultiset{jsonNumber(1), jsonNumber(3)},
+	} {
+		if got := similarity(a, leaves(n), newOptions(nil)); got != 0.5 {
+			t.Errorf("got similarity %v with %T. want 0.5", got, n)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package jd
+
+import (
+	"fmt"
+	"strings"
+)
+
+// StatCounts are the numbers of values added, removed and changed by
+// a Diff.
+type StatCounts struct {
+	Added   int
+	Removed int
+	Changed int
+}
+
+func (c *StatCounts) add(o StatCounts) {
+	c.Added += o.Added
+	c.Removed += o.Removed
+	c.Changed += o.Changed
+}
+
+// Total is the number of values touched.
+func (c StatCounts) Total() int {
+	return c.Added + c.Removed + c.Changed
+}
+
+// PathStats are the StatCounts of all hunks at or below Path.
+type PathStats struct {
+	Path Path
+	StatCounts
+}
+
+// Stats summarize a Diff. See Diff.Stats.
+type Stats struct {
+	StatCounts
+
+	// Deepest is the longest Path of any hunk.
+	Deepest Path
+
+	// Paths break the counts down by every prefix of the hunk Paths
+	// in the order they first appear. Prefixes of length one are the
+	// top-level keys (or indices) of the diffed values.
+	Paths []PathStats
+}
+
+// Stats counts the values added, removed and changed by the Diff. A
+// hunk which removes and adds values in the same place counts them as
+// changed in pairs, except for Set and Multiset members which have no
+// identity and are only added or removed. Merge hunks do not know
+// whether a value was present before, so values they set are counted
+// as changed. A move removes one value and adds another and a copy
+// adds one.
+func (d Diff) Stats() Stats {
+	s := Stats{Paths: []PathStats{}}
+	index := map[string]int{}
+	count := func(p Path, c StatCounts) {
+		for i := 1; i <= len(p); i++ {
+			key := p[:i].JsonNode().Json()
+			j, ok := index[key]
+			if !ok {
+				j = len(s.Paths)
+				index[key] = j
+				s.Paths = append(s.Paths, PathStats{Path: p[:i].clone()})
+			}
+			s.Paths[j].add(c)
+		}
+		s.add(c)
+	}
+	for _, de := range d {
+		if s.Deepest == nil || len(de.Path) > len(s.Deepest) {
+			s.Deepest = de.Path.clone()
+		}
+		if de.From != nil {
+			if !de.Copy {
+				count(de.From, StatCounts{Removed: 1})
+			}
+			count(de.Path, StatCounts{Added: 1})
+			continue
+		}
+		count(de.Path, hunkCounts(de))
+	}
+	return s
+}
+
+func hunkCounts(de DiffElement) StatCounts {
+	added, removed := len(de.Add), len(de.Remove)
+	if de.Metadata.Merge || de.Metadata.Unchecked {
+		if added == 1 && isVoid(de.Add[0]) {
+			return StatCounts{Removed: 1}
+		}
+		return StatCounts{Changed: added}
+	}
+	if len(de.Path) > 0 {
+		switch de.Path[len(de.Path)-1].(type) {
+		case PathSet, PathMultiset:
+			return StatCounts{Added: added, Removed: removed}
+		}
+	}
+	changed := min(added, removed)
+	return StatCounts{
+		Added:   added - changed,
+		Removed: removed - changed,
+		Changed: changed,
+	}
+}
+
+// Render writes the Stats for humans, similar to git diff --stat: one
+// line per top-level path followed by the totals and the deepest path.
+// Nothing is written for an empty Diff.
+func (s Stats) Render() string {
+	if s.Deepest == nil {
+		return ""
+	}
+	top := []PathStats{}
+	width := 0
+	for _, ps := range s.Paths {
+		if len(ps.Path) == 1 {
+			top = append(top, ps)
+			width = max(width, len(ps.Path.JsonNode().Json()))
+		}
+	}
+	b := &strings.Builder{}
+	for _, ps := range top {
+		fmt.Fprintf(b, " %-*v | %v %v\n", width, ps.Path.JsonNode().Json(), ps.Total(), ps.signs())
+	}
+	fmt.Fprintf(b, " %v values: %v added, %v removed, %v changed\n", s.Total(), s.Added, s.Removed, s.Changed)
+	fmt.Fprintf(b, " deepest: %v\n", s.Deepest.JsonNode().Json())
+	return b.String()
+}
+
+func (c StatCounts) signs() string {
+	signs := []string{}
+	for _, s := range []struct {
+		sign  string
+		count int
+	}{{"+", c.Added}, {"-", c.Removed}, {"~", c.Changed}} {
+		if s.count > 0 {
+			signs = append(signs, fmt.Sprintf("%v%v", s.sign, s.count))
+		}
+	}
+	return strings.Join(signs, " ")
+}
+
+// Json writes the Stats as a JSON object with the fields of Stats.
+func (s Stats) Json() string {
+	paths := jsonArray{}
+	for _, ps := range s.Paths {
+		o := ps.jsonObject()
+		o["Path"] = ps.Path.JsonNode()
+		paths = append(paths, o)
+	}
+	o := s.jsonObject()
+	o["Paths"] = paths
+	if s.Deepest == nil {
+		o["Deepest"] = jsonNull{}
+	} else {
+		o["Deepest"] = s.Deepest.JsonNode()
+	}
+	return o.Json()
+}
+
+func (c StatCounts) jsonObject() jsonObject {
+	return jsonObject{
+		"Added":   jsonNumber(c.Added),
+		"Removed": jsonNumber(c.Removed),
+		"Changed": jsonNumber(c.Changed),
+	}
+}
@@ -0,0 +1,118 @@
+package jd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDiffStats(t *testing.T) {
+	cases := []struct {
+		name    string
+		options []Option
+		a       string
+		b       string
+		want    []string
+	}{{
+		name: "no diff",
+		a:    `{"a":1}`,
+		b:    `{"a":1}`,
+		want: ss(),
+	}, {
+		name: "objects and lists",
+		a:    `{"a":{"b":[1,2,3],"c":1},"d":[1,2],"e":1}`,
+		b:    `{"a":{"b":[1,4,3,5]},"d":[2,3],"f":2}`,
+		want: ss(
+			` ["a"] | 3 +1 -1 ~1`,
+			` ["d"] | 2 +1 -1`,
+			` ["e"] | 1 -1`,
+			` ["f"] | 1 +1`,
+			` 7 values: 3 added, 3 removed, 1 changed`,
+			` deepest: ["a","b",1]`,
+		),
+	}, {
+		name:    "set members",
+		options: []Option{SET},
+		a:       `{"tags":[1,2]}`,
+		b:       `{"tags":[2,3,4]}`,
+		want: ss(
+			` ["tags"] | 3 +2 -1`,
+			` 3 values: 2 added, 1 removed, 0 changed`,
+			` deepest: ["tags",{}]`,
+		),
+	}, {
+		name:    "merge",
+		options: []Option{MERGE},
+		a:       `{"a":1,"b":2}`,
+		b:       `{"a":3}`,
+		want: ss(
+			` ["a"] | 1 ~1`,
+			` ["b"] | 1 -1`,
+			` 2 values: 0 added, 1 removed, 1 changed`,
+			` deepest: ["a"]`,
+		),
+	}, {
+		name:    "move",
+		options: []Option{DETECT_MOVES},
+		a:       `{"a":{"x":1}}`,
+		b:       `{"b":{"x":1}}`,
+		want: ss(
+			` ["a"] | 1 -1`,
+			` ["b"] | 1 +1`,
+			` 2 values: 1 added, 1 removed, 0 changed`,
+			` deepest: ["b"]`,
+		),
+	}, {
+		name: "root",
+		a:    `1`,
+		b:    `2`,
+		want: ss(
+			` 1 values: 0 added, 0 removed, 1 changed`,
+			` deepest: []`,
+		),
+	}}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			a, err := ReadJsonString(c.a)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := ReadJsonString(c.b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := strings.Join(c.want, "\n")
+			if len(c.want) > 0 {
+				want += "\n"
+			}
+			if got := a.Diff(b, c.options...).Stats().Render(); got != want {
+				t.Errorf("got:\n%v\nwant:\n%v", got, want)
+			}
+		})
+	}
+}
+
+func TestDiffStatsJson(t *testing.T) {
+	d, err := ReadDiffString(s(
+		`@ ["a","b"]`,
+		`- 1`,
+		`+ 2`,
+		`@ ["c"]`,
+		`< ["a","d"]`,
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"Added":1,"Changed":1,"Deepest":["a","b"],"Paths":[` +
+		`{"Added":0,"Changed":1,"Path":["a"],"Removed":1},` +
+		`{"Added":0,"Changed":1,"Path":["a","b"],"Removed":0},` +
+		`{"Added":0,"Changed":0,"Path":["a","d"],"Removed":1},` +
+		`{"Added":1,"Changed":0,"Path":["c"],"Removed":0}` +
+		`],"Removed":1}`
+	if got := d.Stats().Json(); got != want {
+		t.Errorf("got %v. want %v", got, want)
+	}
+	want = `{"Added":0,"Changed":0,"Deepest":null,"Paths":[],"Removed":0}`
+	if got := (Diff{}).Stats().Json(); got != want {
+		t.Errorf("got %v. want %v", got, want)
+	}
+}
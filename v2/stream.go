@@ -0,0 +1,439 @@
+package jd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// streamWindow is how many list elements are read ahead on each side
+// when looking for the next match after a difference. Lists which
+// drift further apart than this are diffed as if the whole window
+// was replaced.
+const streamWindow = 64
+
+// DiffStream reads JSON values from a and b incrementally and writes
+// the hunks between them to w in native jd format as they are found.
+// Objects are compared key by key and Lists element by element, so
+// only values which differ (plus the unmatched keys of objects whose
+// keys are not in the same order) are held in memory. Lists which are
+// mostly aligned are diffed within a bounded window.
+//
+// The hunks are equivalent to those of an in-memory Diff, though Lists
+// which differ by more than the window may be diffed less minimally.
+// Sets and Multisets are read into memory. DETECT_MOVES is not
+// supported because it needs the whole Diff, nor are AUTO_KEYS and
+// Similarity because they need whole values. DiffStream reports
+// whether any hunks were written.
+func DiffStream(a, b io.Reader, w io.Writer, opts ...Option) (bool, error) {
+	o := newOptions(opts)
+	if checkOption[detectMovesOption](o) {
+		return false, fmt.Errorf("DETECT_MOVES is not supported when streaming")
+	}
+	if checkOption[autoKeysOption](o) {
+		return false, fmt.Errorf("AUTO_KEYS is not supported when streaming")
+	}
+	if checkOption[similarityOption](o) {
+		return false, fmt.Errorf("similarity is not supported when streaming")
+	}
+	s := &streamDiffer{
+		w:        w,
+		opts:     opts,
+		strategy: getPatchStrategy(o),
+	}
+	da := json.NewDecoder(a)
+	db := json.NewDecoder(b)
+	da.UseNumber()
+	db.UseNumber()
+	ta, err := rootToken(da)
+	if err != nil {
+		return false, err
+	}
+	tb, err := rootToken(db)
+	if err != nil {
+		return false, err
+	}
+	switch {
+	case isDelim(ta, '{') && isDelim(tb, '{'):
+		err = s.diffObject(da, db, make(Path, 0), o)
+	case isDelim(ta, '[') && isDelim(tb, '[') && s.isList(jsonArray{}.refineForArrayDispatch(o)):
+		err = s.diffList(da, db, make(Path, 0), jsonArray{}.refineForArrayDispatch(o))
+	default:
+		var na, nb JsonNode
+		if na, err = readTokenNode(da, ta); err != nil {
+			return false, err
+		}
+		if nb, err = readTokenNode(db, tb); err != nil {
+			return false, err
+		}
+		err = s.emit(na.Diff(nb, opts...))
+	}
+	if err != nil {
+		return s.written, err
+	}
+	for _, dec := range []*json.Decoder{da, db} {
+		if _, err := dec.Token(); err != io.EOF {
+			return s.written, fmt.Errorf("unexpected data after top-level value")
+		}
+	}
+	return s.written, nil
+}
+
+type streamDiffer struct {
+	w        io.Writer
+	opts     []Option
+	strategy patchStrategy
+	written  bool
+}
+
+// emit writes d, preceded by the global options before the first hunk.
+func (s *streamDiffer) emit(d Diff) error {
+	if len(d) == 0 {
+		return nil
+	}
+	var out string
+	if s.written {
+		for _, de := range d {
+			out += de.Render(s.opts...)
+		}
+	} else {
+		out = d.Render(s.opts...)
+	}
+	s.written = true
+	_, err := io.WriteString(s.w, out)
+	return err
+}
+
+// isList reports whether arrays are diffed as Lists under opts.
+func (s *streamDiffer) isList(opts *options) bool {
+	if s.strategy != strictPatchStrategy {
+		return false
+	}
+	_, ok := dispatch(jsonArray{}, opts).(jsonList)
+	return ok
+}
+
+// diffObject diffs two objects whose opening braces have been read.
+// Values under the same key at the same position are diffed in place.
+// Other values are held until their key is found on the other side.
+func (s *streamDiffer) diffObject(da, db *json.Decoder, path Path, opts *options) error {
+	pendingA := map[string]JsonNode{}
+	pendingB := map[string]JsonNode{}
+	for {
+		moreA, moreB := da.More(), db.More()
+		if !moreA && !moreB {
+			break
+		}
+		var ka, kb string
+		var err error
+		if moreA {
+			if ka, err = readKey(da); err != nil {
+				return err
+			}
+		}
+		if moreB {
+			if kb, err = readKey(db); err != nil {
+				return err
+			}
+		}
+		if moreA && moreB && ka == kb {
+			if err := s.diffValue(da, db, ka, path, opts); err != nil {
+				return err
+			}
+			continue
+		}
+		if moreA {
+			va, err := readNode(da)
+			if err != nil {
+				return err
+			}
+			if vb, ok := pendingB[ka]; ok {
+				delete(pendingB, ka)
+				if err := s.diffKey(ka, va, vb, path, opts); err != nil {
+					return err
+				}
+			} else {
+				pendingA[ka] = va
+			}
+		}
+		if moreB {
+			vb, err := readNode(db)
+			if err != nil {
+				return err
+			}
+			if va, ok := pendingA[kb]; ok {
+				delete(pendingA, kb)
+				if err := s.diffKey(kb, va, vb, path, opts); err != nil {
+					return err
+				}
+			} else {
+				pendingB[kb] = vb
+			}
+		}
+	}
+	for _, dec := range []*json.Decoder{da, db} {
+		if _, err := dec.Token(); err != nil {
+			return err
+		}
+	}
+	keys := make([]string, 0, len(pendingA)+len(pendingB))
+	for k := range pendingA {
+		keys = append(keys, k)
+	}
+	for k := range pendingB {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		va, ok := pendingA[k]
+		if !ok {
+			va = voidNode{}
+		}
+		vb, ok := pendingB[k]
+		if !ok {
+			vb = voidNode{}
+		}
+		if err := s.diffKey(k, va, vb, path, opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// diffValue diffs the values of key k on both sides. Objects and
+// Lists are streamed. Anything else is read into memory.
+func (s *streamDiffer) diffValue(da, db *json.Decoder, k string, path Path, opts *options) error {
+	ta, err := da.Token()
+	if err != nil {
+		return err
+	}
+	tb, err := db.Token()
+	if err != nil {
+		return err
+	}
+	keyOpts := refine(opts, PathKey(k))
+	keyPath := append(path.clone(), PathKey(k))
+	switch {
+	case isDelim(ta, '{') && isDelim(tb, '{'):
+		return s.diffObject(da, db, keyPath, keyOpts)
+	case isDelim(ta, '[') && isDelim(tb, '[') && s.isList(keyOpts):
+		return s.diffList(da, db, keyPath, keyOpts)
+	}
+	va, err := readTokenNode(da, ta)
+	if err != nil {
+		return err
+	}
+	vb, err := readTokenNode(db, tb)
+	if err != nil {
+		return err
+	}
+	return s.diffKey(k, va, vb, path, opts)
+}
+
+// diffKey emits the in-memory diff of key k between va and vb. Either
+// may be void when the key is missing on that side.
+func (s *streamDiffer) diffKey(k string, va, vb JsonNode, path Path, opts *options) error {
+	oa, ob := jsonObject{}, jsonObject{}
+	if !isVoid(va) {
+		oa[k] = va
+	}
+	if !isVoid(vb) {
+		ob[k] = vb
+	}
+	return s.emit(oa.diff(ob, path, opts, s.strategy))
+}
+
+// streamList is a window of elements read ahead from a List.
+type streamList struct {
+	dec  *json.Decoder
+	q    []JsonNode
+	done bool
+}
+
+// fill reads ahead until n elements are queued or the List ends.
+func (l *streamList) fill(n int) error {
+	for len(l.q) < n && !l.done {
+		if !l.dec.More() {
+			if _, err := l.dec.Token(); err != nil {
+				return err
+			}
+			l.done = true
+			return nil
+		}
+		v, err := readNode(l.dec)
+		if err != nil {
+			return err
+		}
+		l.q = append(l.q, v)
+	}
+	return nil
+}
+
+// diffList diffs two Lists whose opening brackets have been read. Hunks
+// are built the same way as by the in-memory List diff: the path index
+// is the position in b, Before is the preceding element of b and After
+// is the element of a following the removed run.
+func (s *streamDiffer) diffList(da, db *json.Decoder, path Path, opts *options) error {
+	la, lb := &streamList{dec: da}, &streamList{dec: db}
+	index := 0
+	var previous JsonNode = voidNode{}
+	for {
+		if err := la.fill(1); err != nil {
+			return err
+		}
+		if err := lb.fill(1); err != nil {
+			return err
+		}
+		if len(la.q) == 0 && len(lb.q) == 0 {
+			return nil
+		}
+		if len(la.q) > 0 && len(lb.q) > 0 && la.q[0].equals(lb.q[0], refine(opts, PathIndex(index))) {
+			previous = lb.q[0]
+			la.q, lb.q = la.q[1:], lb.q[1:]
+			index++
+			continue
+		}
+		x, y, err := s.resync(la, lb, index, opts)
+		if err != nil {
+			return err
+		}
+		// Leading pairs of containers are diffed recursively.
+		for x > 0 && y > 0 && sameContainerType(la.q[0], lb.q[0], refine(opts, PathIndex(index))) {
+			elemPath := append(path.clone(), PathIndex(index))
+			d := la.q[0].diff(lb.q[0], elemPath, refine(opts, PathIndex(index)), s.strategy)
+			if err := s.emit(d); err != nil {
+				return err
+			}
+			previous = lb.q[0]
+			la.q, lb.q = la.q[1:], lb.q[1:]
+			x, y = x-1, y-1
+			index++
+		}
+		if x == 0 && y == 0 {
+			continue
+		}
+		if err := la.fill(x + 1); err != nil {
+			return err
+		}
+		var after JsonNode = voidNode{}
+		if len(la.q) > x {
+			after = la.q[x]
+		}
+		if refine(opts, PathIndex(index)).diffingOn {
+			err := s.emit(Diff{{
+				Path:   append(path.clone(), PathIndex(index)),
+				Before: []JsonNode{previous},
+				Remove: append([]JsonNode{}, la.q[:x]...),
+				Add:    append([]JsonNode{}, lb.q[:y]...),
+				After:  []JsonNode{after},
+			}})
+			if err != nil {
+				return err
+			}
+		}
+		if y > 0 {
+			previous = lb.q[y-1]
+		}
+		la.q, lb.q = la.q[x:], lb.q[y:]
+		index += y
+	}
+}
+
+// resync returns the lengths of the runs of a and b before their next
+// common element, looking no further than the window. When there is
+// none the whole windows are returned.
+func (s *streamDiffer) resync(la, lb *streamList, index int, opts *options) (int, int, error) {
+	if err := la.fill(streamWindow); err != nil {
+		return 0, 0, err
+	}
+	if err := lb.fill(streamWindow); err != nil {
+		return 0, 0, err
+	}
+	elemOpts := refine(opts, PathIndex(index))
+	for d := 1; d < len(la.q)+len(lb.q); d++ {
+		for x := 0; x <= d; x++ {
+			y := d - x
+			if x < len(la.q) && y < len(lb.q) && la.q[x].equals(lb.q[y], elemOpts) {
+				return x, y, nil
+			}
+		}
+	}
+	return len(la.q), len(lb.q), nil
+}
+
+// voidToken stands in for the missing value of an empty document.
+type voidToken struct{}
+
+func rootToken(dec *json.Decoder) (json.Token, error) {
+	t, err := dec.Token()
+	if err == io.EOF {
+		return voidToken{}, nil
+	}
+	return t, err
+}
+
+func isDelim(t json.Token, d json.Delim) bool {
+	delim, ok := t.(json.Delim)
+	return ok && delim == d
+}
+
+func readKey(dec *json.Decoder) (string, error) {
+	t, err := dec.Token()
+	if err != nil {
+		return "", err
+	}
+	k, ok := t.(string)
+	if !ok { //jd:nocover — the decoder only returns strings as keys
+		return "", fmt.Errorf("expected object key. got %v", t)
+	}
+	return k, nil
+}
+
+// readNode reads the next whole value from dec.
+func readNode(dec *json.Decoder) (JsonNode, error) {
+	t, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	return readTokenNode(dec, t)
+}
+
+// readTokenNode reads the rest of the value starting with token t.
+func readTokenNode(dec *json.Decoder, t json.Token) (JsonNode, error) {
+	switch {
+	case t == voidToken{}:
+		return voidNode{}, nil
+	case isDelim(t, '{'):
+		o := newJsonObject()
+		for dec.More() {
+			k, err := readKey(dec)
+			if err != nil {
+				return nil, err
+			}
+			v, err := readNode(dec)
+			if err != nil {
+				return nil, err
+			}
+			o[k] = v
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		return o, nil
+	case isDelim(t, '['):
+		a := jsonArray{}
+		for dec.More() {
+			v, err := readNode(dec)
+			if err != nil {
+				return nil, err
+			}
+			a = append(a, v)
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		return a, nil
+	}
+	return NewJsonNode(t)
+}
@@ -0,0 +1,292 @@
+package jd
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestDiffStream(t *testing.T) {
+	cases := []struct {
+		name    string
+		options []Option
+		a       string
+		b       string
+		want    []string
+	}{{
+		name: "empty documents",
+		a:    ``,
+		b:    ``,
+		want: ss(),
+	}, {
+		name: "void to object",
+		a:    ``,
+		b:    `{}`,
+		want: ss(
+			`@ []`,
+			`+ {}`,
+		),
+	}, {
+		name: "scalar roots",
+		a:    `1`,
+		b:    `[1]`,
+		want: ss(
+			`@ []`,
+			`- 1`,
+			`+ [1]`,
+		),
+	}, {
+		name: "aligned object keys",
+		a:    `{"a":1,"b":{"c":null,"d":[1,2,3]}}`,
+		b:    `{"a":2,"b":{"c":null,"d":[1,3]}}`,
+		want: ss(
+			`@ ["a"]`,
+			`- 1`,
+			`+ 2`,
+			`@ ["b","d",1]`,
+			`  1`,
+			`- 2`,
+			`  3`,
+		),
+	}, {
+		name: "reordered object keys",
+		a:    `{"a":1,"b":2,"c":3}`,
+		b:    `{"c":4,"b":2,"d":5}`,
+		want: ss(
+			`@ ["c"]`,
+			`- 3`,
+			`+ 4`,
+			`@ ["a"]`,
+			`- 1`,
+			`@ ["d"]`,
+			`+ 5`,
+		),
+	}, {
+		name: "list changes",
+		a:    `[1,2,3,{"a":1},5]`,
+		b:    `[1,3,{"a":2},5,6]`,
+		want: ss(
+			`@ [1]`,
+			`  1`,
+			`- 2`,
+			`  3`,
+			`@ [2,"a"]`,
+			`- 1`,
+			`+ 2`,
+			`@ [4]`,
+			`  5`,
+			`+ 6`,
+			`]`,
+		),
+	}, {
+		name: "list replaced",
+		a:    `[1,2]`,
+		b:    `[3]`,
+		want: ss(
+			`@ [0]`,
+			`[`,
+			`- 1`,
+			`- 2`,
+			`+ 3`,
+			`]`,
+		),
+	}, {
+		name:    "options header",
+		options: []Option{Precision(0.1)},
+		a:       `{"a":1.0,"b":2.0}`,
+		b:       `{"a":1.05,"b":3.0}`,
+		want: ss(
+			`^ {"precision":0.1}`,
+			`@ ["b"]`,
+			`- 2`,
+			`+ 3`,
+		),
+	}, {
+		name:    "set is read into memory",
+		options: []Option{SET},
+		a:       `{"a":[1,2]}`,
+		b:       `{"a":[2,1,3]}`,
+		want: ss(
+			`^ "SET"`,
+			`@ ["a",{}]`,
+			`+ 3`,
+		),
+	}, {
+		name:    "merge",
+		options: []Option{MERGE},
+		a:       `{"a":[1,2],"b":{"c":1}}`,
+		b:       `{"a":[1],"b":{"d":1}}`,
+		want: ss(
+			`^ "MERGE"`,
+			`@ ["a"]`,
+			`+ [1]`,
+			`@ ["b","c"]`,
+			`+`,
+			`@ ["b","d"]`,
+			`+ 1`,
+		),
+	}, {
+		name:    "diffing off",
+		options: []Option{PathOption(Path{PathKey("a")}, DIFF_OFF)},
+		a:       `{"a":[1],"b":[1]}`,
+		b:       `{"a":[2],"b":[2]}`,
+		want: ss(
+			`^ {"@":["a"],"^":["DIFF_OFF"]}`,
+			`@ ["b",0]`,
+			`[`,
+			`- 1`,
+			`+ 2`,
+			`]`,
+		),
+	}}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			w := bytes.NewBuffer(nil)
+			haveDiff, err := DiffStream(strings.NewReader(c.a), strings.NewReader(c.b), w, c.options...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := strings.Join(c.want, "\n")
+			if len(c.want) > 0 {
+				want += "\n"
+			}
+			if got := w.String(); got != want {
+				t.Errorf("got diff:\n%v\nwant:\n%v", got, want)
+			}
+			if haveDiff != (len(c.want) > 0) {
+				t.Errorf("got haveDiff %v. want %v", haveDiff, len(c.want) > 0)
+			}
+		})
+	}
+}
+
+func TestDiffStreamPatch(t *testing.T) {
+	// A long list which drifts in and out of alignment.
+	a, b := []string{}, []string{}
+	for i := 0; i < 1000; i++ {
+		a = append(a, fmt.Sprintf(`{"id":%v,"v":%v}`, i, i%7))
+		switch {
+		case i%97 == 0:
+			// removed
+		case i%89 == 0:
+			b = append(b, fmt.Sprintf(`{"id":%v,"v":"x"}`, i))
+		case i == 500:
+			for j := 0; j < 2*streamWindow; j++ {
+				b = append(b, fmt.Sprintf(`%v`, j))
+			}
+		default:
+			b = append(b, fmt.Sprintf(`{"id":%v,"v":%v}`, i, i%7))
+		}
+	}
+	for _, c := range []struct {
+		a string
+		b string
+	}{
+		{`[` + strings.Join(a, ",") + `]`, `[` + strings.Join(b, ",") + `]`},
+		{`[` + strings.Join(b, ",") + `]`, `[` + strings.Join(a, ",") + `]`},
+		{`{"x":[` + strings.Join(a, ",") + `],"y":1}`, `{"y":2,"x":[` + strings.Join(b, ",") + `]}`},
+		{`[]`, `[` + strings.Join(b, ",") + `]`},
+	} {
+		w := bytes.NewBuffer(nil)
+		if _, err := DiffStream(strings.NewReader(c.a), strings.NewReader(c.b), w); err != nil {
+			t.Fatal(err)
+		}
+		d, err := ReadDiffString(w.String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		na, _ := ReadJsonString(c.a)
+		nb, _ := ReadJsonString(c.b)
+		got, err := na.Patch(d)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !got.Equals(nb) {
+			t.Errorf("patching with the streamed diff did not produce b")
+		}
+	}
+}
+
+func TestDiffStreamErrors(t *testing.T) {
+	cases := []struct {
+		name    string
+		options []Option
+		a       string
+		b       string
+	}{
+		{"invalid a", nil, `{`, `{}`},
+		{"invalid b", nil, `{}`, `}`},
+		{"truncated object", nil, `{"a":1`, `{"a":1}`},
+		{"truncated list", nil, `[1,2`, `[1,2,3]`},
+		{"invalid value", nil, `{"a":[1,x]}`, `{"a":[1,2]}`},
+		{"trailing data", nil, `{}`, `{} {}`},
+		{"detect moves", []Option{DETECT_MOVES}, `{}`, `{}`},
+		{"auto keys", []Option{AUTO_KEYS}, `{}`, `{}`},
+		{"similarity", []Option{Similarity(0.5)}, `{}`, `{}`},
+		{"invalid a root", nil, `]`, `{}`},
+		{"invalid scalar a", nil, `[1,x]`, `1`},
+		{"invalid scalar b", nil, `1`, `[1,x]`},
+		{"truncated key a", nil, `{"a":1,`, `{"a":1,"b":2}`},
+		{"truncated key b", nil, `{"a":1,"b":2}`, `{"a":1,`},
+		{"invalid pending a", nil, `{"x":[1,x]}`, `{"y":1}`},
+		{"invalid pending b", nil, `{"x":1}`, `{"y":[x]}`},
+		{"truncated value a", nil, `{"a":`, `{"a":1}`},
+		{"truncated value b", nil, `{"a":1}`, `{"a":`},
+		{"invalid value a", nil, `{"a":[x]}`, `{"a":1}`},
+		{"invalid value b", nil, `{"a":1}`, `{"a":[x]}`},
+		{"invalid nested object", nil, `{"a":[{"b":x}]}`, `{"a":1}`},
+		{"invalid nested key", nil, `{"a":[{1:2}]}`, `{"a":1}`},
+		{"mismatched nested object", nil, `{"a":{"b":[{"c":1]]}}`, `{"a":1}`},
+		{"mismatched nested list", nil, `{"a":[[1}]}`, `{"a":1}`},
+		{"mismatched object end", nil, `{"a":1]`, `{"a":2}`},
+		{"mismatched list end", nil, `[1}`, `[1,2]`},
+		{"mismatched end beyond window", nil, `[` + strings.Repeat(`"x",`, streamWindow-1) + `"x"}`, `[1]`},
+		{"truncated list a", nil, `[1`, `[1]`},
+		{"truncated list b", nil, `[1]`, `[1`},
+		{"truncated list end", nil, `[1,2,3`, `[1,3,4]`},
+		{"truncated window a", nil, `[1,2,3`, `[4,5,6]`},
+		{"truncated window b", nil, `[4,5,6]`, `[1,2,3`},
+		{"truncated after run", nil, `[1,[2],3`, `[1,[4],5]`},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			w := bytes.NewBuffer(nil)
+			_, err := DiffStream(strings.NewReader(c.a), strings.NewReader(c.b), w, c.options...)
+			if err == nil {
+				t.Errorf("wanted error. got %q", w.String())
+			}
+		})
+	}
+}
+
+func TestDiffStreamWriteErrors(t *testing.T) {
+	cases := []struct {
+		name string
+		a    string
+		b    string
+	}{
+		{"scalars", `1`, `2`},
+		{"aligned key", `{"a":1}`, `{"a":2}`},
+		{"pending a", `{"a":1,"b":1}`, `{"b":2,"a":1}`},
+		{"pending b", `{"b":1,"a":1}`, `{"a":1,"c":1,"b":2}`},
+		{"leftover key", `{"a":1}`, `{"b":1}`},
+		{"list hunk", `[1,2]`, `[1,3]`},
+		{"nested list element", `[{"a":1}]`, `[{"a":2}]`},
+		{"list element", `[[1]]`, `[{}]`},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := DiffStream(strings.NewReader(c.a), strings.NewReader(c.b), failingWriter{})
+			if err == nil {
+				t.Errorf("wanted write error")
+			}
+		})
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, fmt.Errorf("write failed")
+}
@@ -1,5 +1,7 @@
 package jd
 
+import "context"
+
 type jsonString string
 
 var _ JsonNode = jsonString("")
@@ -22,15 +24,33 @@ func (s1 jsonString) Equals(n JsonNode, opts ...Option) bool {
 }
 
 func (s1 jsonString) equals(n JsonNode, o *options) bool {
+	if c := coerced(s1, o); c != s1 {
+		return c.equals(n, o)
+	}
+	if d1, d2, e, ok := embeddedPair(s1, n, o); ok {
+		return d1.equals(d2, refine(o, e))
+	}
 	s2, ok := n.(jsonString)
 	if !ok {
 		return false
 	}
-	return s1 == s2
+	p1, matched1 := patterned(s1, o)
+	p2, matched2 := patterned(s2, o)
+	if matched1 != matched2 {
+		return false
+	}
+	return normalized(p1, o) == normalized(p2, o)
 }
 
-func (s jsonString) hashCode(_ *options) [8]byte {
-	return hash([]byte(s))
+func (s jsonString) hashCode(opts *options) [8]byte {
+	if c := coerced(s, opts); c != s {
+		return c.hashCode(opts)
+	}
+	if d, e, ok := embeddedDocument(s, opts); ok {
+		return d.hashCode(refine(opts, e))
+	}
+	p, _ := patterned(s, opts)
+	return hash([]byte(normalized(p, opts)))
 }
 
 func (s jsonString) Diff(n JsonNode, opts ...Option) Diff {
@@ -44,6 +64,9 @@ func (s1 jsonString) diff(
 	opts *options,
 	strategy patchStrategy,
 ) Diff {
+	if d1, d2, e, ok := embeddedPair(s1, n, opts); ok && strategy != mergePatchStrategy {
+		return d1.diff(d2, append(path.clone(), e), refine(opts, e), strategy)
+	}
 	// Use event-driven diff architecture
 	events := generateSimpleEvents(s1, n, opts)
 	processor := newSimpleDiffProcessor(path, opts, strategy)
@@ -54,10 +77,23 @@ func (s jsonString) Patch(d Diff) (JsonNode, error) {
 	return patchAll(s, d)
 }
 
+func (s jsonString) DiffContext(ctx context.Context, n JsonNode, opts ...Option) (Diff, error) {
+	return diffContext(ctx, s, n, opts)
+}
+
+func (s jsonString) PatchContext(ctx context.Context, d Diff) (JsonNode, error) {
+	return patchContext(ctx, s, d)
+}
+
 func (s jsonString) patch(
 	pathBehind, pathAhead Path,
 	before, oldValues, newValues, after []JsonNode,
 	strategy patchStrategy,
 ) (JsonNode, error) {
+	if len(pathAhead) > 0 {
+		if e, ok := pathAhead[0].(PathEmbedded); ok {
+			return patchEmbedded(s, e, pathBehind, pathAhead[1:], before, oldValues, newValues, after, strategy)
+		}
+	}
 	return patch(s, pathBehind, pathAhead, before, oldValues, newValues, after, strategy)
 }
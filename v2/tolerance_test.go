@@ -0,0 +1,206 @@
+package jd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestToleranceSetDiff(t *testing.T) {
+	cases := []struct {
+		name    string
+		options []Option
+		a       string
+		b       string
+		want    []string
+	}{{
+		name:    "set of measurements",
+		options: []Option{SET, Precision(0.01)},
+		a:       `[1.001,2.5,3.2]`,
+		b:       `[3.205,1,2.5,4]`,
+		want: ss(
+			`@ [{}]`,
+			`+ 4`,
+		),
+	}, {
+		name:    "set without tolerance",
+		options: []Option{SET},
+		a:       `[1.001,2.5]`,
+		b:       `[2.5,1]`,
+		want: ss(
+			`@ [{}]`,
+			`- 1.001`,
+			`+ 1`,
+		),
+	}, {
+		name:    "set with relative tolerance",
+		options: []Option{SET, Relative(0.01)},
+		a:       `[1000,0.001]`,
+		b:       `[0.001005,1005,7]`,
+		want: ss(
+			`@ [{}]`,
+			`+ 7`,
+		),
+	}, {
+		name:    "set pairs each element once",
+		options: []Option{SET, Precision(0.1)},
+		a:       `[1.0,1.15]`,
+		b:       `[1.1,1.3]`,
+		want: ss(
+			`@ [{}]`,
+			`- 1`,
+			`+ 1.3`,
+		),
+	}, {
+		name:    "set of objects",
+		options: []Option{SET, Precision(0.01)},
+		a:       `[{"x":1.001,"y":2},{"x":5,"y":5}]`,
+		b:       `[{"x":5,"y":6},{"x":1,"y":2.002}]`,
+		want: ss(
+			`@ [{}]`,
+			`- {"x":5,"y":5}`,
+			`+ {"x":5,"y":6}`,
+		),
+	}, {
+		name:    "set keyed by a measurement",
+		options: []Option{SetKeys("at"), Precision(0.01)},
+		a:       `[{"at":1.001,"v":"a"},{"at":2,"v":"b"},{"at":5},{"v":1.001},{"v":"y"}]`,
+		b:       `[{"at":2,"v":"b"},{"at":1,"v":"c"},{"at":6},{"v":1},{"w":"y"},7]`,
+		want: ss(
+			`@ [{"at":1.001},"v"]`,
+			`- "a"`,
+			`+ "c"`,
+			`@ [{}]`,
+			`- {"v":"y"}`,
+			`- {"at":5}`,
+			`+ 7`,
+			`+ {"at":6}`,
+			`+ {"w":"y"}`,
+		),
+	}, {
+		name:    "path option",
+		options: []Option{SET, PathOption(Path{PathKey("t")}, Precision(0.5))},
+		a:       `{"t":[20.1,21.7],"n":[1]}`,
+		b:       `{"t":[21.5,20.3],"n":[1.1]}`,
+		want: ss(
+			`@ ["n",{}]`,
+			`- 1`,
+			`+ 1.1`,
+		),
+	}, {
+		name:    "nested sets",
+		options: []Option{SET, Precision(0.01)},
+		a:       `[[1.001,2],[3]]`,
+		b:       `[[3],[2,1]]`,
+		want:    ss(),
+	}, {
+		name:    "multiset of measurements",
+		options: []Option{MULTISET, Precision(0.01)},
+		a:       `[1.001,1.002,2.5]`,
+		b:       `[2.5,1,3]`,
+		want: ss(
+			`@ [[]]`,
+			`- 1.001`,
+			`+ 3`,
+		),
+	}, {
+		name:    "multiset with ulp tolerance",
+		options: []Option{MULTISET, ULP(1)},
+		a:       `[0.30000000000000004,0.30000000000000004]`,
+		b:       `[0.3,0.3]`,
+		want:    ss(),
+	}}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ctx := newTestContext(t).withOptions(c.options...)
+			checkDiff(ctx, c.a, c.b, c.want...)
+			checkRoundTrip(t, c.a, c.b, c.options)
+		})
+	}
+}
+
+func TestToleranceListRoundTrip(t *testing.T) {
+	cases := []struct {
+		name    string
+		options []Option
+		a       string
+		b       string
+	}{{
+		name:    "precision",
+		options: []Option{Precision(0.05)},
+		a:       `[[1.02,1.01],5]`,
+		b:       `[[1]]`,
+	}, {
+		name:    "relative",
+		options: []Option{Relative(0.05)},
+		a:       `[[1.02,1.01],5]`,
+		b:       `[[1]]`,
+	}, {
+		name:    "ulp",
+		options: []Option{ULP(1)},
+		a:       `[{"a":1.0000000000000002,"b":2},5,6]`,
+		b:       `[{"a":1},6]`,
+	}, {
+		name:    "nested lists",
+		options: []Option{Relative(0.05)},
+		a:       `[[[1.01,2],3],[4],5]`,
+		b:       `[[[1],3],[4.01]]`,
+	}}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			checkRoundTrip(t, c.a, c.b, c.options)
+		})
+	}
+}
+
+func TestToleranceEquals(t *testing.T) {
+	cases := []struct {
+		options []Option
+		a, b    string
+		want    bool
+	}{
+		{[]Option{SET, Precision(0.01)}, `[1.001,2]`, `[2,1]`, true},
+		{[]Option{SET, Precision(0.01)}, `[1.001,2]`, `[2,1,3]`, false},
+		{[]Option{SET, Precision(0.01)}, `[1.001,2,3]`, `[2,1]`, false},
+		{[]Option{SET}, `[1.001,2]`, `[2,1]`, false},
+		{[]Option{MULTISET, Precision(0.01)}, `[1.001,1,2]`, `[2,1,1]`, true},
+		{[]Option{MULTISET, Precision(0.01)}, `[1.001,1,2]`, `[2,2,1]`, false},
+		{[]Option{MULTISET}, `[1.001,1]`, `[1,1]`, false},
+		{[]Option{PathOption(Path{PathKey("a")}, MULTISET, Relative(0.1))}, `{"a":[10,20]}`, `{"a":[21,9.5]}`, true},
+	}
+	for _, c := range cases {
+		a, _ := ReadJsonString(c.a)
+		b, _ := ReadJsonString(c.b)
+		if got := a.Equals(b, c.options...); got != c.want {
+			t.Errorf("%v.Equals(%v) = %v. Want %v", c.a, c.b, got, c.want)
+		}
+	}
+}
+
+func TestMatchEqual(t *testing.T) {
+	within := func(x, y JsonNode) bool {
+		return x.equals(y, refine(newOptions([]Option{Precision(0.1)}), nil))
+	}
+	cases := []struct {
+		a, b []JsonNode
+		want []int
+	}{{
+		// Pairing 1 with 1.05 first must give way so that 1.1
+		// finds a partner too.
+		a:    []JsonNode{jsonNumber(1), jsonNumber(1.1)},
+		b:    []JsonNode{jsonNumber(1.05), jsonNumber(0.95)},
+		want: []int{1, 0},
+	}, {
+		a:    []JsonNode{jsonNumber(1), jsonNumber(5)},
+		b:    []JsonNode{jsonNumber(1)},
+		want: []int{0, -1},
+	}, {
+		a:    []JsonNode{},
+		b:    []JsonNode{jsonNumber(1)},
+		want: []int{},
+	}}
+	for _, c := range cases {
+		if got := matchEqual(c.a, c.b, within); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("matchEqual(%v, %v) = %v. Want %v", c.a, c.b, got, c.want)
+		}
+	}
+}
@@ -1,5 +1,7 @@
 package jd
 
+import "context"
+
 type voidNode struct{}
 
 var _ JsonNode = voidNode{}
@@ -55,6 +57,7 @@ func (v voidNode) hashCode(_ *options) [8]byte {
 }
 
 func (v voidNode) Diff(n JsonNode, opts ...Option) Diff {
+	opts = InferKeys(v, n, opts...)
 	o := refine(newOptions(opts), nil)
 	return v.diff(n, make(Path, 0), o, getPatchStrategy(o))
 }
@@ -75,6 +78,14 @@ func (v voidNode) Patch(d Diff) (JsonNode, error) {
 	return patchAll(v, d)
 }
 
+func (v voidNode) DiffContext(ctx context.Context, n JsonNode, opts ...Option) (Diff, error) {
+	return diffContext(ctx, v, n, opts)
+}
+
+func (v voidNode) PatchContext(ctx context.Context, d Diff) (JsonNode, error) {
+	return patchContext(ctx, v, d)
+}
+
 func (v voidNode) patch(
 	pathBehind, pathAhead Path,
 	before, oldValues, newValues, after []JsonNode,